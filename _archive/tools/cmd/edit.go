@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	dbpkg "dfw-dragevents/tools/internal/db"
+)
+
+// clearValue is typed at an interactive prompt to clear an optional field.
+const clearValue = "-"
+
+func editTrack(db *sql.DB, args []string) {
+	id := parseEditID(args, "track", "go run ./cmd track edit <id> [--name=... --city=... --address=... --url=... --timezone=...]")
+	fs := flag.NewFlagSet("track edit", flag.ExitOnError)
+	name := fs.String("name", "", "track name")
+	city := fs.String("city", "", "city")
+	address := fs.String("address", "", "street address")
+	url := fs.String("url", "", "website URL")
+	timeZone := fs.String("timezone", "", "IANA time zone, e.g. America/Chicago")
+	fs.Parse(args[1:])
+
+	current, err := dbpkg.GetTrack(db, id)
+	if err != nil {
+		log.Fatalf("Failed to load track: %v", err)
+	}
+
+	var u dbpkg.TrackUpdate
+	if fs.NFlag() == 0 {
+		p := newPrompter(fmt.Sprintf("Edit Track %d", id))
+		u.Name = p.required("Name", current.Name)
+		u.City = p.required("City", current.City)
+		u.Address = p.required("Address", current.Address)
+		u.URL = p.optional("URL", current.URL)
+		u.TimeZone = p.required("Time Zone", current.TimeZone)
+	} else {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "name":
+				u.Name = name
+			case "city":
+				u.City = city
+			case "address":
+				u.Address = address
+			case "url":
+				u.URL = url
+			case "timezone":
+				u.TimeZone = timeZone
+			}
+		})
+	}
+	reportUpdate("Track", id, dbpkg.UpdateTrack(db, id, u))
+}
+
+func editEvent(db *sql.DB, args []string) {
+	id := parseEditID(args, "event", "go run ./cmd event edit <id> [--title=... --track-id=... --start-date=... --end-date=... --driver-fee=... --spectator-fee=... --url=... --description=... --series=...]")
+	fs := flag.NewFlagSet("event edit", flag.ExitOnError)
+	title := fs.String("title", "", "event title")
+	trackID := fs.Int64("track-id", 0, "track ID")
+	startDate := fs.String("start-date", "", "start date, local time at the track (YYYY-MM-DD HH:MM:SS)")
+	endDate := fs.String("end-date", "", "end date, local time at the track (YYYY-MM-DD HH:MM:SS, empty clears)")
+	driverFee := fs.String("driver-fee", "", "driver fee (empty clears)")
+	spectatorFee := fs.String("spectator-fee", "", "spectator fee (empty clears)")
+	url := fs.String("url", "", "event URL")
+	description := fs.String("description", "", "description")
+	series := fs.String("series", "", "series, e.g. TMCCC (empty clears)")
+	fs.Parse(args[1:])
+
+	current, err := dbpkg.GetEvent(db, id)
+	if err != nil {
+		log.Fatalf("Failed to load event: %v", err)
+	}
+
+	var u dbpkg.EventUpdate
+	if fs.NFlag() == 0 {
+		p := newPrompter(fmt.Sprintf("Edit Event %d", id))
+		u.Title = p.required("Title", current.Title)
+		if v := p.required("Track ID", strconv.FormatInt(current.TrackID, 10)); v != nil {
+			u.TrackID = mustParseID(*v, "track ID")
+		}
+		u.StartDate = p.required("Start Date (YYYY-MM-DD HH:MM:SS)", current.StartDate.Format(dbpkg.DateLayout))
+		currentEnd := ""
+		if current.EndDate != nil {
+			currentEnd = current.EndDate.Format(dbpkg.DateLayout)
+		}
+		u.EndDate = p.optional("End Date (YYYY-MM-DD HH:MM:SS)", currentEnd)
+		u.DriverFee = p.fee("Driver Fee", current.DriverFee)
+		u.SpectatorFee = p.fee("Spectator Fee", current.SpectatorFee)
+		u.URL = p.optional("URL", current.URL)
+		u.Description = p.optional("Description", current.Description)
+		u.Series = p.optional("Series", current.Series)
+	} else {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "title":
+				u.Title = title
+			case "track-id":
+				u.TrackID = trackID
+			case "start-date":
+				u.StartDate = startDate
+			case "end-date":
+				u.EndDate = endDate
+			case "driver-fee":
+				u.DriverFee = mustParseFee(*driverFee, "driver fee")
+			case "spectator-fee":
+				u.SpectatorFee = mustParseFee(*spectatorFee, "spectator fee")
+			case "url":
+				u.URL = url
+			case "description":
+				u.Description = description
+			case "series":
+				u.Series = series
+			}
+		})
+	}
+	reportUpdate("Event", id, dbpkg.UpdateEvent(db, id, u))
+}
+
+func editEventClass(db *sql.DB, args []string) {
+	id := parseEditID(args, "class", "go run ./cmd class edit <id> [--name=... --buyin-fee=... --event-id=...]")
+	fs := flag.NewFlagSet("class edit", flag.ExitOnError)
+	eventID := fs.Int64("event-id", 0, "event ID")
+	name := fs.String("name", "", "class name")
+	buyinFee := fs.String("buyin-fee", "", "buy-in fee (empty clears)")
+	fs.Parse(args[1:])
+
+	current, err := dbpkg.GetEventClass(db, id)
+	if err != nil {
+		log.Fatalf("Failed to load event class: %v", err)
+	}
+
+	var u dbpkg.EventClassUpdate
+	if fs.NFlag() == 0 {
+		p := newPrompter(fmt.Sprintf("Edit Event Class %d", id))
+		if v := p.required("Event ID", strconv.FormatInt(current.EventID, 10)); v != nil {
+			u.EventID = mustParseID(*v, "event ID")
+		}
+		u.Name = p.required("Name", current.Name)
+		u.BuyinFee = p.fee("Buy-in Fee", current.BuyinFee)
+	} else {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "event-id":
+				u.EventID = eventID
+			case "name":
+				u.Name = name
+			case "buyin-fee":
+				u.BuyinFee = mustParseFee(*buyinFee, "buy-in fee")
+			}
+		})
+	}
+	reportUpdate("Event class", id, dbpkg.UpdateEventClass(db, id, u))
+}
+
+func editEventClassRule(db *sql.DB, args []string) {
+	id := parseEditID(args, "rule", "go run ./cmd rule edit <id> [--rule=... --class-id=...]")
+	fs := flag.NewFlagSet("rule edit", flag.ExitOnError)
+	classID := fs.Int64("class-id", 0, "event class ID")
+	rule := fs.String("rule", "", "rule text")
+	fs.Parse(args[1:])
+
+	current, err := dbpkg.GetEventClassRule(db, id)
+	if err != nil {
+		log.Fatalf("Failed to load rule: %v", err)
+	}
+
+	var u dbpkg.EventClassRuleUpdate
+	if fs.NFlag() == 0 {
+		p := newPrompter(fmt.Sprintf("Edit Rule %d", id))
+		if v := p.required("Event Class ID", strconv.FormatInt(current.EventClassID, 10)); v != nil {
+			u.EventClassID = mustParseID(*v, "event class ID")
+		}
+		u.Rule = p.required("Rule", current.Rule)
+	} else {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "class-id":
+				u.EventClassID = classID
+			case "rule":
+				u.Rule = rule
+			}
+		})
+	}
+	reportUpdate("Rule", id, dbpkg.UpdateEventClassRule(db, id, u))
+}
+
+func parseEditID(args []string, noun, usageLine string) int64 {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Printf("Error: %s ID required\n", noun)
+		fmt.Println("Usage:", usageLine)
+		os.Exit(2)
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s ID: %v", noun, err)
+	}
+	return id
+}
+
+func mustParseID(s, what string) *int64 {
+	id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", what, err)
+	}
+	return &id
+}
+
+// mustParseFee parses a fee flag; an empty value clears the fee.
+func mustParseFee(s, what string) *sql.NullFloat64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return &sql.NullFloat64{}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", what, err)
+	}
+	return &sql.NullFloat64{Float64: v, Valid: true}
+}
+
+func reportUpdate(noun string, id int64, err error) {
+	if errors.Is(err, dbpkg.ErrNoChanges) {
+		fmt.Println("No changes.")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to update %s: %v", strings.ToLower(noun), err)
+	}
+	fmt.Printf("✓ %s %d updated successfully!\n", noun, id)
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'make export' to update JSON files")
+}
+
+// prompter asks for each field with its current value pre-filled. Pressing
+// Enter keeps the value; typing "-" clears an optional field. Each method
+// returns nil when the value is unchanged.
+type prompter struct {
+	scanner *bufio.Scanner
+}
+
+func newPrompter(heading string) *prompter {
+	fmt.Printf("\n=== %s ===\n", heading)
+	fmt.Println("Press Enter to keep the current value, or '-' to clear an optional field.")
+	return &prompter{scanner: bufio.NewScanner(os.Stdin)}
+}
+
+func (p *prompter) read(label, current string) string {
+	fmt.Printf("%s [%s]: ", label, current)
+	p.scanner.Scan()
+	return strings.TrimSpace(p.scanner.Text())
+}
+
+func (p *prompter) required(label, current string) *string {
+	v := p.read(label, current)
+	if v == clearValue {
+		log.Fatalf("%s is required", label)
+	}
+	if v == "" || v == current {
+		return nil
+	}
+	return &v
+}
+
+func (p *prompter) optional(label, current string) *string {
+	v := p.read(label, current)
+	if v == clearValue {
+		v = ""
+	} else if v == "" {
+		return nil
+	}
+	if v == current {
+		return nil
+	}
+	return &v
+}
+
+func (p *prompter) fee(label string, current *float64) *sql.NullFloat64 {
+	cur := ""
+	if current != nil {
+		cur = strconv.FormatFloat(*current, 'f', -1, 64)
+	}
+	v := p.optional(label, cur)
+	if v == nil {
+		return nil
+	}
+	return mustParseFee(*v, strings.ToLower(label))
+}
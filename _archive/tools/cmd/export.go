@@ -0,0 +1,250 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dbpkg "dfw-dragevents/tools/internal/db"
+	exportpkg "dfw-dragevents/tools/internal/export"
+)
+
+// exportFormats are the shapes "export --format" can write.
+var exportFormats = []string{"flat", "sharded", "aggregator", "ics", "atom"}
+
+func exportSite(db *sql.DB, args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "csv":
+			exportCSV(db, args[1:])
+			return
+		case "html":
+			exportHTML(db, args[1:])
+			return
+		case "merge":
+			exportMerge(db, args[1:])
+			return
+		}
+	}
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "flat", "output shape: flat (tracks.json and events.json as stored), sharded (flat plus tracks/, months/ and index.json), aggregator ("+exportpkg.AggregatorFile+" in the events.schema.json shape), ics (iCalendar feeds in calendars/) or atom (events.atom, a feed of added and changed events)")
+	siteData := filepath.Clean(filepath.Join("..", "site", "data"))
+	dataDir := fs.String("dir", siteData, "directory to write to")
+	schemaPath := fs.String("schema", filepath.Join(siteData, "events.schema.json"), "JSON Schema the aggregator export is validated against")
+	feedLimit := fs.Int("feed-limit", exportpkg.FeedLimit, "number of changes in the Atom feed (0 for all)")
+	diff := fs.Bool("diff", false, "print the events added, removed and changed compared to the files on disk")
+	fs.Parse(args)
+
+	tracks, events, err := loadExportData(db)
+	if err != nil {
+		log.Fatalf("Failed to load events: %v", err)
+	}
+
+	var res exportpkg.Result
+	switch *format {
+	case "flat":
+		res, err = exportpkg.All(*dataDir, tracks, events)
+	case "sharded":
+		res, err = exportpkg.Sharded(*dataDir, tracks, events)
+	case "aggregator":
+		schema, loadErr := exportpkg.LoadSchema(*schemaPath)
+		if loadErr != nil {
+			log.Fatalf("Failed to load schema: %v", loadErr)
+		}
+		res, err = exportpkg.Aggregator(*dataDir, tracks, events, schema)
+	case "ics":
+		res, err = exportpkg.Calendars(*dataDir, tracks, events)
+	case "atom":
+		changes, listErr := dbpkg.ListEventChanges(db, *feedLimit)
+		if listErr != nil {
+			log.Fatalf("Failed to load event changes: %v", listErr)
+		}
+		res, err = exportpkg.Atom(*dataDir, events, changes)
+	default:
+		log.Fatalf("Unknown export format %q (use one of %v)", *format, exportFormats)
+	}
+	if err != nil {
+		reportExportError(*schemaPath, err)
+	}
+	if *diff && res.Events != nil {
+		fmt.Println("Events:", res.Events)
+	}
+	for _, name := range res.Removed {
+		fmt.Println("Removed", name)
+	}
+	switch {
+	case len(res.Written) == 0 && len(res.Removed) == 0:
+		fmt.Println("Export is up to date in", *dataDir)
+	case len(res.Unchanged) == 0:
+		fmt.Printf("Exported %s to %s\n", *format, *dataDir)
+	default:
+		fmt.Printf("Exported %s to %s (%s unchanged)\n", *format, *dataDir, strings.Join(res.Unchanged, ", "))
+	}
+}
+
+// exportCSV writes the database as CSV files the import commands read back.
+func exportCSV(db *sql.DB, args []string) {
+	if len(args) != 1 {
+		fmt.Println("Error: output directory required")
+		fmt.Println("Usage: go run ./cmd export csv <dir>")
+		os.Exit(2)
+	}
+	dir := args[0]
+	tracks, events, err := loadExportData(db)
+	if err != nil {
+		log.Fatalf("Failed to load events: %v", err)
+	}
+	res, err := exportpkg.CSV(dir, tracks, events)
+	if err != nil {
+		log.Fatalf("Failed to export CSV: %v", err)
+	}
+	if len(res.Written) == 0 {
+		fmt.Println("CSV export is up to date in", dir)
+		return
+	}
+	fmt.Printf("✓ Exported %d events to %s\n", len(events), dir)
+	fmt.Println("\nTo load them into another database (with tracks of the same names):")
+	fmt.Printf("  go run ./cmd event import %s\n", filepath.Join(dir, exportpkg.EventsCSV))
+	fmt.Printf("  go run ./cmd event import-classes %s\n", filepath.Join(dir, exportpkg.EventClassesCSV))
+	fmt.Printf("  go run ./cmd event import-rules %s\n", filepath.Join(dir, exportpkg.EventClassRulesCSV))
+	fmt.Printf("  go run ./cmd event import-reschedules %s\n", filepath.Join(dir, exportpkg.EventReschedulesCSV))
+}
+
+// exportHTML pre-renders event pages into the site and updates its sitemap.
+func exportHTML(db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("export html", flag.ExitOnError)
+	siteDir := fs.String("dir", filepath.Clean(filepath.Join("..", "site")), "site directory to write events/ and sitemap.xml to")
+	fs.Parse(args)
+
+	tracks, events, err := loadExportData(db)
+	if err != nil {
+		log.Fatalf("Failed to load events: %v", err)
+	}
+	res, err := exportpkg.EventPages(*siteDir, tracks, events)
+	if err != nil {
+		log.Fatalf("Failed to export HTML: %v", err)
+	}
+	for _, name := range res.Removed {
+		fmt.Println("Removed", name)
+	}
+	if len(res.Written) == 0 && len(res.Removed) == 0 {
+		fmt.Println("Event pages are up to date in", filepath.Join(*siteDir, exportpkg.EventPageDir))
+		return
+	}
+	fmt.Printf("✓ Rendered %d event pages to %s (%d unchanged)\n",
+		len(events), filepath.Join(*siteDir, exportpkg.EventPageDir), len(res.Unchanged))
+}
+
+// exportMerge writes the aggregator's feed, with the local database's
+// events, edits and suppressions applied, as the site's events.json.
+func exportMerge(db *sql.DB, args []string) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Error: aggregator events.json path required")
+		fmt.Println("Usage: go run ./cmd export merge <events.json> [--dir=../site/data] [--diff]")
+		os.Exit(2)
+	}
+	feedPath := args[0]
+	fs := flag.NewFlagSet("export merge", flag.ExitOnError)
+	dataDir := fs.String("dir", filepath.Clean(filepath.Join("..", "site", "data")), "directory to write events.json to")
+	diff := fs.Bool("diff", false, "print the events added, removed and changed compared to the file on disk")
+	fs.Parse(args[1:])
+
+	feed, err := exportpkg.LoadFeed(feedPath)
+	if err != nil {
+		log.Fatalf("Failed to load aggregator feed: %v", err)
+	}
+	tracks, events, err := loadExportData(db)
+	if err != nil {
+		log.Fatalf("Failed to load events: %v", err)
+	}
+	list, err := dbpkg.ListSuppressed(db)
+	if err != nil {
+		log.Fatalf("Failed to load suppressed events: %v", err)
+	}
+	suppressed := make(map[string]bool, len(list))
+	for _, s := range list {
+		suppressed[s.UUID] = true
+	}
+
+	merged, hidden, err := exportpkg.MergeEvents(feed, tracks, events, suppressed)
+	if err != nil {
+		log.Fatalf("Failed to merge events: %v", err)
+	}
+	res, err := exportpkg.Merged(*dataDir, merged)
+	if err != nil {
+		log.Fatalf("Failed to export: %v", err)
+	}
+	if *diff && res.Events != nil {
+		fmt.Println("Events:", res.Events)
+	}
+	counts := make(map[string]int)
+	for _, ev := range merged {
+		counts[ev.Provenance]++
+	}
+	fmt.Printf("✓ Merged %d events into %s: %d from the aggregator, %d with local edits, %d local only; %d suppressed\n",
+		len(merged), filepath.Join(*dataDir, "events.json"), counts[exportpkg.ProvenanceAggregator],
+		counts[exportpkg.ProvenanceMerged], counts[exportpkg.ProvenanceLocal], len(hidden))
+	for _, r := range hidden {
+		fmt.Println("  suppressed", r)
+	}
+}
+
+// reportExportError prints every schema violation, or the error, and exits
+// non-zero.
+func reportExportError(schemaPath string, err error) {
+	var validationErr *exportpkg.ValidationError
+	if errors.As(err, &validationErr) {
+		fmt.Printf("✗ %s does not match %s: %d violations, nothing was written\n",
+			validationErr.File, schemaPath, len(validationErr.Violations))
+		for _, v := range validationErr.Violations {
+			fmt.Printf("  %s\n", v)
+		}
+		os.Exit(1)
+	}
+	log.Fatalf("Failed to export: %v", err)
+}
+
+// loadExportData returns all tracks and events, with rules nested into
+// classes and classes into events.
+func loadExportData(db *sql.DB) ([]dbpkg.Track, []dbpkg.Event, error) {
+	tracks, err := dbpkg.ListTracks(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := dbpkg.ListEvents(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	classes, err := dbpkg.ListEventClasses(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := dbpkg.ListEventClassRules(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Nest rules into classes
+	rulesByClass := make(map[int64][]dbpkg.EventClassRule)
+	for _, r := range rules {
+		rulesByClass[r.EventClassID] = append(rulesByClass[r.EventClassID], r)
+	}
+	for i := range classes {
+		classes[i].Rules = rulesByClass[classes[i].ID]
+	}
+
+	// Nest classes into events
+	classesByEvent := make(map[int64][]dbpkg.EventClass)
+	for _, c := range classes {
+		classesByEvent[c.EventID] = append(classesByEvent[c.EventID], c)
+	}
+	for i := range events {
+		events[i].Classes = classesByEvent[events[i].ID]
+	}
+	return tracks, events, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	dbpkg "dfw-dragevents/tools/internal/db"
+	"dfw-dragevents/tools/internal/lint"
+)
+
+// lintData runs the data quality checks and exits with status 1 if any
+// reports an error, so it can gate an export.
+func lintData(db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	format := fs.String("format", "text", "output: text or json")
+	checks := fs.String("checks", "", "comma-separated checks to run (default all)")
+	disable := fs.String("disable", "", "comma-separated checks to skip")
+	pastDays := fs.Int("past-days", lint.DefaultPastDays, "days after it ends that an event is reported by past-events")
+	listChecks := fs.Bool("list-checks", false, "list the checks and exit")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Printf("Error: unexpected argument %q\n", fs.Arg(0))
+		fmt.Println("Usage: go run ./cmd lint [--checks=a,b|--disable=a,b] [--format=json] [--past-days=30] [--list-checks]")
+		fmt.Println("       go run ./cmd lint duplicates [--auto|--list] [--threshold=0.75]")
+		os.Exit(2)
+	}
+
+	if *listChecks {
+		for _, c := range lint.Checks {
+			fmt.Printf("%-20s %-8s %s\n", c.Name, c.Severity, c.Description)
+		}
+		return
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown lint format %q (use text or json)", *format)
+	}
+	selected, err := lint.Select(splitNames(*checks), splitNames(*disable))
+	if err != nil {
+		log.Fatalf("Failed to select checks: %v (see --list-checks)", err)
+	}
+
+	_, events, err := loadExportData(db)
+	if err != nil {
+		log.Fatalf("Failed to load events: %v", err)
+	}
+	findings := lint.Run(events, selected, lint.Options{PastDays: *pastDays})
+	errs := lint.Count(findings, lint.SeverityError)
+	warnings := lint.Count(findings, lint.SeverityWarning)
+	infos := lint.Count(findings, lint.SeverityInfo)
+
+	if *format == "json" {
+		out := struct {
+			Errors   int            `json:"errors"`
+			Warnings int            `json:"warnings"`
+			Info     int            `json:"info"`
+			Findings []lint.Finding `json:"findings"`
+		}{errs, warnings, infos, findings}
+		if out.Findings == nil {
+			out.Findings = []lint.Finding{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			log.Fatalf("Failed to write findings: %v", err)
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Printf("  %-7s %s\n", f.Severity, f)
+		}
+		if len(findings) == 0 {
+			fmt.Printf("✓ No problems found in %d events (%d checks)\n", len(events), len(selected))
+		} else {
+			mark := "✓"
+			if errs > 0 {
+				mark = "✗"
+			}
+			fmt.Printf("\n%s %d errors, %d warnings, %d info in %d events (%d checks)\n", mark, errs, warnings, infos, len(events), len(selected))
+		}
+	}
+	if errs > 0 {
+		os.Exit(1)
+	}
+}
+
+// splitNames splits a comma-separated flag value, dropping empty names.
+func splitNames(s string) []string {
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// lintDuplicates lists likely duplicate events and merges each cluster into
+// one event, asking which to keep unless --auto is given.
+func lintDuplicates(db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("lint duplicates", flag.ExitOnError)
+	auto := fs.Bool("auto", false, "merge clusters whose titles have the same words (give or take a typo) into their suggested event without asking")
+	list := fs.Bool("list", false, "only list the clusters")
+	threshold := fs.Float64("threshold", lint.DefaultSimilarity, "title similarity, 0 to 1, at which events are duplicates")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Printf("Error: unexpected argument %q\n", fs.Arg(0))
+		fmt.Println("Usage: go run ./cmd lint duplicates [--auto|--list] [--threshold=0.75]")
+		os.Exit(2)
+	}
+	if *auto && *list {
+		log.Fatalf("--auto and --list cannot be combined")
+	}
+
+	_, events, err := loadExportData(db)
+	if err != nil {
+		log.Fatalf("Failed to load events: %v", err)
+	}
+	clusters := lint.Duplicates(events, *threshold)
+	if len(clusters) == 0 {
+		fmt.Println("No likely duplicates found.")
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	merged := 0
+	for i, c := range clusters {
+		fmt.Printf("\n=== Likely duplicates %d of %d (title similarity %.2f) ===\n", i+1, len(clusters), c.Similarity)
+		for _, ev := range c.Events {
+			marker := " "
+			if ev.ID == c.Keep {
+				marker = "*"
+			}
+			fmt.Printf("%s [%d] %q  %s  %s  %s, %s\n", marker, ev.ID, ev.Title,
+				ev.StartDate.Format(dbpkg.DateLayout), ev.TrackName, ev.Source, describeDetail(ev))
+		}
+		if *list {
+			continue
+		}
+
+		keep := c.Keep
+		if *auto && c.Similarity < lint.AutoMergeSimilarity {
+			fmt.Println("Skipped: the titles differ; merge it without --auto")
+			continue
+		}
+		if !*auto {
+			fmt.Printf("Keep which event? [%d] (Enter keeps *, an ID keeps another, 's' skips, 'q' quits): ", keep)
+			if !scanner.Scan() {
+				fmt.Println()
+				break
+			}
+			answer := strings.TrimSpace(scanner.Text())
+			switch answer {
+			case "":
+			case "s":
+				continue
+			case "q":
+				fmt.Printf("\n✓ Merged %d of %d clusters\n", merged, len(clusters))
+				return
+			default:
+				id, err := strconv.ParseInt(answer, 10, 64)
+				if err != nil || !clusterHas(c, id) {
+					fmt.Printf("Skipped: %q is not an event of this cluster\n", answer)
+					continue
+				}
+				keep = id
+			}
+		}
+
+		var from []int64
+		var deleted []dbpkg.Event
+		for _, ev := range c.Events {
+			if ev.ID != keep {
+				from = append(from, ev.ID)
+				deleted = append(deleted, ev)
+			}
+		}
+		res, err := dbpkg.MergeEvents(db, keep, from)
+		if err != nil {
+			log.Fatalf("Failed to merge events into %d: %v", keep, err)
+		}
+		merged++
+		fmt.Printf("✓ Merged %v into event %d (%d classes and %d rules moved)\n", from, keep, res.Classes, res.Rules)
+		for _, ev := range deleted {
+			fmt.Printf("  deleted [%d] %q  %s\n", ev.ID, ev.Title, ev.StartDate.Format(dbpkg.DateLayout))
+		}
+		if res.Suppressed > 0 {
+			fmt.Printf("  %d aggregator events suppressed so re-imports do not bring them back\n", res.Suppressed)
+		}
+	}
+
+	if *list {
+		fmt.Printf("\nTotal: %d clusters (* is the suggested event to keep)\n", len(clusters))
+		return
+	}
+	fmt.Printf("\n✓ Merged %d of %d clusters\n", merged, len(clusters))
+	if merged > 0 {
+		fmt.Println("\nNext steps:")
+		fmt.Println("  1. Run 'make export' to update JSON files")
+	}
+}
+
+func clusterHas(c lint.Cluster, id int64) bool {
+	for _, ev := range c.Events {
+		if ev.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// describeDetail summarizes an event's classes and rules, e.g. "3 classes, 5 rules".
+func describeDetail(ev dbpkg.Event) string {
+	rules := 0
+	for _, c := range ev.Classes {
+		rules += len(c.Rules)
+	}
+	return fmt.Sprintf("%d classes, %d rules", len(ev.Classes), rules)
+}
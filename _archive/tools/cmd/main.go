@@ -3,25 +3,33 @@ package main
 import (
 	"bufio"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
 	dbpkg "dfw-dragevents/tools/internal/db"
-	exportpkg "dfw-dragevents/tools/internal/export"
 )
 
 func usage() {
-	fmt.Println("Usage:")
+	fmt.Println("Usage: go run ./cmd [--db <path>] <command>")
+	fmt.Println("  The database defaults to $DFW_DB_PATH, or db/db.sqlite when unset.")
 	fmt.Println("  Database:")
-	fmt.Println("    go run ./cmd db init           # apply migrations")
+	fmt.Println("    go run ./cmd db init           # apply pending migrations")
+	fmt.Println("    go run ./cmd db status         # list applied and pending migrations")
+	fmt.Println("    go run ./cmd db migrate --to <version> # migrate up or down to a version (0 = empty)")
+	fmt.Println("    go run ./cmd db rollback [N]   # revert the last N migrations (default 1)")
 	fmt.Println("    go run ./cmd db seed           # insert sample data")
+	fmt.Println("    go run ./cmd db normalize-dates # rewrite stored event dates as YYYY-MM-DD HH:MM:SS")
 	fmt.Println("  Tracks:")
 	fmt.Println("    go run ./cmd track add         # interactively add a track")
 	fmt.Println("    go run ./cmd track list        # list all tracks")
+	fmt.Println("    go run ./cmd track edit <id> [--name=... --city=... --address=... --url=...]")
+	fmt.Println("    go run ./cmd track delete <id> [--cascade] # delete a track (--cascade also deletes its events)")
+	fmt.Println("    go run ./cmd track merge <from> <into>     # move events to <into> and delete <from>")
 	fmt.Println("  Events:")
 	fmt.Println("    go run ./cmd event add         # interactively add an event")
 	fmt.Println("    go run ./cmd event list        # list all events")
@@ -29,26 +37,67 @@ func usage() {
 	fmt.Println("    go run ./cmd event import <csv> # import events from CSV")
 	fmt.Println("    go run ./cmd event import-classes <csv> # import event classes from CSV")
 	fmt.Println("    go run ./cmd event import-rules <csv>   # import class rules from CSV")
+	fmt.Println("    go run ./cmd event import-reschedules <csv> # link events to their replacements from CSV")
+	fmt.Println("    go run ./cmd event import-bundle <file> # import events with nested classes and rules (YAML or JSON)")
 	fmt.Println("    go run ./cmd event list-classes   # list all event classes")
+	fmt.Println("    (imports accept --dry-run to validate every line without saving,")
+	fmt.Println("     and CSV imports --upsert to update existing rows instead of duplicating them)")
+	fmt.Println("    go run ./cmd event edit <id> [--title=... --end-date=... --series=... ...] # edit an event")
+	fmt.Println("    go run ./cmd event cancel <id> [--rained-out] # mark an event cancelled or rained out")
+	fmt.Println("    go run ./cmd event postpone <id>")
+	fmt.Println("    go run ./cmd event reschedule <id> --to=<id> | --start-date=... [--end-date=...] # link or copy to a replacement")
+	fmt.Println("    go run ./cmd event reinstate <id> # put a postponed or cancelled event back on the schedule")
+	fmt.Println("  Aggregator:")
+	fmt.Println("    go run ./cmd import aggregator <events.json> [--dry-run] # import or update events from the aggregator's feed")
+	fmt.Println("    go run ./cmd event suppress <aggregator-id> [--reason=...] # leave an aggregator event out of the merge")
+	fmt.Println("    go run ./cmd event unsuppress <aggregator-id>")
+	fmt.Println("    go run ./cmd event list-suppressed")
+	fmt.Println("    go run ./cmd export merge <events.json> # write the aggregator's feed with local events and edits merged in")
+	fmt.Println("  Classes and rules:")
+	fmt.Println("    go run ./cmd class edit <id> [--name=... --buyin-fee=... --event-id=...]")
+	fmt.Println("    go run ./cmd rule edit <id> [--rule=... --class-id=...]")
+	fmt.Println("  Edit commands prompt with current values when no flags are given.")
+	fmt.Println("  Checks:")
+	fmt.Println("    go run ./cmd lint [--checks=a,b|--disable=a,b] [--format=json] [--past-days=30] # check data quality; exits 1 on errors")
+	fmt.Println("    go run ./cmd lint --list-checks")
+	fmt.Println("    go run ./cmd lint duplicates [--auto|--list] [--threshold=0.75] # find and merge likely duplicate events")
 	fmt.Println("  Export:")
 	fmt.Println("    go run ./cmd export            # write JSON to ../site/data/")
+	fmt.Println("    go run ./cmd export --format=sharded # also write tracks/<slug>.json, months/<YYYY-MM>.json and index.json")
+	fmt.Println("    go run ./cmd export --format=aggregator # write events.json in the site's events.schema.json shape")
+	fmt.Println("    go run ./cmd export --format=ics # write iCalendar feeds (all events, per track, per series)")
+	fmt.Println("    go run ./cmd export --format=atom # write events.atom, a feed of added and changed events")
+	fmt.Println("    go run ./cmd export csv <dir>  # write events, classes and rules as importable CSV")
+	fmt.Println("    go run ./cmd export html           # pre-render ../site/events/<uuid>.html and update sitemap.xml")
+}
+
+// defaultDBPath returns DFW_DB_PATH when set, otherwise dbpkg.DBPath.
+func defaultDBPath() string {
+	if p := os.Getenv("DFW_DB_PATH"); p != "" {
+		return p
+	}
+	return dbpkg.DBPath
 }
 
 func main() {
 	log.SetFlags(0)
-	if len(os.Args) < 2 {
+	flag.Usage = usage
+	dbPath := flag.String("db", defaultDBPath(), "path to the SQLite database")
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 1 {
 		usage()
 		os.Exit(2)
 	}
-	switch os.Args[1] {
+	switch args[0] {
 	case "db":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			usage()
 			os.Exit(2)
 		}
-		switch os.Args[2] {
+		switch args[1] {
 		case "init":
-			db, err := dbpkg.Open()
+			db, err := dbpkg.Open(*dbPath)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -57,8 +106,60 @@ func main() {
 				log.Fatal(err)
 			}
 			fmt.Println("Migrations applied.")
+		case "status":
+			db, err := dbpkg.Open(*dbPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer db.Close()
+			migrationStatus(db)
+		case "migrate":
+			fs := flag.NewFlagSet("db migrate", flag.ExitOnError)
+			to := fs.String("to", "", "target migration version (0 rolls back everything)")
+			fs.Parse(args[2:])
+			db, err := dbpkg.Open(*dbPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer db.Close()
+			if *to == "" {
+				if err := dbpkg.Migrate(db); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println("Migrations applied.")
+				return
+			}
+			migrateTo(db, *to)
+		case "rollback":
+			steps := 1
+			if len(args) > 2 {
+				n, err := strconv.Atoi(args[2])
+				if err != nil || n < 1 {
+					fmt.Println("Error: rollback count must be a positive integer")
+					fmt.Println("Usage: go run ./cmd db rollback [N]")
+					os.Exit(2)
+				}
+				steps = n
+			}
+			db, err := dbpkg.Open(*dbPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer db.Close()
+			rollbackMigrations(db, steps)
+		case "normalize-dates":
+			db, err := dbpkg.Open(*dbPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer db.Close()
+			n, err := dbpkg.NormalizeEventDates(db)
+			if err != nil {
+				log.Fatalf("Failed to normalize dates (nothing was changed): %v", err)
+			}
+			fmt.Printf("✓ Normalized dates of %d events\n", n)
 		case "seed":
-			db, err := dbpkg.Open()
+			db, err := dbpkg.Open(*dbPath)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -72,129 +173,248 @@ func main() {
 			os.Exit(2)
 		}
 	case "track":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			usage()
 			os.Exit(2)
 		}
-		db, err := dbpkg.Open()
+		db, err := dbpkg.Open(*dbPath)
 		if err != nil {
 			log.Fatal(err)
 		}
 		defer db.Close()
-		switch os.Args[2] {
+		switch args[1] {
 		case "add":
 			addTrackInteractive(db)
 		case "list":
 			listTracks(db)
+		case "edit":
+			editTrack(db, args[2:])
+		case "delete":
+			if len(args) < 3 {
+				fmt.Println("Error: track ID required")
+				fmt.Println("Usage: go run ./cmd track delete <id> [--cascade]")
+				os.Exit(2)
+			}
+			id, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid track ID: %v", err)
+			}
+			fs := flag.NewFlagSet("track delete", flag.ExitOnError)
+			cascade := fs.Bool("cascade", false, "also delete the track's events, classes and rules")
+			fs.Parse(args[3:])
+			deleteTrack(db, id, *cascade)
+		case "merge":
+			if len(args) < 4 {
+				fmt.Println("Error: source and target track IDs required")
+				fmt.Println("Usage: go run ./cmd track merge <from> <into>")
+				os.Exit(2)
+			}
+			fromID, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid track ID: %v", err)
+			}
+			intoID, err := strconv.ParseInt(args[3], 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid track ID: %v", err)
+			}
+			mergeTracks(db, fromID, intoID)
 		default:
 			usage()
 			os.Exit(2)
 		}
 	case "event":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			usage()
 			os.Exit(2)
 		}
-		db, err := dbpkg.Open()
+		db, err := dbpkg.Open(*dbPath)
 		if err != nil {
 			log.Fatal(err)
 		}
 		defer db.Close()
-		switch os.Args[2] {
+		switch args[1] {
 		case "add":
 			addEventInteractive(db)
 		case "list":
 			listEvents(db)
 		case "delete":
-			if len(os.Args) < 4 {
+			if len(args) < 3 {
 				fmt.Println("Error: event ID required")
 				fmt.Println("Usage: go run ./cmd event delete <id>")
 				os.Exit(2)
 			}
-			id, err := strconv.ParseInt(os.Args[3], 10, 64)
+			id, err := strconv.ParseInt(args[2], 10, 64)
 			if err != nil {
 				log.Fatalf("Invalid event ID: %v", err)
 			}
 			deleteEvent(db, id)
 		case "import":
-			if len(os.Args) < 4 {
+			if len(args) < 3 {
 				fmt.Println("Error: CSV file path required")
-				fmt.Println("Usage: go run ./cmd event import <csv_file>")
+				fmt.Println("Usage: go run ./cmd event import <csv_file> [--dry-run] [--upsert]")
 				os.Exit(2)
 			}
-			importEventsFromCSV(db, os.Args[3])
+			importEventsFromCSV(db, args[2], parseImportOptions("event import", args[3:]))
 		case "import-classes":
-			if len(os.Args) < 4 {
+			if len(args) < 3 {
 				fmt.Println("Error: CSV file path required")
-				fmt.Println("Usage: go run ./cmd event import-classes <csv_file>")
+				fmt.Println("Usage: go run ./cmd event import-classes <csv_file> [--dry-run] [--upsert]")
 				os.Exit(2)
 			}
-			importEventClassesFromCSV(db, os.Args[3])
+			importEventClassesFromCSV(db, args[2], parseImportOptions("event import-classes", args[3:]))
 		case "import-rules":
-			if len(os.Args) < 4 {
+			if len(args) < 3 {
 				fmt.Println("Error: CSV file path required")
-				fmt.Println("Usage: go run ./cmd event import-rules <csv_file>")
+				fmt.Println("Usage: go run ./cmd event import-rules <csv_file> [--dry-run] [--upsert]")
 				os.Exit(2)
 			}
-			importEventClassRulesFromCSV(db, os.Args[3])
+			importEventClassRulesFromCSV(db, args[2], parseImportOptions("event import-rules", args[3:]))
+		case "import-reschedules":
+			if len(args) < 3 {
+				fmt.Println("Error: CSV file path required")
+				fmt.Println("Usage: go run ./cmd event import-reschedules <csv_file> [--dry-run] [--upsert]")
+				os.Exit(2)
+			}
+			importEventReschedulesFromCSV(db, args[2], parseImportOptions("event import-reschedules", args[3:]))
+		case "import-bundle":
+			if len(args) < 3 {
+				fmt.Println("Error: bundle file path required")
+				fmt.Println("Usage: go run ./cmd event import-bundle <file.yaml|file.json> [--dry-run]")
+				os.Exit(2)
+			}
+			importEventBundle(db, args[2], parseImportOptions("event import-bundle", args[3:]))
 		case "list-classes":
 			listEventClasses(db)
+		case "suppress", "unsuppress":
+			if len(args) < 3 {
+				fmt.Println("Error: aggregator event ID required")
+				fmt.Printf("Usage: go run ./cmd event %s <aggregator-id>\n", args[1])
+				os.Exit(2)
+			}
+			if args[1] == "suppress" {
+				suppressEvent(db, args[2], args[3:])
+			} else {
+				unsuppressEvent(db, args[2])
+			}
+		case "list-suppressed":
+			listSuppressed(db)
+		case "edit":
+			editEvent(db, args[2:])
+		case "cancel", "postpone", "reinstate":
+			setEventStatus(db, args[1], args[2:])
+		case "reschedule":
+			rescheduleEvent(db, args[2:])
 		default:
 			usage()
 			os.Exit(2)
 		}
-	case "export":
-		db, err := dbpkg.Open()
+	case "class", "rule":
+		if len(args) < 2 || args[1] != "edit" {
+			usage()
+			os.Exit(2)
+		}
+		db, err := dbpkg.Open(*dbPath)
 		if err != nil {
 			log.Fatal(err)
 		}
 		defer db.Close()
-		tracks, err := dbpkg.ListTracks(db)
-		if err != nil {
-			log.Fatal(err)
+		if args[0] == "class" {
+			editEventClass(db, args[2:])
+		} else {
+			editEventClassRule(db, args[2:])
 		}
-		events, err := dbpkg.ListEvents(db)
-		if err != nil {
-			log.Fatal(err)
+	case "import":
+		if len(args) < 2 || args[1] != "aggregator" {
+			usage()
+			os.Exit(2)
+		}
+		if len(args) < 3 {
+			fmt.Println("Error: events.json path required")
+			fmt.Println("Usage: go run ./cmd import aggregator <events.json> [--dry-run]")
+			os.Exit(2)
 		}
-		classes, err := dbpkg.ListEventClasses(db)
+		db, err := dbpkg.Open(*dbPath)
 		if err != nil {
 			log.Fatal(err)
 		}
-		rules, err := dbpkg.ListEventClassRules(db)
+		defer db.Close()
+		importAggregator(db, args[2], parseImportOptions("import aggregator", args[3:]))
+	case "lint":
+		db, err := dbpkg.Open(*dbPath)
 		if err != nil {
 			log.Fatal(err)
 		}
-
-		// Nest rules into classes
-		rulesByClass := make(map[int64][]dbpkg.EventClassRule)
-		for _, r := range rules {
-			rulesByClass[r.EventClassID] = append(rulesByClass[r.EventClassID], r)
-		}
-		for i := range classes {
-			classes[i].Rules = rulesByClass[classes[i].ID]
-		}
-
-		// Nest classes into events
-		classesByEvent := make(map[int64][]dbpkg.EventClass)
-		for _, c := range classes {
-			classesByEvent[c.EventID] = append(classesByEvent[c.EventID], c)
-		}
-		for i := range events {
-			events[i].Classes = classesByEvent[events[i].ID]
+		defer db.Close()
+		if len(args) > 1 && args[1] == "duplicates" {
+			lintDuplicates(db, args[2:])
+		} else {
+			lintData(db, args[1:])
 		}
-
-		dataDir := filepath.Clean(filepath.Join("..", "site", "data"))
-		if err := exportpkg.All(dataDir, tracks, events); err != nil {
+	case "export":
+		db, err := dbpkg.Open(*dbPath)
+		if err != nil {
 			log.Fatal(err)
 		}
-		fmt.Println("Exported JSON to", dataDir)
+		defer db.Close()
+		exportSite(db, args[1:])
 	default:
 		usage()
 		os.Exit(2)
 	}
 }
 
+func migrationStatus(db *sql.DB) {
+	statuses, err := dbpkg.Status(db)
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+
+	fmt.Println("\n=== Migrations ===")
+	fmt.Println()
+	pending := 0
+	for _, s := range statuses {
+		if s.Applied {
+			appliedAt := ""
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(dbpkg.DateLayout)
+			}
+			fmt.Printf("  [applied] %s_%s  %s\n", s.Version, s.Name, appliedAt)
+		} else {
+			pending++
+			fmt.Printf("  [pending] %s_%s\n", s.Version, s.Name)
+		}
+	}
+	fmt.Printf("\nTotal: %d applied, %d pending\n", len(statuses)-pending, pending)
+}
+
+func migrateTo(db *sql.DB, version string) {
+	ran, err := dbpkg.MigrateTo(db, version)
+	for _, m := range ran {
+		fmt.Printf("✓ %s_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatalf("Failed to migrate to %s: %v", version, err)
+	}
+	if len(ran) == 0 {
+		fmt.Printf("Already at version %s.\n", version)
+		return
+	}
+	fmt.Printf("\nSchema is now at version %s.\n", version)
+}
+
+func rollbackMigrations(db *sql.DB, steps int) {
+	reverted, err := dbpkg.Rollback(db, steps)
+	for _, m := range reverted {
+		fmt.Printf("✓ Rolled back %s_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatalf("Failed to roll back: %v", err)
+	}
+	if len(reverted) == 0 {
+		fmt.Println("No applied migrations to roll back.")
+	}
+}
+
 func addTrackInteractive(db *sql.DB) {
 	scanner := bufio.NewScanner(os.Stdin)
 
@@ -229,8 +449,13 @@ func addTrackInteractive(db *sql.DB) {
 	scanner.Scan()
 	url := strings.TrimSpace(scanner.Text())
 
+	// Time zone
+	fmt.Printf("Time Zone [%s]: ", dbpkg.DefaultTimeZone)
+	scanner.Scan()
+	timeZone := strings.TrimSpace(scanner.Text())
+
 	// Create track
-	id, err := dbpkg.CreateTrack(db, name, city, address, url)
+	id, err := dbpkg.CreateTrackInZone(db, name, city, address, url, timeZone)
 	if err != nil {
 		log.Fatalf("Failed to create track: %v", err)
 	}
@@ -262,11 +487,41 @@ func listTracks(db *sql.DB) {
 		if t.URL != "" {
 			fmt.Printf("URL: %s\n", t.URL)
 		}
+		fmt.Printf("Time Zone: %s\n", t.TimeZone)
+		if t.Slug != "" {
+			fmt.Printf("Slug: %s\n", t.Slug)
+		}
 		fmt.Println()
 	}
 	fmt.Printf("Total: %d tracks\n", len(tracks))
 }
 
+func deleteTrack(db *sql.DB, trackID int64, cascade bool) {
+	deleted, err := dbpkg.DeleteTrack(db, trackID, cascade)
+	if errors.Is(err, dbpkg.ErrTrackInUse) {
+		log.Fatalf("Failed to delete track: %v\nMerge it into another track with 'track merge', or re-run with --cascade to delete its events too.", err)
+	}
+	if err != nil {
+		log.Fatalf("Failed to delete track: %v", err)
+	}
+	fmt.Printf("✓ Track %d deleted successfully!\n", trackID)
+	if deleted > 0 {
+		fmt.Printf("  %d events and their classes and rules were deleted.\n", deleted)
+	}
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'make export' to update JSON files")
+}
+
+func mergeTracks(db *sql.DB, fromID, intoID int64) {
+	moved, err := dbpkg.MergeTracks(db, fromID, intoID)
+	if err != nil {
+		log.Fatalf("Failed to merge tracks: %v", err)
+	}
+	fmt.Printf("✓ Merged track %d into track %d: %d events moved\n", fromID, intoID, moved)
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'make export' to update JSON files")
+}
+
 func addEventInteractive(db *sql.DB) {
 	scanner := bufio.NewScanner(os.Stdin)
 
@@ -289,7 +544,7 @@ func addEventInteractive(db *sql.DB) {
 	}
 
 	// Start Date
-	fmt.Print("Start Date (YYYY-MM-DD HH:MM:SS): ")
+	fmt.Print("Start Date, local time at the track (YYYY-MM-DD HH:MM:SS): ")
 	scanner.Scan()
 	startDate := strings.TrimSpace(scanner.Text())
 	if startDate == "" {
@@ -363,10 +618,17 @@ func listEvents(db *sql.DB) {
 	for _, e := range events {
 		fmt.Printf("ID: %d\n", e.ID)
 		fmt.Printf("Title: %s\n", e.Title)
+		if e.Status != dbpkg.StatusScheduled {
+			fmt.Printf("Status: %s", dbpkg.StatusLabel(e.Status))
+			if e.RescheduledTo != nil {
+				fmt.Printf(" (rescheduled to event %d)", *e.RescheduledTo)
+			}
+			fmt.Println()
+		}
 		fmt.Printf("Track: %s\n", e.TrackName)
-		fmt.Printf("Start: %s\n", e.StartDate.Format("2006-01-02 15:04:05"))
+		fmt.Printf("Start: %s %s\n", e.StartDate.Format(dbpkg.DateLayout), e.StartDate.Format("MST"))
 		if e.EndDate != nil {
-			fmt.Printf("End: %s\n", e.EndDate.Format("2006-01-02 15:04:05"))
+			fmt.Printf("End: %s %s\n", e.EndDate.Format(dbpkg.DateLayout), e.EndDate.Format("MST"))
 		}
 		if e.DriverFee != nil {
 			fmt.Printf("Driver Fee: $%.2f\n", *e.DriverFee)
@@ -394,39 +656,241 @@ func deleteEvent(db *sql.DB, eventID int64) {
 	fmt.Println("  1. Run 'make export' to update JSON files")
 }
 
-func importEventsFromCSV(db *sql.DB, filename string) {
-	count, err := dbpkg.ImportEventsFromCSV(db, filename)
+// setEventStatus runs "event cancel", "event postpone" or "event reinstate".
+func setEventStatus(db *sql.DB, command string, args []string) {
+	id := parseEditID(args, "event", "go run ./cmd event "+command+" <id>")
+	fs := flag.NewFlagSet("event "+command, flag.ExitOnError)
+	rainedOut := fs.Bool("rained-out", false, "the event was rained out rather than cancelled (cancel only)")
+	fs.Parse(args[1:])
+
+	status := map[string]string{
+		"cancel":    dbpkg.StatusCancelled,
+		"postpone":  dbpkg.StatusPostponed,
+		"reinstate": dbpkg.StatusScheduled,
+	}[command]
+	if *rainedOut {
+		if command != "cancel" {
+			log.Fatalf("--rained-out only applies to 'event cancel'")
+		}
+		status = dbpkg.StatusRainedOut
+	}
+	if err := dbpkg.SetEventStatus(db, id, status); err != nil {
+		log.Fatalf("Failed to %s event: %v", command, err)
+	}
+	fmt.Printf("✓ Event %d is now %s\n", id, dbpkg.StatusLabel(status))
+	fmt.Println("\nNext steps:")
+	if status == dbpkg.StatusPostponed || status == dbpkg.StatusRainedOut {
+		fmt.Printf("  1. Once there is a new date, run 'go run ./cmd event reschedule %d --start-date=...'\n", id)
+		fmt.Println("  2. Run 'make export' to update JSON files")
+		return
+	}
+	fmt.Println("  1. Run 'make export' to update JSON files")
+}
+
+// rescheduleEvent links an event to its replacement, given with --to or
+// created as a copy on --start-date.
+func rescheduleEvent(db *sql.DB, args []string) {
+	usageLine := "go run ./cmd event reschedule <id> --to=<replacement id> | --start-date=... [--end-date=...]"
+	id := parseEditID(args, "event", usageLine)
+	fs := flag.NewFlagSet("event reschedule", flag.ExitOnError)
+	to := fs.Int64("to", 0, "ID of an existing event that replaces this one")
+	startDate := fs.String("start-date", "", "new start date, local time at the track (YYYY-MM-DD HH:MM:SS); copies the event with its classes and rules")
+	endDate := fs.String("end-date", "", "new end date, local time at the track (with --start-date)")
+	fs.Parse(args[1:])
+	if (*to == 0) == (*startDate == "") {
+		fmt.Println("Error: give either --to or --start-date")
+		fmt.Println("Usage:", usageLine)
+		os.Exit(2)
+	}
+
+	replacement := *to
+	if replacement != 0 {
+		if err := dbpkg.RescheduleEvent(db, id, replacement); err != nil {
+			log.Fatalf("Failed to reschedule event: %v", err)
+		}
+	} else {
+		var err error
+		if replacement, err = dbpkg.RescheduleEventTo(db, id, *startDate, *endDate); err != nil {
+			log.Fatalf("Failed to reschedule event: %v", err)
+		}
+		fmt.Printf("✓ Event %d created on %s with the classes and rules of event %d\n", replacement, *startDate, id)
+	}
+	fmt.Printf("✓ Event %d is rescheduled to event %d\n", id, replacement)
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'make export' to update JSON files")
+}
+
+func suppressEvent(db *sql.DB, id string, args []string) {
+	fs := flag.NewFlagSet("event suppress", flag.ExitOnError)
+	reason := fs.String("reason", "", "why the aggregator's event is hidden")
+	fs.Parse(args)
+	if err := dbpkg.SuppressEvent(db, id, *reason); err != nil {
+		log.Fatalf("Failed to suppress event: %v", err)
+	}
+	fmt.Printf("✓ Aggregator event %s will be left out of the merged events.json\n", id)
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'go run ./cmd export merge <aggregator events.json>' to update the site")
+}
+
+func unsuppressEvent(db *sql.DB, id string) {
+	if err := dbpkg.UnsuppressEvent(db, id); err != nil {
+		log.Fatalf("Failed to unsuppress event: %v", err)
+	}
+	fmt.Printf("✓ Aggregator event %s will be merged again\n", id)
+}
+
+func listSuppressed(db *sql.DB) {
+	list, err := dbpkg.ListSuppressed(db)
+	if err != nil {
+		log.Fatalf("Failed to list suppressed events: %v", err)
+	}
+	if len(list) == 0 {
+		fmt.Println("No suppressed events.")
+		return
+	}
+	fmt.Println("\n=== Suppressed Aggregator Events ===")
+	fmt.Println()
+	for _, s := range list {
+		fmt.Printf("%s  %s", s.UUID, s.CreatedAt)
+		if s.Reason != "" {
+			fmt.Printf("  %s", s.Reason)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("\nTotal: %d suppressed\n", len(list))
+}
+
+func parseImportOptions(name string, args []string) dbpkg.ImportOptions {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "validate every line and report errors without saving")
+	upsert := fs.Bool("upsert", false, "update rows that already exist instead of adding duplicates")
+	fs.Parse(args)
+	return dbpkg.ImportOptions{DryRun: *dryRun, Upsert: *upsert}
+}
+
+// reportImportError prints every failed line and exits non-zero.
+func reportImportError(what, filename string, err error) {
+	var importErr *dbpkg.ImportError
+	if errors.As(err, &importErr) {
+		fmt.Printf("✗ %s: %d lines failed, nothing was imported\n", filename, len(importErr.Lines))
+		for _, l := range importErr.Lines {
+			fmt.Printf("  %v\n", l)
+		}
+		os.Exit(1)
+	}
+	log.Fatalf("Failed to import %s: %v", what, err)
+}
+
+// reportImport prints the outcome of an import. It returns false for a dry
+// run, after which there are no next steps to suggest.
+func reportImport(res dbpkg.ImportResult, what, filename string, opts dbpkg.ImportOptions) bool {
+	summary := ""
+	if opts.Upsert {
+		summary = fmt.Sprintf(" (%d created, %d updated, %d unchanged)", res.Created, res.Updated, res.Unchanged)
+	}
+	if opts.DryRun {
+		fmt.Printf("✓ Dry run: all %d %s in %s are valid%s; nothing was saved\n", res.Total(), what, filename, summary)
+		return false
+	}
+	fmt.Printf("✓ Successfully imported %d %s from %s%s\n", res.Total(), what, filename, summary)
+	return true
+}
+
+func importEventsFromCSV(db *sql.DB, filename string, opts dbpkg.ImportOptions) {
+	res, err := dbpkg.ImportEventsFromCSVWithOptions(db, filename, opts)
 	if err != nil {
-		log.Fatalf("Failed to import events: %v", err)
+		reportImportError("events", filename, err)
+	}
+	if !reportImport(res, "events", filename, opts) {
+		return
 	}
-	fmt.Printf("✓ Successfully imported %d events from %s\n", count, filename)
 	fmt.Println("\nNext steps:")
 	fmt.Println("  1. Run 'make export' to generate JSON files")
 	fmt.Println("  2. Test locally with 'python -m http.server 8000' in the site/ directory")
 }
 
-func importEventClassesFromCSV(db *sql.DB, filename string) {
-	count, err := dbpkg.ImportEventClassesFromCSV(db, filename)
+func importEventClassesFromCSV(db *sql.DB, filename string, opts dbpkg.ImportOptions) {
+	res, err := dbpkg.ImportEventClassesFromCSVWithOptions(db, filename, opts)
 	if err != nil {
-		log.Fatalf("Failed to import event classes: %v", err)
+		reportImportError("event classes", filename, err)
+	}
+	if !reportImport(res, "event classes", filename, opts) {
+		return
 	}
-	fmt.Printf("✓ Successfully imported %d event classes from %s\n", count, filename)
 	fmt.Println("\nNext steps:")
 	fmt.Println("  1. Import rules with 'make event-import-rules FILE=rules.csv'")
 	fmt.Println("  2. Run 'make export' to generate JSON files")
 }
 
-func importEventClassRulesFromCSV(db *sql.DB, filename string) {
-	count, err := dbpkg.ImportEventClassRulesFromCSV(db, filename)
+func importEventClassRulesFromCSV(db *sql.DB, filename string, opts dbpkg.ImportOptions) {
+	res, err := dbpkg.ImportEventClassRulesFromCSVWithOptions(db, filename, opts)
+	if err != nil {
+		reportImportError("event class rules", filename, err)
+	}
+	if !reportImport(res, "rules", filename, opts) {
+		return
+	}
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'make export' to generate JSON files")
+	fmt.Println("  2. Test locally with 'python -m http.server 8000' in the site/ directory")
+}
+
+func importEventReschedulesFromCSV(db *sql.DB, filename string, opts dbpkg.ImportOptions) {
+	res, err := dbpkg.ImportEventReschedulesFromCSVWithOptions(db, filename, opts)
 	if err != nil {
-		log.Fatalf("Failed to import event class rules: %v", err)
+		reportImportError("event reschedules", filename, err)
+	}
+	if !reportImport(res, "reschedules", filename, opts) {
+		return
 	}
-	fmt.Printf("✓ Successfully imported %d rules from %s\n", count, filename)
 	fmt.Println("\nNext steps:")
 	fmt.Println("  1. Run 'make export' to generate JSON files")
 	fmt.Println("  2. Test locally with 'python -m http.server 8000' in the site/ directory")
 }
 
+func importEventBundle(db *sql.DB, filename string, opts dbpkg.ImportOptions) {
+	if opts.Upsert {
+		log.Fatalf("event import-bundle does not support --upsert")
+	}
+	res, err := dbpkg.ImportBundleFile(db, filename, opts)
+	if err != nil {
+		log.Fatalf("Failed to import bundle (nothing was imported): %v", err)
+	}
+	summary := fmt.Sprintf("%d events, %d classes and %d rules", res.Events, res.Classes, res.Rules)
+	if opts.DryRun {
+		fmt.Printf("✓ Dry run: %s in %s are valid; nothing was saved\n", summary, filename)
+		return
+	}
+	fmt.Printf("✓ Successfully imported %s from %s\n", summary, filename)
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'make export' to generate JSON files")
+	fmt.Println("  2. Test locally with 'python -m http.server 8000' in the site/ directory")
+}
+
+func importAggregator(db *sql.DB, filename string, opts dbpkg.ImportOptions) {
+	if opts.Upsert {
+		log.Fatalf("import aggregator always updates existing events; drop --upsert")
+	}
+	res, err := dbpkg.ImportAggregatorFile(db, filename, opts)
+	if err != nil {
+		log.Fatalf("Failed to import aggregator feed (nothing was imported): %v", err)
+	}
+	summary := fmt.Sprintf("%d events created, %d updated, %d unchanged; %d tracks created, %d linked by name; %d classes and %d rules added, %d removed",
+		res.Events.Created, res.Events.Updated, res.Events.Unchanged, res.TracksCreated, res.TracksLinked,
+		res.Classes.Created, res.Rules.Created, res.Removed)
+	if opts.DryRun {
+		fmt.Printf("✓ Dry run: %s would be imported: %s; nothing was saved\n", filename, summary)
+	} else {
+		fmt.Printf("✓ Imported %s: %s\n", filename, summary)
+	}
+	if res.SkippedLocal > 0 {
+		fmt.Printf("  %d records skipped: their UUID belongs to an event entered locally\n", res.SkippedLocal)
+	}
+	if res.Suppressed > 0 {
+		fmt.Printf("  %d records skipped: suppressed with 'event suppress'\n", res.Suppressed)
+	}
+}
+
 func listEventClasses(db *sql.DB) {
 	classes, err := dbpkg.ListEventClasses(db)
 	if err != nil {
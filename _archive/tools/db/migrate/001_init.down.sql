@@ -0,0 +1,5 @@
+-- drop child tables first; their indexes are dropped with them
+DROP TABLE IF EXISTS event_class_rules;
+DROP TABLE IF EXISTS event_classes;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS tracks;
@@ -0,0 +1,5 @@
+-- Remove end_date column from events table
+-- SQLite cannot drop an indexed column in place, so the migrator rebuilds
+-- the table without it (see internal/db/rebuild.go)
+DROP INDEX IF EXISTS idx_events_end_date;
+ALTER TABLE events DROP COLUMN end_date;
@@ -0,0 +1,3 @@
+-- Remove external_id column from events table
+DROP INDEX IF EXISTS idx_events_external_id;
+ALTER TABLE events DROP COLUMN external_id;
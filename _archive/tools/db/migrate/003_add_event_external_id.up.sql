@@ -0,0 +1,5 @@
+-- Add a stable identity for events imported from outside sources, so that
+-- re-running an import updates the event instead of adding a duplicate
+ALTER TABLE events ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_external_id ON events(external_id);
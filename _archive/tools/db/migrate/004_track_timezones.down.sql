@@ -0,0 +1,3 @@
+-- Remove timezone column from tracks table; event times are converted back
+-- to track-local time first
+ALTER TABLE tracks DROP COLUMN timezone;
@@ -0,0 +1,4 @@
+-- Add each track's IANA time zone. Event times are entered in the track's
+-- local time and stored in UTC; the Go step registered for this migration
+-- (internal/db/timezone.go) converts existing events to UTC
+ALTER TABLE tracks ADD COLUMN timezone TEXT NOT NULL DEFAULT 'America/Chicago';
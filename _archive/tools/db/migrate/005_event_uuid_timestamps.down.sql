@@ -0,0 +1,5 @@
+-- Remove the event UUID and timestamps
+DROP INDEX IF EXISTS idx_events_uuid;
+ALTER TABLE events DROP COLUMN updated_at;
+ALTER TABLE events DROP COLUMN created_at;
+ALTER TABLE events DROP COLUMN uuid;
@@ -0,0 +1,16 @@
+-- Give every event a stable UUID and creation/modification times, as the
+-- aggregator schema the site reads requires. Existing events get a random
+-- version 4 UUID and the time of the migration.
+ALTER TABLE events ADD COLUMN uuid TEXT;
+ALTER TABLE events ADD COLUMN created_at DATETIME;
+ALTER TABLE events ADD COLUMN updated_at DATETIME;
+
+UPDATE events SET
+  uuid = lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+    substr(lower(hex(randomblob(2))), 2) || '-' ||
+    substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' ||
+    lower(hex(randomblob(6))),
+  created_at = datetime('now'),
+  updated_at = datetime('now');
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_uuid ON events(uuid);
@@ -0,0 +1,3 @@
+-- Remove series column from events table
+DROP INDEX IF EXISTS idx_events_series;
+ALTER TABLE events DROP COLUMN series;
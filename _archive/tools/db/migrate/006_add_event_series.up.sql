@@ -0,0 +1,5 @@
+-- Add the series an event belongs to (e.g. TMCCC, NHRA), so events can be
+-- grouped into per-series calendars and exported with the aggregator's series
+ALTER TABLE events ADD COLUMN series TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_events_series ON events(series);
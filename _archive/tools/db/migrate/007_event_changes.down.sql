@@ -0,0 +1,10 @@
+-- Remove the event change log and its triggers
+DROP TRIGGER IF EXISTS event_class_rules_log_delete;
+DROP TRIGGER IF EXISTS event_class_rules_log_update;
+DROP TRIGGER IF EXISTS event_class_rules_log_insert;
+DROP TRIGGER IF EXISTS event_classes_log_delete;
+DROP TRIGGER IF EXISTS event_classes_log_update;
+DROP TRIGGER IF EXISTS event_classes_log_insert;
+DROP TRIGGER IF EXISTS events_log_update;
+DROP TRIGGER IF EXISTS events_log_insert;
+DROP TABLE IF EXISTS event_changes;
@@ -0,0 +1,86 @@
+-- Log what changes about each event, for the Atom feed of new and changed
+-- events. Triggers record every write, whichever command or import made it,
+-- and class and rule changes also bump the event's updated_at.
+CREATE TABLE IF NOT EXISTS event_changes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_id INTEGER NOT NULL,
+  changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  summary TEXT NOT NULL,
+  FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_changes_event_id ON event_changes(event_id);
+CREATE INDEX IF NOT EXISTS idx_event_changes_changed_at ON event_changes(changed_at);
+
+-- existing events count as added when they were created
+INSERT INTO event_changes(event_id, changed_at, summary)
+  SELECT id, COALESCE(created_at, CURRENT_TIMESTAMP), 'added' FROM events;
+
+CREATE TRIGGER IF NOT EXISTS events_log_insert AFTER INSERT ON events
+BEGIN
+  INSERT INTO event_changes(event_id, summary) VALUES (NEW.id, 'added');
+END;
+
+CREATE TRIGGER IF NOT EXISTS events_log_update AFTER UPDATE ON events
+BEGIN
+  INSERT INTO event_changes(event_id, summary)
+    SELECT NEW.id, 'renamed' WHERE OLD.title IS NOT NEW.title
+    UNION ALL
+    SELECT NEW.id, 'date moved' WHERE OLD.event_datetime IS NOT NEW.event_datetime OR OLD.end_date IS NOT NEW.end_date
+    UNION ALL
+    SELECT NEW.id, 'track changed' WHERE OLD.track_id IS NOT NEW.track_id
+    UNION ALL
+    SELECT NEW.id, 'fees changed' WHERE OLD.event_driver_fee IS NOT NEW.event_driver_fee OR OLD.event_spectator_fee IS NOT NEW.event_spectator_fee
+    UNION ALL
+    SELECT NEW.id, 'details updated' WHERE OLD.url IS NOT NEW.url OR OLD.description IS NOT NEW.description
+    UNION ALL
+    SELECT NEW.id, 'series changed' WHERE OLD.series IS NOT NEW.series;
+END;
+
+CREATE TRIGGER IF NOT EXISTS event_classes_log_insert AFTER INSERT ON event_classes
+BEGIN
+  INSERT INTO event_changes(event_id, summary) VALUES (NEW.event_id, 'classes added');
+  UPDATE events SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.event_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS event_classes_log_update AFTER UPDATE ON event_classes
+BEGIN
+  INSERT INTO event_changes(event_id, summary) VALUES (NEW.event_id, 'classes changed');
+  UPDATE events SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.event_id;
+  INSERT INTO event_changes(event_id, summary)
+    SELECT OLD.event_id, 'classes removed' WHERE OLD.event_id IS NOT NEW.event_id;
+  UPDATE events SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.event_id AND OLD.event_id IS NOT NEW.event_id;
+END;
+
+-- when the event itself is being deleted its classes go with it; there is
+-- nothing left to log against
+CREATE TRIGGER IF NOT EXISTS event_classes_log_delete AFTER DELETE ON event_classes
+BEGIN
+  INSERT INTO event_changes(event_id, summary)
+    SELECT id, 'classes removed' FROM events WHERE id = OLD.event_id;
+  UPDATE events SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.event_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS event_class_rules_log_insert AFTER INSERT ON event_class_rules
+BEGIN
+  INSERT INTO event_changes(event_id, summary)
+    SELECT event_id, 'rules changed' FROM event_classes WHERE id = NEW.event_class_id;
+  UPDATE events SET updated_at = CURRENT_TIMESTAMP
+    WHERE id = (SELECT event_id FROM event_classes WHERE id = NEW.event_class_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS event_class_rules_log_update AFTER UPDATE ON event_class_rules
+BEGIN
+  INSERT INTO event_changes(event_id, summary)
+    SELECT event_id, 'rules changed' FROM event_classes WHERE id IN (OLD.event_class_id, NEW.event_class_id);
+  UPDATE events SET updated_at = CURRENT_TIMESTAMP
+    WHERE id IN (SELECT event_id FROM event_classes WHERE id IN (OLD.event_class_id, NEW.event_class_id));
+END;
+
+CREATE TRIGGER IF NOT EXISTS event_class_rules_log_delete AFTER DELETE ON event_class_rules
+BEGIN
+  INSERT INTO event_changes(event_id, summary)
+    SELECT event_id, 'rules changed' FROM event_classes WHERE id = OLD.event_class_id;
+  UPDATE events SET updated_at = CURRENT_TIMESTAMP
+    WHERE id = (SELECT event_id FROM event_classes WHERE id = OLD.event_class_id);
+END;
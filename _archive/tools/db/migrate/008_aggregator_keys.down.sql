@@ -0,0 +1,4 @@
+-- Remove the aggregator's keys
+DROP INDEX IF EXISTS idx_tracks_slug;
+ALTER TABLE events DROP COLUMN source;
+ALTER TABLE tracks DROP COLUMN slug;
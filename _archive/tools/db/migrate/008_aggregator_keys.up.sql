@@ -0,0 +1,8 @@
+-- Keep the aggregator's keys so its events.json can be imported again without
+-- duplicating rows: tracks get the aggregator's track ID (e.g. xtreme-tx) as
+-- their slug, imported events keep the aggregator's id as their uuid, and
+-- source tells imported events from ones entered here.
+ALTER TABLE tracks ADD COLUMN slug TEXT;
+ALTER TABLE events ADD COLUMN source TEXT NOT NULL DEFAULT 'local';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tracks_slug ON tracks(slug);
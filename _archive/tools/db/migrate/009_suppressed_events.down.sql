@@ -0,0 +1,2 @@
+-- Remove suppressed_events table
+DROP TABLE IF EXISTS suppressed_events;
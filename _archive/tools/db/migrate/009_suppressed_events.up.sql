@@ -0,0 +1,7 @@
+-- Aggregator events to leave out of the merged events.json, by the
+-- aggregator's id, e.g. because it misread a flyer or listed an event twice
+CREATE TABLE IF NOT EXISTS suppressed_events (
+  uuid TEXT PRIMARY KEY,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Remove event status
+ALTER TABLE events DROP COLUMN rescheduled_to;
+ALTER TABLE events DROP COLUMN status;
@@ -0,0 +1,6 @@
+-- Event status, so a cancelled or postponed event keeps its title and stays
+-- listed as such: scheduled, postponed, cancelled or rained_out. Allowed
+-- values and transitions are checked by the tools (internal/db/status.go).
+-- rescheduled_to links a postponed or rained out event to its replacement.
+ALTER TABLE events ADD COLUMN status TEXT NOT NULL DEFAULT 'scheduled';
+ALTER TABLE events ADD COLUMN rescheduled_to INTEGER REFERENCES events(id) ON DELETE SET NULL;
@@ -0,0 +1,18 @@
+-- Stop logging status changes: restore the events_log_update trigger of 007
+DROP TRIGGER IF EXISTS events_log_update;
+
+CREATE TRIGGER IF NOT EXISTS events_log_update AFTER UPDATE ON events
+BEGIN
+  INSERT INTO event_changes(event_id, summary)
+    SELECT NEW.id, 'renamed' WHERE OLD.title IS NOT NEW.title
+    UNION ALL
+    SELECT NEW.id, 'date moved' WHERE OLD.event_datetime IS NOT NEW.event_datetime OR OLD.end_date IS NOT NEW.end_date
+    UNION ALL
+    SELECT NEW.id, 'track changed' WHERE OLD.track_id IS NOT NEW.track_id
+    UNION ALL
+    SELECT NEW.id, 'fees changed' WHERE OLD.event_driver_fee IS NOT NEW.event_driver_fee OR OLD.event_spectator_fee IS NOT NEW.event_spectator_fee
+    UNION ALL
+    SELECT NEW.id, 'details updated' WHERE OLD.url IS NOT NEW.url OR OLD.description IS NOT NEW.description
+    UNION ALL
+    SELECT NEW.id, 'series changed' WHERE OLD.series IS NOT NEW.series;
+END;
@@ -0,0 +1,2 @@
+-- Remove the record of locally edited event fields
+ALTER TABLE events DROP COLUMN local_fields;
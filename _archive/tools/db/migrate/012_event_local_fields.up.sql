@@ -0,0 +1,4 @@
+-- The fields of an event edited here, comma-separated (e.g. "title,classes"),
+-- so the merged events.json takes only those from the local copy of an
+-- aggregator event and keeps the feed's corrections to the rest
+ALTER TABLE events ADD COLUMN local_fields TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+// Package migrate embeds the SQL migration files so the CLI can apply them
+// regardless of the working directory it is run from.
+package migrate
+
+import "embed"
+
+// FS holds every *.sql file in this directory.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,340 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Event sources: where an events row came from.
+const (
+	SourceLocal      = "local"      // entered or imported here
+	SourceAggregator = "aggregator" // imported from the aggregator's events.json
+)
+
+// AggregatorRecord is one event of the aggregator's events.json, the flat
+// list the site reads. Fields the database has no place for, such as the
+// raw fee text, confidence and flyers, are not decoded.
+type AggregatorRecord struct {
+	ID           string        `json:"id"` // UUID
+	Title        string        `json:"title"`
+	Series       string        `json:"series"`
+	TrackID      string        `json:"track_id"` // slug, e.g. xtreme-tx
+	TrackName    string        `json:"track_name"`
+	TrackCity    string        `json:"track_city"`
+	TrackState   string        `json:"track_state"`
+	StartDate    string        `json:"start_date"` // local time at the track
+	EndDate      string        `json:"end_date"`
+	Description  string        `json:"description"`
+	DriverFee    *float64      `json:"event_driver_fee"`
+	SpectatorFee *float64      `json:"event_spectator_fee"`
+	URL          string        `json:"url"`
+	Classes      []BundleClass `json:"classes"`
+}
+
+// AggregatorResult counts what ImportAggregator did.
+type AggregatorResult struct {
+	TracksCreated int // tracks the feed introduced
+	TracksLinked  int // existing tracks matched by name and given the feed's slug
+	Events        ImportResult
+	Classes       ImportResult
+	Rules         ImportResult
+	Removed       int // classes and rules of imported events no longer in the feed
+	SkippedLocal  int // records whose UUID belongs to a local event, left alone
+	Suppressed    int // records hidden with SuppressEvent, not imported
+}
+
+// LoadAggregatorFeed reads the aggregator's events.json: a list of records,
+// or an object with an "events" list.
+func LoadAggregatorFeed(filename string) ([]AggregatorRecord, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read aggregator feed: %w", err)
+	}
+	var records []AggregatorRecord
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &records)
+	} else {
+		var feed struct {
+			Events []AggregatorRecord `json:"events"`
+		}
+		err = json.Unmarshal(data, &feed)
+		records = feed.Events
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse aggregator feed: %w", err)
+	}
+	return records, nil
+}
+
+// ImportAggregatorFile loads the aggregator's events.json and imports it
+// with ImportAggregator.
+func ImportAggregatorFile(db *sql.DB, filename string, opts ImportOptions) (AggregatorResult, error) {
+	records, err := LoadAggregatorFeed(filename)
+	if err != nil {
+		return AggregatorResult{}, err
+	}
+	return ImportAggregator(db, records, opts)
+}
+
+// ImportAggregator maps aggregator records onto tracks, events, classes and
+// rules in a single transaction; if any record fails, nothing is imported.
+//
+// The aggregator's keys are kept so the feed can be imported again: tracks
+// are found by slug, or else by name, in which case the track is given the
+// slug; events are found by UUID. An event imported before is updated to
+// match the feed, with classes and rules missing from the feed removed. An
+// event entered here that shares a record's UUID is never touched, and
+// suppressed records are not imported. Events that drop out of the feed are
+// kept.
+func ImportAggregator(db *sql.DB, records []AggregatorRecord, opts ImportOptions) (AggregatorResult, error) {
+	if len(records) == 0 {
+		return AggregatorResult{}, fmt.Errorf("aggregator feed has no events")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return AggregatorResult{}, err
+	}
+	defer tx.Rollback()
+
+	var res AggregatorResult
+	seen := make(map[string]bool, len(records))
+	for i, rec := range records {
+		id := strings.ToLower(strings.TrimSpace(rec.ID))
+		if seen[id] {
+			return AggregatorResult{}, fmt.Errorf("events[%d] (%q): duplicate id %s", i, rec.Title, id)
+		}
+		seen[id] = true
+		if err := importAggregatorRecord(tx, rec, &res); err != nil {
+			return AggregatorResult{}, fmt.Errorf("events[%d] (%q): %w", i, rec.Title, err)
+		}
+	}
+
+	if opts.DryRun {
+		return res, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return AggregatorResult{}, err
+	}
+	return res, nil
+}
+
+func importAggregatorRecord(tx *sql.Tx, rec AggregatorRecord, res *AggregatorResult) error {
+	id := strings.ToLower(strings.TrimSpace(rec.ID))
+	if id == "" {
+		return fmt.Errorf("id is required")
+	}
+	title := strings.TrimSpace(rec.Title)
+	if title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if strings.TrimSpace(rec.StartDate) == "" {
+		return fmt.Errorf("start_date is required")
+	}
+	var suppressed bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM suppressed_events WHERE uuid = ?)`, id).Scan(&suppressed); err != nil {
+		return err
+	}
+	if suppressed {
+		res.Suppressed++
+		return nil
+	}
+
+	trackID, err := resolveAggregatorTrack(tx, rec, res)
+	if err != nil {
+		return err
+	}
+	f := eventFields{
+		Title:        title,
+		TrackID:      trackID,
+		StartDate:    strings.TrimSpace(rec.StartDate),
+		EndDate:      strings.TrimSpace(rec.EndDate),
+		DriverFee:    rec.DriverFee,
+		SpectatorFee: rec.SpectatorFee,
+		URL:          strings.TrimSpace(rec.URL),
+		Description:  strings.TrimSpace(rec.Description),
+		Series:       strings.TrimSpace(rec.Series),
+		UUID:         id,
+		Source:       SourceAggregator,
+	}
+	if err := f.normalizeDates(tx); err != nil {
+		return err
+	}
+
+	var eventID int64
+	var source string
+	err = tx.QueryRow(`SELECT id, source FROM events WHERE uuid = ?`, id).Scan(&eventID, &source)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if eventID, err = insertNormalizedEvent(tx, f); err != nil {
+			return fmt.Errorf("create event: %w", err)
+		}
+		res.Events.add(rowCreated)
+	case err != nil:
+		return err
+	case source != SourceAggregator:
+		res.SkippedLocal++
+		return nil
+	default:
+		existing, err := scanExistingEvents(tx, `SELECT id, title, track_id, CAST(event_datetime AS TEXT), COALESCE(CAST(end_date AS TEXT), ''),
+			event_driver_fee, event_spectator_fee, COALESCE(url, ''), COALESCE(description, ''), COALESCE(external_id, ''), COALESCE(series, ''), status
+			FROM events WHERE id = ?`, eventID)
+		if err != nil {
+			return err
+		}
+		f.ExternalID = existing[0].ExternalID
+		outcome, err := updateExistingEvent(tx, existing[0], f)
+		if err != nil {
+			return err
+		}
+		res.Events.add(outcome)
+	}
+	return syncAggregatorClasses(tx, eventID, rec.Classes, res)
+}
+
+// resolveAggregatorTrack returns the track of a record: the track with the
+// record's slug, else the track with its name, which is given the slug, else
+// a new track.
+func resolveAggregatorTrack(tx *sql.Tx, rec AggregatorRecord, res *AggregatorResult) (int64, error) {
+	slug := strings.TrimSpace(rec.TrackID)
+	name := strings.TrimSpace(rec.TrackName)
+	if slug == "" {
+		return 0, fmt.Errorf("track_id is required")
+	}
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM tracks WHERE slug = ?`, slug).Scan(&id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return id, err
+	}
+	if name == "" {
+		return 0, fmt.Errorf("track %q: track_name is required for a new track", slug)
+	}
+
+	var current string
+	err = tx.QueryRow(`SELECT id, COALESCE(slug, '') FROM tracks WHERE name = ? COLLATE NOCASE ORDER BY id LIMIT 1`, name).Scan(&id, &current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return 0, err
+	case current != "":
+		return 0, fmt.Errorf("track %q is %s in the database, not %s", name, current, slug)
+	default:
+		if _, err := tx.Exec(`UPDATE tracks SET slug = ? WHERE id = ?`, slug, id); err != nil {
+			return 0, fmt.Errorf("set slug of track %d: %w", id, err)
+		}
+		res.TracksLinked++
+		return id, nil
+	}
+
+	city := strings.TrimSpace(rec.TrackCity)
+	address := city
+	if state := strings.TrimSpace(rec.TrackState); state != "" {
+		address = strings.TrimPrefix(city+", "+state, ", ")
+	}
+	r, err := tx.Exec(`INSERT INTO tracks(name, city, address, url, timezone, slug) VALUES(?, ?, ?, '', ?, ?)`,
+		name, city, address, DefaultTimeZone, slug)
+	if err != nil {
+		return 0, fmt.Errorf("create track %q: %w", name, err)
+	}
+	res.TracksCreated++
+	return r.LastInsertId()
+}
+
+// syncAggregatorClasses makes an imported event's classes and rules match
+// the feed's.
+func syncAggregatorClasses(tx *sql.Tx, eventID int64, classes []BundleClass, res *AggregatorResult) error {
+	keep := make(map[string]bool, len(classes))
+	for i, c := range classes {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			return fmt.Errorf("classes[%d]: name is required", i)
+		}
+		keep[strings.ToLower(name)] = true
+		outcome, err := upsertEventClass(tx, eventID, name, c.BuyinFee)
+		if err != nil {
+			return fmt.Errorf("classes[%d] (%q): %w", i, name, err)
+		}
+		res.Classes.add(outcome)
+
+		var classID int64
+		if err := tx.QueryRow(`SELECT id FROM event_classes WHERE event_id = ? AND name = ? COLLATE NOCASE`,
+			eventID, name).Scan(&classID); err != nil {
+			return err
+		}
+		rules := make([]string, 0, len(c.Rules))
+		for j, rule := range c.Rules {
+			text := strings.TrimSpace(string(rule))
+			if text == "" {
+				return fmt.Errorf("classes[%d] (%q): rules[%d]: rule is empty", i, name, j)
+			}
+			outcome, err := upsertEventClassRule(tx, classID, text)
+			if err != nil {
+				return fmt.Errorf("classes[%d] (%q): rules[%d]: %w", i, name, j, err)
+			}
+			res.Rules.add(outcome)
+			rules = append(rules, text)
+		}
+		n, err := deleteRulesExcept(tx, classID, rules)
+		if err != nil {
+			return err
+		}
+		res.Removed += n
+	}
+
+	rows, err := tx.Query(`SELECT id, name FROM event_classes WHERE event_id = ?`, eventID)
+	if err != nil {
+		return err
+	}
+	var stale []int64
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		if !keep[strings.ToLower(name)] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range stale {
+		r, err := tx.Exec(`DELETE FROM event_class_rules WHERE event_class_id = ?`, id)
+		if err != nil {
+			return err
+		}
+		n, _ := r.RowsAffected()
+		if _, err := tx.Exec(`DELETE FROM event_classes WHERE id = ?`, id); err != nil {
+			return err
+		}
+		res.Removed += int(n) + 1
+	}
+	return nil
+}
+
+// deleteRulesExcept deletes the class's rules whose text is not in keep and
+// returns how many it deleted.
+func deleteRulesExcept(tx *sql.Tx, classID int64, keep []string) (int, error) {
+	q := `DELETE FROM event_class_rules WHERE event_class_id = ?`
+	args := []any{classID}
+	if len(keep) > 0 {
+		q += ` AND rule NOT IN (?` + strings.Repeat(", ?", len(keep)-1) + `)`
+		for _, r := range keep {
+			args = append(args, r)
+		}
+	}
+	r, err := tx.Exec(q, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.RowsAffected()
+	return int(n), err
+}
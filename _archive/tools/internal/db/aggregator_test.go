@@ -0,0 +1,304 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAggregatorFeed = `[
+  {
+    "id": "57BC97B9-F865-436D-9D4F-190B56C92A25",
+    "title": "TMCCC Race #2",
+    "event_type": "points_race",
+    "series": "TMCCC",
+    "track_id": "thunder-valley-ok",
+    "track_name": "Thunder Valley Raceway Park",
+    "track_city": "Lexington",
+    "track_state": "OK",
+    "start_date": "2026-04-12T08:00:00",
+    "end_date": "2026-04-12T23:59:59",
+    "description": "1/4 Mile",
+    "event_driver_fee": 40,
+    "event_spectator_fee": null,
+    "raw_driver_fee": "$40",
+    "url": "https://www.thundervalleyracewaypark.com",
+    "classes": [
+      {"name": "Stock Muscle", "buyin_fee": 40, "rules": ["1/8 mile- 9.40 & Slower"]},
+      {"name": "Test N Tune", "buyin_fee": null, "rules": []}
+    ],
+    "confidence": 0.95,
+    "flyers": [{"file": "https://tmccc.org/events"}]
+  },
+  {
+    "id": "0d5f3c1e-2b6a-4c8e-9f10-1a2b3c4d5e6f",
+    "title": "Friday Night Drags",
+    "track_id": "xtreme-tx",
+    "track_name": "Xtreme Raceway Park",
+    "track_city": "Ferris",
+    "track_state": "TX",
+    "start_date": "2026-04-17T18:00:00",
+    "classes": []
+  }
+]`
+
+func TestImportAggregator(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// an existing track is matched by name and given the feed's slug
+	if _, err := CreateTrack(db, "Xtreme Raceway Park", "Ferris", "1800 S I-45, Ferris, TX", ""); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	feed := writeTestBundle(t, "events.json", testAggregatorFeed)
+
+	res, err := ImportAggregatorFile(db, feed, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportAggregatorFile failed: %v", err)
+	}
+	if res.TracksCreated != 1 || res.TracksLinked != 1 || res.Events.Created != 2 || res.Classes.Created != 2 || res.Rules.Created != 1 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+
+	tracks, _ := ListTracks(db)
+	if len(tracks) != 2 || tracks[0].Slug != "thunder-valley-ok" || tracks[0].Address != "Lexington, OK" || tracks[1].Slug != "xtreme-tx" {
+		t.Errorf("Unexpected tracks: %+v", tracks)
+	}
+	events, _ := ListEvents(db)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.UUID != "57bc97b9-f865-436d-9d4f-190b56c92a25" || ev.Source != SourceAggregator || ev.Series != "TMCCC" {
+		t.Errorf("Expected the aggregator's keys to be kept, got %+v", ev)
+	}
+	if ev.StartLocal != "2026-04-12T08:00:00" || ev.DriverFee == nil || *ev.DriverFee != 40 {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+
+	// importing the same feed again changes nothing
+	res, err = ImportAggregatorFile(db, feed, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if res.TracksCreated != 0 || res.TracksLinked != 0 || res.Events.Unchanged != 2 || res.Classes.Unchanged != 2 || res.Removed != 0 {
+		t.Errorf("Expected an unchanged re-import, got %+v", res)
+	}
+
+	// an updated feed updates the event and drops classes and rules it lost
+	updated := strings.Replace(testAggregatorFeed, `"event_driver_fee": 40`, `"event_driver_fee": 45`, 1)
+	updated = strings.Replace(updated, `,
+      {"name": "Test N Tune", "buyin_fee": null, "rules": []}`, "", 1)
+	updated = strings.Replace(updated, `["1/8 mile- 9.40 & Slower"]`, `["1/8 mile- 9.50 & Slower"]`, 1)
+	res, err = ImportAggregatorFile(db, writeTestBundle(t, "updated.json", updated), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Updated import failed: %v", err)
+	}
+	if res.Events.Updated != 1 || res.Events.Unchanged != 1 || res.Rules.Created != 1 || res.Removed != 2 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	events, _ = ListEvents(db)
+	classes, _ := ListEventClasses(db)
+	rules, _ := ListEventClassRules(db)
+	if len(events) != 2 || *events[0].DriverFee != 45 {
+		t.Errorf("Expected the event to be updated in place, got %+v", events)
+	}
+	if len(classes) != 1 || len(rules) != 1 || rules[0].Rule != "1/8 mile- 9.50 & Slower" {
+		t.Errorf("Expected one class with the new rule, got %+v and %+v", classes, rules)
+	}
+}
+
+func TestImportAggregatorSkipsLocalEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Xtreme Raceway Park", "Ferris", "", "")
+	eventID, _ := CreateEvent(db, "Local Edit", trackID, "2026-04-17 18:00:00", "", nil, nil, "", "")
+	if _, err := db.Exec(`UPDATE events SET uuid = '0d5f3c1e-2b6a-4c8e-9f10-1a2b3c4d5e6f' WHERE id = ?`, eventID); err != nil {
+		t.Fatalf("Failed to set uuid: %v", err)
+	}
+
+	records, err := LoadAggregatorFeed(writeTestBundle(t, "events.json", testAggregatorFeed))
+	if err != nil {
+		t.Fatalf("LoadAggregatorFeed failed: %v", err)
+	}
+	res, err := ImportAggregator(db, records, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportAggregator failed: %v", err)
+	}
+	if res.SkippedLocal != 1 || res.Events.Created != 1 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+
+	// a suppressed record is not imported either
+	if err := SuppressEvent(db, records[0].ID, "misread flyer"); err != nil {
+		t.Fatalf("SuppressEvent failed: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM events WHERE source = ?`, SourceAggregator); err != nil {
+		t.Fatalf("Failed to delete imported events: %v", err)
+	}
+	res, err = ImportAggregator(db, records, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportAggregator failed: %v", err)
+	}
+	if res.Suppressed != 1 || res.Events.Created != 0 {
+		t.Errorf("Expected the suppressed record to be skipped, got %+v", res)
+	}
+	ev, _ := GetEvent(db, eventID)
+	if ev.Title != "Local Edit" || ev.Source != SourceLocal {
+		t.Errorf("Expected the local event to be left alone, got %+v", ev)
+	}
+}
+
+func TestImportAggregatorDryRun(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	records, _ := LoadAggregatorFeed(writeTestBundle(t, "events.json", `{"events": `+testAggregatorFeed+`}`))
+	res, err := ImportAggregator(db, records, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportAggregator failed: %v", err)
+	}
+	if res.Events.Created != 2 {
+		t.Errorf("Expected 2 events to be validated, got %+v", res)
+	}
+	if tracks, _ := ListTracks(db); len(tracks) != 0 {
+		t.Errorf("Expected a dry run to save nothing, got %+v", tracks)
+	}
+}
+
+func TestEditedAggregatorEventBecomesLocal(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	feed := writeTestBundle(t, "events.json", testAggregatorFeed)
+	if _, err := ImportAggregatorFile(db, feed, ImportOptions{}); err != nil {
+		t.Fatalf("ImportAggregatorFile failed: %v", err)
+	}
+	events, _ := ListEvents(db)
+	title := "TMCCC Race #2 (corrected)"
+	if err := UpdateEvent(db, events[0].ID, EventUpdate{Title: &title}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+
+	res, err := ImportAggregatorFile(db, feed, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if res.SkippedLocal != 1 {
+		t.Errorf("Expected the edited event to be skipped, got %+v", res)
+	}
+	ev, _ := GetEvent(db, events[0].ID)
+	if ev.Title != title || ev.Source != SourceLocal {
+		t.Errorf("Expected the edit to survive the re-import, got %+v", ev)
+	}
+	if want := []string{LocalTitle}; !reflect.DeepEqual(ev.LocalFields, want) {
+		t.Errorf("Expected local fields %v, got %v", want, ev.LocalFields)
+	}
+}
+
+func TestEditedAggregatorClassBecomesLocal(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	feed := writeTestBundle(t, "events.json", testAggregatorFeed)
+	if _, err := ImportAggregatorFile(db, feed, ImportOptions{}); err != nil {
+		t.Fatalf("ImportAggregatorFile failed: %v", err)
+	}
+	classes, _ := ListEventClasses(db)
+	class := classes[0]
+
+	// setting the fee it has is not an edit
+	same := sql.NullFloat64{Float64: 40, Valid: true}
+	if err := UpdateEventClass(db, class.ID, EventClassUpdate{BuyinFee: &same}); err != nil {
+		t.Fatalf("UpdateEventClass failed: %v", err)
+	}
+	if ev, _ := GetEvent(db, class.EventID); ev.Source != SourceAggregator {
+		t.Errorf("Expected an unchanged class to leave the event alone, got source %q", ev.Source)
+	}
+
+	fee := sql.NullFloat64{Float64: 50, Valid: true}
+	if err := UpdateEventClass(db, class.ID, EventClassUpdate{BuyinFee: &fee}); err != nil {
+		t.Fatalf("UpdateEventClass failed: %v", err)
+	}
+	if ev, _ := GetEvent(db, class.EventID); ev.Source != SourceLocal || !reflect.DeepEqual(ev.LocalFields, []string{LocalClasses}) {
+		t.Errorf("Expected the class edit to make the classes local, got source %q, local fields %v", ev.Source, ev.LocalFields)
+	}
+
+	res, err := ImportAggregatorFile(db, feed, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if res.SkippedLocal != 1 {
+		t.Errorf("Expected the edited event to be skipped, got %+v", res)
+	}
+	ec, _ := GetEventClass(db, class.ID)
+	if ec.BuyinFee == nil || *ec.BuyinFee != 50 {
+		t.Errorf("Expected the class edit to survive the re-import, got %+v", ec)
+	}
+}
+
+func TestEditedAggregatorRuleBecomesLocal(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	feed := writeTestBundle(t, "events.json", testAggregatorFeed)
+	if _, err := ImportAggregatorFile(db, feed, ImportOptions{}); err != nil {
+		t.Fatalf("ImportAggregatorFile failed: %v", err)
+	}
+	rules, _ := ListEventClassRules(db)
+	text := "1/8 mile- 9.50 & Slower"
+	if err := UpdateEventClassRule(db, rules[0].ID, EventClassRuleUpdate{Rule: &text}); err != nil {
+		t.Fatalf("UpdateEventClassRule failed: %v", err)
+	}
+	if _, err := ImportAggregatorFile(db, feed, ImportOptions{}); err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	r, _ := GetEventClassRule(db, rules[0].ID)
+	if r.Rule != text {
+		t.Errorf("Expected the rule edit to survive the re-import, got %q", r.Rule)
+	}
+}
+
+func TestMergedAggregatorTrackSurvivesReimport(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	localID, _ := CreateTrack(db, "XRP", "Ferris", "", "")
+	feed := writeTestBundle(t, "events.json", testAggregatorFeed)
+	if _, err := ImportAggregatorFile(db, feed, ImportOptions{}); err != nil {
+		t.Fatalf("ImportAggregatorFile failed: %v", err)
+	}
+	var xtremeID, thunderID int64
+	db.QueryRow(`SELECT id FROM tracks WHERE slug = 'xtreme-tx'`).Scan(&xtremeID)
+	db.QueryRow(`SELECT id FROM tracks WHERE slug = 'thunder-valley-ok'`).Scan(&thunderID)
+
+	// the kept track takes over the feed's slug
+	if _, err := MergeTracks(db, xtremeID, localID); err != nil {
+		t.Fatalf("MergeTracks failed: %v", err)
+	}
+	if tr, _ := GetTrack(db, localID); tr.Slug != "xtreme-tx" {
+		t.Errorf("Expected the kept track to get the slug, got %q", tr.Slug)
+	}
+
+	res, err := ImportAggregatorFile(db, feed, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if res.TracksCreated != 0 || res.TracksLinked != 0 || res.Events.Unchanged != 2 {
+		t.Errorf("Expected the re-import to change nothing, got %+v", res)
+	}
+	events, _ := ListEvents(db)
+	if events[1].TrackID != localID {
+		t.Errorf("Expected the event to stay at the kept track, got track %d", events[1].TrackID)
+	}
+
+	// two tracks from the feed cannot be merged
+	if _, err := MergeTracks(db, thunderID, localID); err == nil || !strings.Contains(err.Error(), "both in the aggregator's feed") {
+		t.Errorf("Expected merging two feed tracks to be refused, got %v", err)
+	}
+	if _, err := GetTrack(db, thunderID); err != nil {
+		t.Errorf("Expected the track to be kept after a refused merge: %v", err)
+	}
+}
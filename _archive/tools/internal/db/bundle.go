@@ -0,0 +1,255 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle is a set of events with their classes and rules nested inside, in
+// the same shape as the exported events.json. The file can be a JSON or YAML
+// list of events, or an object with an "events" list.
+type Bundle struct {
+	Events []BundleEvent `json:"events" yaml:"events"`
+}
+
+// BundleEvent is one event of a bundle. The track is given either by
+// TrackID or by Track, which holds a track ID or name.
+type BundleEvent struct {
+	Title        string        `json:"title" yaml:"title"`
+	TrackID      int64         `json:"track_id" yaml:"track_id"`
+	Track        string        `json:"track" yaml:"track"`
+	StartDate    string        `json:"start_date" yaml:"start_date"`
+	EndDate      string        `json:"end_date" yaml:"end_date"`
+	DriverFee    *float64      `json:"event_driver_fee" yaml:"event_driver_fee"`
+	SpectatorFee *float64      `json:"event_spectator_fee" yaml:"event_spectator_fee"`
+	URL          string        `json:"url" yaml:"url"`
+	Description  string        `json:"description" yaml:"description"`
+	Series       string        `json:"series" yaml:"series"`
+	Classes      []BundleClass `json:"classes" yaml:"classes"`
+}
+
+// BundleClass is one class of a bundle event.
+type BundleClass struct {
+	Name     string       `json:"name" yaml:"name"`
+	BuyinFee *float64     `json:"buyin_fee" yaml:"buyin_fee"`
+	Rules    []BundleRule `json:"rules" yaml:"rules"`
+}
+
+// BundleRule is a rule's text. It can be written as a plain string or, as in
+// exported JSON, as an object with a "rule" field.
+type BundleRule string
+
+func (r *BundleRule) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*r = BundleRule(s)
+		return nil
+	}
+	var obj struct {
+		Rule string `json:"rule"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return fmt.Errorf("rule must be a string or an object with a \"rule\" field")
+	}
+	*r = BundleRule(obj.Rule)
+	return nil
+}
+
+func (r *BundleRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = BundleRule(node.Value)
+		return nil
+	}
+	var obj struct {
+		Rule string `yaml:"rule"`
+	}
+	if err := node.Decode(&obj); err != nil {
+		return fmt.Errorf("line %d: rule must be a string or a mapping with a \"rule\" key", node.Line)
+	}
+	*r = BundleRule(obj.Rule)
+	return nil
+}
+
+// BundleResult counts what ImportBundle inserted.
+type BundleResult struct {
+	Events  int
+	Classes int
+	Rules   int
+}
+
+// LoadBundle reads a bundle file. Files ending in .yaml or .yml are read as
+// YAML, anything else as JSON.
+func LoadBundle(filename string) (Bundle, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("read bundle: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return parseYAMLBundle(data)
+	default:
+		return parseJSONBundle(data)
+	}
+}
+
+func parseJSONBundle(data []byte) (Bundle, error) {
+	var b Bundle
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &b.Events)
+	} else {
+		err = json.Unmarshal(data, &b)
+	}
+	if err != nil {
+		return Bundle{}, fmt.Errorf("parse JSON bundle: %w", err)
+	}
+	return b, nil
+}
+
+func parseYAMLBundle(data []byte) (Bundle, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Bundle{}, fmt.Errorf("parse YAML bundle: %w", err)
+	}
+	var b Bundle
+	if len(doc.Content) == 0 {
+		return b, nil
+	}
+	root := doc.Content[0]
+	var err error
+	if root.Kind == yaml.SequenceNode {
+		err = root.Decode(&b.Events)
+	} else {
+		err = root.Decode(&b)
+	}
+	if err != nil {
+		return Bundle{}, fmt.Errorf("parse YAML bundle: %w", err)
+	}
+	return b, nil
+}
+
+// ImportBundleFile loads a bundle file and imports it with ImportBundle.
+func ImportBundleFile(db *sql.DB, filename string, opts ImportOptions) (BundleResult, error) {
+	b, err := LoadBundle(filename)
+	if err != nil {
+		return BundleResult{}, err
+	}
+	return ImportBundle(db, b, opts)
+}
+
+// ImportBundle inserts every event of the bundle with its classes and rules
+// in a single transaction; if anything fails, nothing is imported. In
+// dry-run mode the transaction is always rolled back.
+func ImportBundle(db *sql.DB, b Bundle, opts ImportOptions) (BundleResult, error) {
+	if len(b.Events) == 0 {
+		return BundleResult{}, fmt.Errorf("bundle has no events")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return BundleResult{}, err
+	}
+	defer tx.Rollback()
+
+	var res BundleResult
+	for i, ev := range b.Events {
+		if err := insertBundleEvent(tx, ev, &res); err != nil {
+			return BundleResult{}, fmt.Errorf("events[%d] (%q): %w", i, ev.Title, err)
+		}
+	}
+
+	if opts.DryRun {
+		return res, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return BundleResult{}, err
+	}
+	return res, nil
+}
+
+func insertBundleEvent(tx *sql.Tx, ev BundleEvent, res *BundleResult) error {
+	title := strings.TrimSpace(ev.Title)
+	if title == "" {
+		return fmt.Errorf("title is required")
+	}
+	startDate := strings.TrimSpace(ev.StartDate)
+	if startDate == "" {
+		return fmt.Errorf("start_date is required")
+	}
+
+	trackID := ev.TrackID
+	switch {
+	case ev.Track != "" && trackID != 0:
+		return fmt.Errorf("give either track_id or track, not both")
+	case ev.Track != "":
+		id, err := ResolveTrack(tx, ev.Track)
+		if err != nil {
+			return err
+		}
+		trackID = id
+	case trackID == 0:
+		return fmt.Errorf("track_id or track is required")
+	}
+
+	eventID, err := insertEvent(tx, eventFields{
+		Title:        title,
+		TrackID:      trackID,
+		StartDate:    startDate,
+		EndDate:      strings.TrimSpace(ev.EndDate),
+		DriverFee:    ev.DriverFee,
+		SpectatorFee: ev.SpectatorFee,
+		URL:          strings.TrimSpace(ev.URL),
+		Description:  strings.TrimSpace(ev.Description),
+		Series:       strings.TrimSpace(ev.Series),
+	})
+	if err != nil {
+		return fmt.Errorf("create event: %w", err)
+	}
+	res.Events++
+
+	for i, c := range ev.Classes {
+		if err := insertBundleClass(tx, eventID, c, res); err != nil {
+			return fmt.Errorf("classes[%d] (%q): %w", i, c.Name, err)
+		}
+	}
+	return nil
+}
+
+func insertBundleClass(tx *sql.Tx, eventID int64, c BundleClass, res *BundleResult) error {
+	name := strings.TrimSpace(c.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	var buyinFee any
+	if c.BuyinFee != nil {
+		buyinFee = *c.BuyinFee
+	}
+	r, err := tx.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, ?, ?)`, eventID, name, buyinFee)
+	if err != nil {
+		return fmt.Errorf("insert class: %w", err)
+	}
+	classID, err := r.LastInsertId()
+	if err != nil {
+		return err
+	}
+	res.Classes++
+
+	for i, rule := range c.Rules {
+		text := strings.TrimSpace(string(rule))
+		if text == "" {
+			return fmt.Errorf("rules[%d]: rule is empty", i)
+		}
+		if _, err := tx.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES(?, ?)`, classID, text); err != nil {
+			return fmt.Errorf("rules[%d]: insert rule: %w", i, err)
+		}
+		res.Rules++
+	}
+	return nil
+}
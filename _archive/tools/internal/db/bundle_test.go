@@ -0,0 +1,151 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestBundle(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create bundle file: %v", err)
+	}
+	return path
+}
+
+func TestImportBundleYAML(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := CreateTrack(db, "Xtreme Raceway Park", "Ferris", "1800 S I-45", "https://xrp.example"); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	bundle := writeTestBundle(t, "series.yaml", `
+events:
+  - title: TMCCC Series
+    track: Xtreme Raceway Park
+    start_date: 2026-03-22 09:00:00
+    event_driver_fee: 40
+    classes:
+      - name: Stock Muscle
+        buyin_fee: 40
+        rules:
+          - 1/8 mile- 9.40 & Slower
+          - rule: 1/4 mile- 14.69 & Slower
+      - name: Test N Tune
+  - title: TMCCC Series
+    track_id: 1
+    start_date: 2026-06-28 09:00:00
+`)
+
+	res, err := ImportBundleFile(db, bundle, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportBundleFile failed: %v", err)
+	}
+	if res != (BundleResult{Events: 2, Classes: 2, Rules: 2}) {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+
+	events, _ := ListEvents(db)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].DriverFee == nil || *events[0].DriverFee != 40 {
+		t.Errorf("Expected driver fee 40, got %v", events[0].DriverFee)
+	}
+	if got := events[0].StartDate.Format("2006-01-02 15:04:05"); got != "2026-03-22 09:00:00" {
+		t.Errorf("Expected start date 2026-03-22 09:00:00, got %s", got)
+	}
+	classes, _ := ListEventClasses(db)
+	rules, _ := ListEventClassRules(db)
+	if len(classes) != 2 || classes[0].EventID != events[0].ID || classes[1].BuyinFee != nil {
+		t.Errorf("Unexpected classes: %+v", classes)
+	}
+	if len(rules) != 2 || rules[1].Rule != "1/4 mile- 14.69 & Slower" || rules[1].EventClassID != classes[0].ID {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+}
+
+func TestImportBundleJSONList(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+
+	// the shape of an exported events.json, ids and all
+	bundle := writeTestBundle(t, "events.json", `[
+  {
+    "id": 12,
+    "title": "Fall Nationals",
+    "track_id": 1,
+    "track_name": "Test Track",
+    "start_date": "2025-10-03T08:00:00Z",
+    "classes": [
+      {"id": 4, "event_id": 12, "name": "Pro Street", "rules": [{"id": 9, "event_class_id": 4, "rule": "DOT tires"}]}
+    ]
+  }
+]`)
+
+	res, err := ImportBundleFile(db, bundle, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportBundleFile failed: %v", err)
+	}
+	if res != (BundleResult{Events: 1, Classes: 1, Rules: 1}) {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+}
+
+func TestImportBundleIsAllOrNothing(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+
+	bundle := writeTestBundle(t, "bad.yaml", `
+- title: Good Event
+  track_id: 1
+  start_date: 2026-03-22 09:00:00
+  classes:
+    - name: Pro
+      rules: [No nitrous]
+- title: Bad Event
+  track: No Such Track
+  start_date: 2026-04-22 09:00:00
+`)
+
+	if _, err := ImportBundleFile(db, bundle, ImportOptions{}); err == nil {
+		t.Fatal("Expected error for unknown track")
+	}
+	events, _ := ListEvents(db)
+	classes, _ := ListEventClasses(db)
+	rules, _ := ListEventClassRules(db)
+	if len(events)+len(classes)+len(rules) != 0 {
+		t.Errorf("Expected nothing committed, got %d events, %d classes, %d rules", len(events), len(classes), len(rules))
+	}
+}
+
+func TestImportBundleDryRun(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	bundle := writeTestBundle(t, "ok.yml", `
+- title: Good Event
+  track_id: 1
+  start_date: 2026-03-22 09:00:00
+`)
+
+	res, err := ImportBundleFile(db, bundle, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if res.Events != 1 {
+		t.Errorf("Expected 1 event in dry run, got %d", res.Events)
+	}
+	if events, _ := ListEvents(db); len(events) != 0 {
+		t.Errorf("Expected dry run to commit nothing, got %d events", len(events))
+	}
+}
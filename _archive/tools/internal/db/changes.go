@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// ChangeAdded is the summary logged when an event is created. The other
+// summaries are "renamed", "date moved", "track changed", "fees changed",
+// "details updated", "series changed", "classes added", "classes changed",
+// "classes removed" and "rules changed"; see migration 007.
+const ChangeAdded = "added"
+
+// EventChange is a set of changes made to one event at the same time, as
+// logged in event_changes.
+type EventChange struct {
+	ID        int64 // the first change in the set, stable across exports
+	EventID   int64
+	ChangedAt string   // UTC, RFC 3339
+	Summaries []string // in the order they were logged, without repeats
+}
+
+// Added reports whether the event was created in this change.
+func (c EventChange) Added() bool {
+	return slices.Contains(c.Summaries, ChangeAdded)
+}
+
+// ListEventChanges returns the most recent changes to events, newest first.
+// Changes logged for the same event in the same second are one EventChange.
+// A limit of 0 returns every change.
+func ListEventChanges(dbx *sql.DB, limit int) ([]EventChange, error) {
+	rows, err := dbx.Query(`SELECT id, event_id, CAST(changed_at AS TEXT), summary FROM event_changes
+		ORDER BY changed_at DESC, event_id, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []EventChange
+	index := make(map[string]int) // event ID and time to position in out
+	for rows.Next() {
+		var id, eventID int64
+		var changedAt, summary string
+		if err := rows.Scan(&id, &eventID, &changedAt, &summary); err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%d@%s", eventID, changedAt)
+		i, ok := index[key]
+		if !ok {
+			if limit > 0 && len(out) == limit {
+				break
+			}
+			at, err := formatStoredTimestamp(changedAt)
+			if err != nil {
+				return nil, fmt.Errorf("event change %d: %w", id, err)
+			}
+			out = append(out, EventChange{ID: id, EventID: eventID, ChangedAt: at})
+			i = len(out) - 1
+			index[key] = i
+		}
+		if c := &out[i]; !slices.Contains(c.Summaries, summary) {
+			c.Summaries = append(c.Summaries, summary)
+		}
+	}
+	return out, rows.Err()
+}
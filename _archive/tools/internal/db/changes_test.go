@@ -0,0 +1,106 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventChangesAreLogged(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	id, err := CreateEvent(db, "Test Event", trackID, "2026-03-22 09:00:00", "", nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	changes, err := ListEventChanges(db, 0)
+	if err != nil {
+		t.Fatalf("ListEventChanges failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].EventID != id || !changes[0].Added() {
+		t.Fatalf("Expected one added change, got %+v", changes)
+	}
+
+	// Backdate the log so the next changes are a separate set
+	if _, err := db.Exec(`UPDATE event_changes SET changed_at = '2026-01-01 00:00:00'`); err != nil {
+		t.Fatalf("Failed to backdate changes: %v", err)
+	}
+	title, start := "Renamed", "2026-03-29 09:00:00"
+	if err := UpdateEvent(db, id, EventUpdate{Title: &title, StartDate: &start}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Pro Street', 100.0)`, id); err != nil {
+		t.Fatalf("Failed to add class: %v", err)
+	}
+
+	changes, err = ListEventChanges(db, 0)
+	if err != nil {
+		t.Fatalf("ListEventChanges failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 change sets, got %+v", changes)
+	}
+	want := []string{"renamed", "date moved", "classes added"}
+	if !reflect.DeepEqual(changes[0].Summaries, want) {
+		t.Errorf("Expected summaries %v, got %v", want, changes[0].Summaries)
+	}
+	if changes[0].Added() {
+		t.Error("Expected the update not to count as added")
+	}
+	if changes[1].ChangedAt != "2026-01-01T00:00:00Z" || !changes[1].Added() {
+		t.Errorf("Expected the backdated add last, got %+v", changes[1])
+	}
+
+	limited, err := ListEventChanges(db, 1)
+	if err != nil {
+		t.Fatalf("ListEventChanges failed: %v", err)
+	}
+	if len(limited) != 1 || len(limited[0].Summaries) != 3 {
+		t.Errorf("Expected the limit to count change sets, got %+v", limited)
+	}
+}
+
+func TestUnchangedUpdateIsNotLogged(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	id, _ := CreateEvent(db, "Test Event", trackID, "2026-03-22 09:00:00", "", nil, nil, "", "")
+	title := "Test Event"
+	if err := UpdateEvent(db, id, EventUpdate{Title: &title}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	changes, _ := ListEventChanges(db, 0)
+	if len(changes) != 1 || !reflect.DeepEqual(changes[0].Summaries, []string{ChangeAdded}) {
+		t.Errorf("Expected only the add to be logged, got %+v", changes)
+	}
+}
+
+func TestMigrateBackfillsEventChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := Seed(db); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	events, _ := ListEvents(db)
+	if _, err := MigrateTo(db, "006"); err != nil {
+		t.Fatalf("MigrateTo 006 failed: %v", err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	changes, err := ListEventChanges(db, 0)
+	if err != nil {
+		t.Fatalf("ListEventChanges failed: %v", err)
+	}
+	if len(changes) != len(events) {
+		t.Fatalf("Expected an added change per event, got %+v", changes)
+	}
+	for _, c := range changes {
+		if !c.Added() || len(c.Summaries) != 1 {
+			t.Errorf("Expected only added, got %+v", c)
+		}
+	}
+}
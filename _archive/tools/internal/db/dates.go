@@ -0,0 +1,165 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DateLayout is the canonical format event dates are stored in.
+const DateLayout = "2006-01-02 15:04:05"
+
+// dateLayouts are the formats ParseDate accepts, tried in order. Input is
+// upper-cased first, so "9:00 pm" matches the PM layouts.
+var dateLayouts = []string{
+	// ISO, as stored and as typed
+	DateLayout,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 3:04 PM",
+	"2006-01-02 3:04PM",
+	"2006-01-02 3 PM",
+	"2006-01-02 3PM",
+	"2006-01-02",
+	// US month/day/year, as spreadsheets write it
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	"1/2/2006 3:04PM",
+	"1/2/2006 3 PM",
+	"1/2/2006 3PM",
+	"1/2/2006",
+	// what the SQLite driver hands back for DATETIME columns
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05Z07:00",
+	time.RFC3339Nano,
+	// what the driver stores for a time.Time argument: time.Time.String
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+}
+
+// monotonicRE matches the monotonic clock reading time.Time.String appends,
+// e.g. " m=+86400.261".
+var monotonicRE = regexp.MustCompile(` M=[+-][0-9.]+$`)
+
+// ParseDate parses an event date in any of the accepted formats: ISO
+// ("2026-02-13 09:00:00", "2026-02-13T09:00"), US ("2/13/2026 9:00"),
+// date-only ("2026-02-13", "2/13/2026") and 12-hour times
+// ("2/13/2026 9:00 PM"). Date-only values are midnight. A value without a
+// time zone is a wall-clock time, returned in UTC; one with an offset
+// ("2026-02-13T09:00:00-06:00", or time.Time.String output such as
+// "2026-02-13 09:00:00 -0600 CST") is that instant, returned at its offset.
+func ParseDate(s string) (time.Time, error) {
+	t, _, err := parseDate(s)
+	return t, err
+}
+
+// parseDate is ParseDate, also reporting whether s carried an offset.
+func parseDate(s string) (t time.Time, zoned bool, err error) {
+	v := strings.ToUpper(strings.Join(strings.Fields(s), " "))
+	if v == "" {
+		return time.Time{}, false, fmt.Errorf("date is empty")
+	}
+	v = monotonicRE.ReplaceAllString(v, "")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			// only the layouts with an offset have a "07" in them
+			return t, strings.Contains(layout, "07"), nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("unrecognized date %q (use YYYY-MM-DD HH:MM:SS or M/D/YYYY H:MM)", s)
+}
+
+// NormalizeDate parses s with ParseDate and formats it as a stored date:
+// DateLayout in UTC. A wall-clock time is taken to be UTC already.
+func NormalizeDate(s string) (string, error) {
+	t, err := ParseDate(s)
+	if err != nil {
+		return "", err
+	}
+	return formatStoredDate(t), nil
+}
+
+// normalizeOptionalDate is NormalizeDate for columns where empty means unset.
+func normalizeOptionalDate(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	return NormalizeDate(s)
+}
+
+// isDateOnly reports whether s has no time part, as in "2026-03-22" or
+// "3/22/2026".
+func isDateOnly(s string) bool {
+	return !strings.ContainsAny(strings.TrimSpace(s), " T:")
+}
+
+// sameDay reports whether a and b fall on the same calendar day.
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// NormalizeEventDates rewrites every stored event date that is not already
+// in DateLayout, such as rows imported before dates were normalized. It
+// fails without changing anything if any date cannot be parsed, and returns
+// the number of events rewritten.
+func NormalizeEventDates(db *sql.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stored, err := storedEventDates(tx, "")
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, d := range stored {
+		start, err := NormalizeDate(d.start)
+		if err != nil {
+			return 0, fmt.Errorf("event %d: start date: %w", d.id, err)
+		}
+		end, err := normalizeOptionalDate(d.end)
+		if err != nil {
+			return 0, fmt.Errorf("event %d: end date: %w", d.id, err)
+		}
+		if start == d.start && end == d.end {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE events SET event_datetime = ?, end_date = ? WHERE id = ?`,
+			start, nullIfEmpty(end), d.id); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+	return changed, tx.Commit()
+}
+
+// storedEventDate is an event's dates as the text in the database.
+type storedEventDate struct {
+	id         int64
+	start, end string
+}
+
+func storedEventDates(tx *sql.Tx, where string, args ...any) ([]storedEventDate, error) {
+	rows, err := tx.Query(`SELECT id, CAST(event_datetime AS TEXT), COALESCE(CAST(end_date AS TEXT), '') FROM events `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []storedEventDate
+	for rows.Next() {
+		var d storedEventDate
+		if err := rows.Scan(&d.id, &d.start, &d.end); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
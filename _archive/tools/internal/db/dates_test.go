@@ -0,0 +1,140 @@
+package db
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestNormalizeDate(t *testing.T) {
+	tests := map[string]string{
+		"2026-02-13 09:00:00":       "2026-02-13 09:00:00",
+		"2026-02-13 9:00":           "2026-02-13 09:00:00",
+		"2026-02-13T09:00:00":       "2026-02-13 09:00:00",
+		"2026-02-13T09:00:00Z":      "2026-02-13 09:00:00",
+		"2026-02-13":                "2026-02-13 00:00:00",
+		"2/13/2026 9:00":            "2026-02-13 09:00:00",
+		"02/13/2026 21:30":          "2026-02-13 21:30:00",
+		"2/13/2026":                 "2026-02-13 00:00:00",
+		"2/13/2026 9:00 PM":         "2026-02-13 21:00:00",
+		"2/13/2026 9:00pm":          "2026-02-13 21:00:00",
+		"2/13/2026 12:15 AM":        "2026-02-13 00:15:00",
+		"2026-02-13 7 pm":           "2026-02-13 19:00:00",
+		"  3/22/2026   9:00  ":      "2026-03-22 09:00:00",
+		"2026-02-13 09:00:00.5":     "2026-02-13 09:00:00",
+		"2026-02-13T09:00:00-06:00": "2026-02-13 15:00:00",
+		// time.Time.String, as the driver stores a time.Time argument
+		"2026-02-13 09:00:00.695866093 -0600 CST m=+86400.261": "2026-02-13 15:00:00",
+		"2026-10-17 18:04:58.695866093 +0000 UTC":              "2026-10-17 18:04:58",
+	}
+	for in, want := range tests {
+		got, err := NormalizeDate(in)
+		if err != nil {
+			t.Errorf("NormalizeDate(%q) failed: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("NormalizeDate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeDateRejectsGarbage(t *testing.T) {
+	for _, in := range []string{"", "next friday", "13/13/2026", "2026-02-30", "2/13/26 9:00"} {
+		if got, err := NormalizeDate(in); err == nil {
+			t.Errorf("NormalizeDate(%q) = %q, expected an error", in, got)
+		}
+	}
+}
+
+func TestCreateEventNormalizesDates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	id, err := CreateEvent(db, "IHRA Bracket Series", trackID, "2/13/2026 9:00", "2/14/2026 11:00 PM", nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	var start, end string
+	if err := db.QueryRow(`SELECT CAST(event_datetime AS TEXT), CAST(end_date AS TEXT) FROM events WHERE id = ?`, id).Scan(&start, &end); err != nil {
+		t.Fatalf("Failed to read stored dates: %v", err)
+	}
+	// entered in Central time, stored in UTC
+	if start != "2026-02-13 15:00:00" || end != "2026-02-15 05:00:00" {
+		t.Errorf("Expected canonical UTC stored dates, got %q and %q", start, end)
+	}
+
+	if _, err := CreateEvent(db, "Bad", trackID, "someday", "", nil, nil, "", ""); err == nil {
+		t.Error("Expected error for unparseable start date")
+	}
+	if _, err := CreateEvent(db, "Bad", trackID, "2026-02-13", "whenever", nil, nil, "", ""); err == nil {
+		t.Error("Expected error for unparseable end date")
+	}
+}
+
+func TestImportEventsReportsBadDates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	track := strconv.FormatInt(trackID, 10)
+	csvFile := writeTestCSV(t, "events.csv", "title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description\n"+
+		"Good,"+track+",3/22/2026 9:00,3/22/2026 23:00,,,,\n"+
+		"Bad,"+track+",TBD,,,,,")
+
+	_, err := ImportEventsFromCSV(db, csvFile)
+	if err == nil {
+		t.Fatal("Expected error for unparseable start date")
+	}
+}
+
+func TestListEventsParsesStoredDates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	// written directly, as rows from before dates were validated may be
+	if _, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, url, description) VALUES('Legacy', ?, '3/22/2026 9:00', '', '')`, trackID); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	events, err := ListEvents(db)
+	if err != nil {
+		t.Fatalf("ListEvents failed on a legacy US date: %v", err)
+	}
+	if got := events[0].StartDate.UTC().Format(DateLayout); got != "2026-03-22 09:00:00" {
+		t.Errorf("Expected legacy date to be read, got %s", got)
+	}
+
+	if _, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, url, description) VALUES('Broken', ?, 'TBD', '', '')`, trackID); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if _, err := ListEvents(db); err == nil {
+		t.Error("Expected ListEvents to fail instead of returning a zero start date")
+	}
+}
+
+func TestNormalizeEventDates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	CreateEvent(db, "Already Canonical", trackID, "2026-01-01 09:00:00", "", nil, nil, "", "")
+	if _, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, end_date, url, description)
+		VALUES('Legacy', ?, '3/22/2026 9:00', '3/22/2026 11:00 PM', '', '')`, trackID); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	n, err := NormalizeEventDates(db)
+	if err != nil {
+		t.Fatalf("NormalizeEventDates failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 event rewritten, got %d", n)
+	}
+	var start, end string
+	db.QueryRow(`SELECT CAST(event_datetime AS TEXT), CAST(end_date AS TEXT) FROM events WHERE title = 'Legacy'`).Scan(&start, &end)
+	if start != "2026-03-22 09:00:00" || end != "2026-03-22 23:00:00" {
+		t.Errorf("Expected canonical dates, got %q and %q", start, end)
+	}
+}
@@ -2,45 +2,60 @@ package db
 
 import (
 	"database/sql"
-	"encoding/csv"
 	"errors"
 	"fmt"
-	"io"
-	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
-const (
-	DBPath     = "db/db.sqlite"
-	MigrateDir = "db/migrate"
-)
+// DBPath is the default database location, relative to the working directory.
+// The CLI overrides it with the --db flag or the DFW_DB_PATH environment variable.
+const DBPath = "db/db.sqlite"
 
 type Track struct {
-	ID      int64  `json:"id"`
-	Name    string `json:"name"`
-	City    string `json:"city"`
-	Address string `json:"address"`
-	URL     string `json:"url"`
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	City     string `json:"city"`
+	Address  string `json:"address"`
+	URL      string `json:"url"`
+	TimeZone string `json:"timezone"`       // IANA name, e.g. America/Chicago
+	Slug     string `json:"slug,omitempty"` // the aggregator's track ID, e.g. xtreme-tx
 }
 
+// Event dates are stored in UTC and read back in the track's time zone, so
+// StartDate and EndDate show local wall-clock time and marshal with the
+// local offset. StartUTC/StartLocal and EndUTC/EndLocal spell out both forms
+// for the site.
 type Event struct {
-	ID           int64        `json:"id"`
-	Title        string       `json:"title"`
-	TrackID      int64        `json:"track_id"`
-	TrackName    string       `json:"track_name"`
-	StartDate    time.Time    `json:"start_date"` // DB column: event_datetime
-	EndDate      *time.Time   `json:"end_date,omitempty"`
-	DriverFee    *float64     `json:"event_driver_fee,omitempty"`
-	SpectatorFee *float64     `json:"event_spectator_fee,omitempty"`
-	URL          string       `json:"url"`
-	Description  string       `json:"description"`
-	Classes      []EventClass `json:"classes,omitempty"`
+	ID            int64        `json:"id"`
+	Title         string       `json:"title"`
+	TrackID       int64        `json:"track_id"`
+	TrackName     string       `json:"track_name"`
+	TimeZone      string       `json:"timezone"`
+	StartDate     time.Time    `json:"start_date"` // DB column: event_datetime
+	StartUTC      string       `json:"start_utc"`
+	StartLocal    string       `json:"start_local"`
+	EndDate       *time.Time   `json:"end_date,omitempty"`
+	EndUTC        string       `json:"end_utc,omitempty"`
+	EndLocal      string       `json:"end_local,omitempty"`
+	DriverFee     *float64     `json:"event_driver_fee,omitempty"`
+	SpectatorFee  *float64     `json:"event_spectator_fee,omitempty"`
+	URL           string       `json:"url"`
+	Description   string       `json:"description"`
+	ExternalID    string       `json:"external_id,omitempty"`
+	Series        string       `json:"series,omitempty"`
+	UUID          string       `json:"uuid"`
+	Source        string       `json:"source"`                   // SourceLocal or SourceAggregator
+	Status        string       `json:"status"`                   // StatusScheduled, StatusPostponed, StatusCancelled or StatusRainedOut
+	RescheduledTo *int64       `json:"rescheduled_to,omitempty"` // the replacement of a postponed or rained out event
+	LocalFields   []string     `json:"local_fields,omitempty"`   // the fields edited here, e.g. LocalTitle
+	CreatedAt     string       `json:"created_at"`               // UTC, RFC 3339
+	UpdatedAt     string       `json:"updated_at"`
+	Classes       []EventClass `json:"classes,omitempty"`
 }
 
 type EventClass struct {
@@ -57,92 +72,93 @@ type EventClassRule struct {
 	Rule         string `json:"rule"`
 }
 
-func Open() (*sql.DB, error) {
-	db, err := sql.Open("sqlite", DBPath)
+// connPragmas are applied to every pooled connection: enforce the schema's
+// foreign keys (and their ON DELETE CASCADE), use WAL so the site export can
+// read while the CLI writes, and wait instead of failing on a locked database.
+var connPragmas = []string{
+	"foreign_keys(1)",
+	"journal_mode(WAL)",
+	"busy_timeout(5000)",
+}
+
+// Open opens the SQLite database at path, creating its parent directory if needed.
+// The DSN is a file: URI so that a '?' or '#' in path is escaped rather than
+// read as the start of the query.
+func Open(path string) (*sql.DB, error) {
+	dsn := url.URL{
+		Scheme:   "file",
+		Path:     filepath.ToSlash(path),
+		OmitHost: true,
+		RawQuery: url.Values{"_pragma": connPragmas}.Encode(),
+	}
+	db, err := sql.Open("sqlite", dsn.String())
 	if err != nil {
 		return nil, err
 	}
-	if _, err := os.Stat(filepath.Dir(DBPath)); errors.Is(err, os.ErrNotExist) {
-		if mkErr := os.MkdirAll(filepath.Dir(DBPath), 0o755); mkErr != nil {
+	if _, err := os.Stat(filepath.Dir(path)); errors.Is(err, os.ErrNotExist) {
+		if mkErr := os.MkdirAll(filepath.Dir(path), 0o755); mkErr != nil {
 			return nil, mkErr
 		}
 	}
 	return db, nil
 }
 
-func Migrate(db *sql.DB) error {
-	entries, err := os.ReadDir(MigrateDir)
+// Seed inserts sample tracks, events, classes and rules in one transaction.
+func Seed(db *sql.DB) error {
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
-	var files []fs.DirEntry
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".sql") {
-			files = append(files, e)
-		}
-	}
-	// simple lexicographic order ensures sequence (001_, 002_, ...)
-	for _, f := range files {
-		p := filepath.Join(MigrateDir, f.Name())
-		b, err := os.ReadFile(p)
-		if err != nil {
-			return err
-		}
-		if _, err := db.Exec(string(b)); err != nil {
-			return fmt.Errorf("migrate %s: %w", f.Name(), err)
-		}
-	}
-	return nil
-}
+	defer tx.Rollback()
 
-func Seed(db *sql.DB) error {
 	// Insert sample tracks
 	tracks := []Track{
 		{Name: "Texas Motorplex", City: "Ennis", Address: "7500 US-287, Ennis, TX", URL: "https://texasmotorplex.com"},
 		{Name: "Xtreme Raceway Park", City: "Ferris", Address: "1800 S Interstate 45, Ferris, TX", URL: "https://www.xtremeracewaypark.com"},
 	}
-	for _, t := range tracks {
-		_, err := db.Exec(`INSERT INTO tracks(name, city, address, url) VALUES(?, ?, ?, ?)`, t.Name, t.City, t.Address, t.URL)
+	trackIDs := make([]int64, len(tracks))
+	for i, t := range tracks {
+		res, err := tx.Exec(`INSERT INTO tracks(name, city, address, url) VALUES(?, ?, ?, ?)`, t.Name, t.City, t.Address, t.URL)
 		if err != nil {
 			return err
 		}
+		trackIDs[i], _ = res.LastInsertId()
 	}
 	// Insert sample events
-	res1, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, end_date, event_driver_fee, event_spectator_fee, url, description)
-		VALUES('Fall Nationals', 1, '2025-10-03 08:00:00', '2025-10-12 18:00:00', 50.0, 20.0, 'https://texasmotorplex.com/events', 'NHRA fall event')`)
+	fee := func(v float64) *float64 { return &v }
+	event1ID, err := createEvent(tx, "Fall Nationals", trackIDs[0], "2025-10-03 08:00:00", "2025-10-12 18:00:00",
+		fee(50.0), fee(20.0), "https://texasmotorplex.com/events", "NHRA fall event")
 	if err != nil {
 		return err
 	}
-	event1ID, _ := res1.LastInsertId()
 
-	res2, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, end_date, event_driver_fee, event_spectator_fee, url, description)
-		VALUES('Friday Night Drags', 2, '2025-10-24 18:00:00', '2025-10-24 23:00:00', 30.0, 10.0, 'https://www.xtremeracewaypark.com', 'Test and tune night')`)
+	event2ID, err := createEvent(tx, "Friday Night Drags", trackIDs[1], "2025-10-24 18:00:00", "2025-10-24 23:00:00",
+		fee(30.0), fee(10.0), "https://www.xtremeracewaypark.com", "Test and tune night")
 	if err != nil {
 		return err
 	}
-	event2ID, _ := res2.LastInsertId()
 
 	// Insert sample event classes
-	class1Res, err := db.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Pro Street', 100.0)`, event1ID)
+	class1Res, err := tx.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Pro Street', 100.0)`, event1ID)
 	if err != nil {
 		return err
 	}
 	class1ID, _ := class1Res.LastInsertId()
 
-	class2Res, err := db.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Street', 50.0)`, event1ID)
+	class2Res, err := tx.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Street', 50.0)`, event1ID)
 	if err != nil {
 		return err
 	}
 	class2ID, _ := class2Res.LastInsertId()
 
-	class3Res, err := db.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Test & Tune', NULL)`, event2ID)
+	class3Res, err := tx.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Test & Tune', NULL)`, event2ID)
 	if err != nil {
 		return err
 	}
 	class3ID, _ := class3Res.LastInsertId()
 
 	// Insert sample rules
-	_, err = db.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES
+	_, err = tx.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES
 		(?, 'DOT street tires only'),
 		(?, 'Maximum 10.5" tire width'),
 		(?, 'Full interior required')`, class1ID, class1ID, class1ID)
@@ -150,22 +166,35 @@ func Seed(db *sql.DB) error {
 		return err
 	}
 
-	_, err = db.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES
+	_, err = tx.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES
 		(?, 'Street legal vehicle'),
 		(?, 'Valid registration and insurance')`, class2ID, class2ID)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES
+	_, err = tx.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES
 		(?, 'All vehicles welcome'),
 		(?, 'Helmet required for sub-14 second runs')`, class3ID, class3ID)
-	return err
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func CreateTrack(db *sql.DB, name, city, address, url string) (int64, error) {
-	result, err := db.Exec(`INSERT INTO tracks(name, city, address, url) VALUES(?, ?, ?, ?)`,
-		name, city, address, url)
+	return CreateTrackInZone(db, name, city, address, url, DefaultTimeZone)
+}
+
+// CreateTrackInZone creates a track whose events are in the given IANA time
+// zone.
+func CreateTrackInZone(db *sql.DB, name, city, address, url, timeZone string) (int64, error) {
+	loc, err := LoadTimeZone(timeZone)
+	if err != nil {
+		return 0, err
+	}
+	result, err := db.Exec(`INSERT INTO tracks(name, city, address, url, timezone) VALUES(?, ?, ?, ?, ?)`,
+		name, city, address, url, loc.String())
 	if err != nil {
 		return 0, err
 	}
@@ -173,7 +202,7 @@ func CreateTrack(db *sql.DB, name, city, address, url string) (int64, error) {
 }
 
 func ListTracks(db *sql.DB) ([]Track, error) {
-	rows, err := db.Query(`SELECT id, name, city, address, url FROM tracks ORDER BY name`)
+	rows, err := db.Query(`SELECT id, name, city, address, url, timezone, COALESCE(slug, '') FROM tracks ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +210,7 @@ func ListTracks(db *sql.DB) ([]Track, error) {
 	var out []Track
 	for rows.Next() {
 		var t Track
-		if err := rows.Scan(&t.ID, &t.Name, &t.City, &t.Address, &t.URL); err != nil {
+		if err := rows.Scan(&t.ID, &t.Name, &t.City, &t.Address, &t.URL, &t.TimeZone, &t.Slug); err != nil {
 			return nil, err
 		}
 		out = append(out, t)
@@ -189,11 +218,221 @@ func ListTracks(db *sql.DB) ([]Track, error) {
 	return out, rows.Err()
 }
 
+// ErrTrackInUse is returned by DeleteTrack when events still reference the track.
+var ErrTrackInUse = errors.New("track is referenced by events")
+
+// DeleteTrack removes a track. If events reference it, it fails with
+// ErrTrackInUse unless cascade is set, in which case those events and their
+// classes and rules are deleted too. It returns the number of events deleted.
+func DeleteTrack(db *sql.DB, trackID int64, cascade bool) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM tracks WHERE id = ?`, trackID).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		return 0, fmt.Errorf("track %d: %w", trackID, sql.ErrNoRows)
+	}
+	var events int64
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM events WHERE track_id = ?`, trackID).Scan(&events); err != nil {
+		return 0, err
+	}
+	if events > 0 && !cascade {
+		return 0, fmt.Errorf("track %d has %d events: %w", trackID, events, ErrTrackInUse)
+	}
+	if events > 0 {
+		if _, err := tx.Exec(`DELETE FROM event_class_rules WHERE event_class_id IN
+			(SELECT c.id FROM event_classes c JOIN events e ON c.event_id = e.id WHERE e.track_id = ?)`, trackID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM event_classes WHERE event_id IN (SELECT id FROM events WHERE track_id = ?)`, trackID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM events WHERE track_id = ?`, trackID); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM tracks WHERE id = ?`, trackID); err != nil {
+		return 0, err
+	}
+	return events, tx.Commit()
+}
+
+// MergeTracks moves every event from track fromID to track intoID and deletes
+// fromID, all in one transaction. It returns the number of events moved.
+// fromID's aggregator slug moves to intoID, so the next import finds the
+// kept track; tracks that both have a slug cannot be merged, as the feed
+// would recreate the one that was deleted.
+func MergeTracks(db *sql.DB, fromID, intoID int64) (int64, error) {
+	if fromID == intoID {
+		return 0, fmt.Errorf("cannot merge track %d into itself", fromID)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var slugs [2]string
+	for i, id := range []int64{fromID, intoID} {
+		if err := tx.QueryRow(`SELECT COALESCE(slug, '') FROM tracks WHERE id = ?`, id).Scan(&slugs[i]); err != nil {
+			return 0, fmt.Errorf("track %d: %w", id, err)
+		}
+	}
+	fromSlug, intoSlug := slugs[0], slugs[1]
+	if fromSlug != "" && intoSlug != "" {
+		return 0, fmt.Errorf("tracks %d and %d are both in the aggregator's feed, as %s and %s; fix the feed instead",
+			fromID, intoID, fromSlug, intoSlug)
+	}
+	res, err := tx.Exec(`UPDATE events SET track_id = ?, updated_at = datetime('now') WHERE track_id = ?`, intoID, fromID)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM tracks WHERE id = ?`, fromID); err != nil {
+		return 0, err
+	}
+	if fromSlug != "" {
+		if _, err := tx.Exec(`UPDATE tracks SET slug = ? WHERE id = ?`, fromSlug, intoID); err != nil {
+			return 0, fmt.Errorf("set slug of track %d: %w", intoID, err)
+		}
+	}
+	return moved, tx.Commit()
+}
+
+// EventMergeResult counts what MergeEvents moved.
+type EventMergeResult struct {
+	Classes    int // classes moved to the kept event
+	Rules      int // rules moved, including into a class the kept event already had
+	Suppressed int // merged aggregator events suppressed so a re-import does not bring them back
+}
+
+// MergeEvents folds the events fromIDs into event intoID, all in one
+// transaction: their classes move to intoID, and a class whose name intoID
+// already has gives its rules to that class instead, skipping rules it
+// already has. The other events are then deleted. Events among them that
+// were imported from the aggregator are suppressed, so importing the feed
+// again does not bring them back. If anything moved, the kept event counts
+// as edited here, as with UpdateEvent, so a re-import leaves it alone.
+func MergeEvents(db *sql.DB, intoID int64, fromIDs []int64) (EventMergeResult, error) {
+	var res EventMergeResult
+	tx, err := db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	var intoUUID string
+	if err := tx.QueryRow(`SELECT COALESCE(uuid, '') FROM events WHERE id = ?`, intoID).Scan(&intoUUID); err != nil {
+		return res, fmt.Errorf("event %d: %w", intoID, err)
+	}
+	for _, fromID := range fromIDs {
+		if fromID == intoID {
+			return res, fmt.Errorf("cannot merge event %d into itself", fromID)
+		}
+		var uuid, source string
+		if err := tx.QueryRow(`SELECT COALESCE(uuid, ''), source FROM events WHERE id = ?`, fromID).Scan(&uuid, &source); err != nil {
+			return res, fmt.Errorf("event %d: %w", fromID, err)
+		}
+
+		classes, err := tx.Query(`SELECT id, name FROM event_classes WHERE event_id = ? ORDER BY id`, fromID)
+		if err != nil {
+			return res, err
+		}
+		type class struct {
+			id   int64
+			name string
+		}
+		var moving []class
+		for classes.Next() {
+			var c class
+			if err := classes.Scan(&c.id, &c.name); err != nil {
+				classes.Close()
+				return res, err
+			}
+			moving = append(moving, c)
+		}
+		classes.Close()
+		if err := classes.Err(); err != nil {
+			return res, err
+		}
+
+		for _, c := range moving {
+			var existing int64
+			err := tx.QueryRow(`SELECT id FROM event_classes WHERE event_id = ? AND name = ? COLLATE NOCASE`, intoID, c.name).Scan(&existing)
+			if errors.Is(err, sql.ErrNoRows) {
+				if _, err := tx.Exec(`UPDATE event_classes SET event_id = ? WHERE id = ?`, intoID, c.id); err != nil {
+					return res, err
+				}
+				var rules int
+				if err := tx.QueryRow(`SELECT COUNT(*) FROM event_class_rules WHERE event_class_id = ?`, c.id).Scan(&rules); err != nil {
+					return res, err
+				}
+				res.Classes++
+				res.Rules += rules
+				continue
+			}
+			if err != nil {
+				return res, err
+			}
+			r, err := tx.Exec(`UPDATE event_class_rules SET event_class_id = ? WHERE event_class_id = ?
+				AND rule NOT IN (SELECT rule FROM event_class_rules WHERE event_class_id = ?)`, existing, c.id, existing)
+			if err != nil {
+				return res, err
+			}
+			n, err := r.RowsAffected()
+			if err != nil {
+				return res, err
+			}
+			res.Rules += int(n)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM event_class_rules WHERE event_class_id IN (SELECT id FROM event_classes WHERE event_id = ?)`, fromID); err != nil {
+			return res, err
+		}
+		if _, err := tx.Exec(`DELETE FROM event_classes WHERE event_id = ?`, fromID); err != nil {
+			return res, err
+		}
+		if _, err := tx.Exec(`DELETE FROM events WHERE id = ?`, fromID); err != nil {
+			return res, err
+		}
+		if source == SourceAggregator && uuid != "" {
+			if _, err := tx.Exec(`INSERT INTO suppressed_events(uuid, reason) VALUES(?, ?)
+				ON CONFLICT(uuid) DO UPDATE SET reason = excluded.reason`, uuid, "duplicate of "+intoUUID); err != nil {
+				return res, err
+			}
+			res.Suppressed++
+		}
+	}
+	if res.Classes > 0 || res.Rules > 0 {
+		if err := markEventsLocal(tx, []string{LocalClasses}, intoID); err != nil {
+			return res, err
+		}
+	}
+	return res, tx.Commit()
+}
+
 func ListEvents(dbx *sql.DB) ([]Event, error) {
-	q := `SELECT e.id, e.title, e.track_id, t.name as track_name, e.event_datetime, e.end_date, e.event_driver_fee, e.event_spectator_fee, e.url, e.description
-		FROM events e JOIN tracks t ON e.track_id = t.id
+	return queryEvents(dbx, "")
+}
+
+// queryEvents lists events matching the optional where clause. Dates are
+// read as text and parsed with ParseDate, so a stored date that cannot be
+// parsed is an error rather than a zero time.
+func queryEvents(dbx *sql.DB, where string, args ...any) ([]Event, error) {
+	q := `SELECT e.id, e.title, e.track_id, t.name as track_name, t.timezone, CAST(e.event_datetime AS TEXT), CAST(e.end_date AS TEXT), e.event_driver_fee, e.event_spectator_fee, e.url, e.description, COALESCE(e.external_id, ''), COALESCE(e.series, ''),
+		COALESCE(e.uuid, ''), e.source, e.status, e.rescheduled_to, e.local_fields, COALESCE(CAST(e.created_at AS TEXT), ''), COALESCE(CAST(e.updated_at AS TEXT), '')
+		FROM events e JOIN tracks t ON e.track_id = t.id ` + where + `
 		ORDER BY e.event_datetime`
-	rows, err := dbx.Query(q)
+	rows, err := dbx.Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -202,50 +441,42 @@ func ListEvents(dbx *sql.DB) ([]Event, error) {
 	for rows.Next() {
 		var ev Event
 		var eventDateStr, endDateStr sql.NullString
+		var localFields, createdAt, updatedAt string
 		var driverFee, spectatorFee sql.NullFloat64
-		if err := rows.Scan(&ev.ID, &ev.Title, &ev.TrackID, &ev.TrackName, &eventDateStr, &endDateStr, &driverFee, &spectatorFee, &ev.URL, &ev.Description); err != nil {
+		var rescheduledTo sql.NullInt64
+		if err := rows.Scan(&ev.ID, &ev.Title, &ev.TrackID, &ev.TrackName, &ev.TimeZone, &eventDateStr, &endDateStr, &driverFee, &spectatorFee, &ev.URL, &ev.Description, &ev.ExternalID, &ev.Series,
+			&ev.UUID, &ev.Source, &ev.Status, &rescheduledTo, &localFields, &createdAt, &updatedAt); err != nil {
 			return nil, err
 		}
+		ev.LocalFields = splitLocalFields(localFields)
+		if rescheduledTo.Valid {
+			ev.RescheduledTo = &rescheduledTo.Int64
+		}
+		if ev.CreatedAt, err = formatStoredTimestamp(createdAt); err != nil {
+			return nil, fmt.Errorf("event %d: created_at: %w", ev.ID, err)
+		}
+		if ev.UpdatedAt, err = formatStoredTimestamp(updatedAt); err != nil {
+			return nil, fmt.Errorf("event %d: updated_at: %w", ev.ID, err)
+		}
+		loc, err := LoadTimeZone(ev.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", ev.ID, err)
+		}
 		if eventDateStr.Valid {
-			// Try multiple datetime formats that SQLite might return
-			formats := []string{
-				"2006-01-02 15:04:05.999999999-07:00",
-				"2006-01-02 15:04:05.999999999",
-				"2006-01-02 15:04:05",
-				"2006-01-02T15:04:05.999999999Z07:00",
-				"2006-01-02T15:04:05.999999999Z",
-				"2006-01-02T15:04:05Z",
-				"2006-01-02T15:04:05",
-				"2006-01-02 15:04:05Z",
-				time.RFC3339,
-				time.RFC3339Nano,
-			}
-			for _, format := range formats {
-				if ts, err := time.Parse(format, eventDateStr.String); err == nil {
-					ev.StartDate = ts
-					break
-				}
+			ts, err := parseStoredDate(eventDateStr.String, loc)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: start date: %w", ev.ID, err)
 			}
+			ev.StartDate = ts
+			ev.StartUTC, ev.StartLocal = ts.UTC().Format(time.RFC3339), ts.Format(localLayout)
 		}
 		if endDateStr.Valid {
-			formats := []string{
-				"2006-01-02 15:04:05.999999999-07:00",
-				"2006-01-02 15:04:05.999999999",
-				"2006-01-02 15:04:05",
-				"2006-01-02T15:04:05.999999999Z07:00",
-				"2006-01-02T15:04:05.999999999Z",
-				"2006-01-02T15:04:05Z",
-				"2006-01-02T15:04:05",
-				"2006-01-02 15:04:05Z",
-				time.RFC3339,
-				time.RFC3339Nano,
-			}
-			for _, format := range formats {
-				if ts, err := time.Parse(format, endDateStr.String); err == nil {
-					ev.EndDate = &ts
-					break
-				}
+			ts, err := parseStoredDate(endDateStr.String, loc)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: end date: %w", ev.ID, err)
 			}
+			ev.EndDate = &ts
+			ev.EndUTC, ev.EndLocal = ts.UTC().Format(time.RFC3339), ts.Format(localLayout)
 		}
 		if driverFee.Valid {
 			v := driverFee.Float64
@@ -301,244 +532,50 @@ func ListEventClassRules(dbx *sql.DB) ([]EventClassRule, error) {
 	return out, rows.Err()
 }
 
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // CreateEvent inserts a new event into the database
 func CreateEvent(db *sql.DB, title string, trackID int64, startDate, endDate string, driverFee, spectatorFee *float64, url, description string) (int64, error) {
-	var endDateVal interface{}
-	if endDate != "" {
-		endDateVal = endDate
-	}
-	var driverFeeVal, spectatorFeeVal interface{}
-	if driverFee != nil {
-		driverFeeVal = *driverFee
-	}
-	if spectatorFee != nil {
-		spectatorFeeVal = *spectatorFee
-	}
+	return createEvent(db, title, trackID, startDate, endDate, driverFee, spectatorFee, url, description)
+}
 
-	result, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, end_date, event_driver_fee, event_spectator_fee, url, description)
-		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
-		title, trackID, startDate, endDateVal, driverFeeVal, spectatorFeeVal, url, description)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
+func createEvent(db execer, title string, trackID int64, startDate, endDate string, driverFee, spectatorFee *float64, url, description string) (int64, error) {
+	return insertEvent(db, eventFields{
+		Title:        title,
+		TrackID:      trackID,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		DriverFee:    driverFee,
+		SpectatorFee: spectatorFee,
+		URL:          url,
+		Description:  description,
+	})
 }
 
 // DeleteEvent removes an event and its associated classes and rules
 func DeleteEvent(db *sql.DB, eventID int64) error {
-	// Delete in order: rules -> classes -> event (due to foreign keys)
-	_, err := db.Exec(`DELETE FROM event_class_rules WHERE event_class_id IN (SELECT id FROM event_classes WHERE event_id = ?)`, eventID)
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(`DELETE FROM event_classes WHERE event_id = ?`, eventID)
+	defer tx.Rollback()
+	// Delete in order: rules -> classes -> event. ON DELETE CASCADE would do
+	// this too, but only on connections opened with foreign keys enabled.
+	_, err = tx.Exec(`DELETE FROM event_class_rules WHERE event_class_id IN (SELECT id FROM event_classes WHERE event_id = ?)`, eventID)
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(`DELETE FROM events WHERE id = ?`, eventID)
-	return err
-}
-
-// ImportEventsFromCSV imports events from a CSV file
-// Expected CSV columns: title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description
-func ImportEventsFromCSV(db *sql.DB, filename string) (int, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return 0, fmt.Errorf("open CSV: %w", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	// Read header
-	header, err := reader.Read()
+	_, err = tx.Exec(`DELETE FROM event_classes WHERE event_id = ?`, eventID)
 	if err != nil {
-		return 0, fmt.Errorf("read CSV header: %w", err)
-	}
-
-	// Validate header
-	expectedHeaders := []string{"title", "track_id", "start_date", "end_date", "driver_fee", "spectator_fee", "url", "description"}
-	if len(header) != len(expectedHeaders) {
-		return 0, fmt.Errorf("invalid CSV format: expected %d columns, got %d", len(expectedHeaders), len(header))
-	}
-
-	count := 0
-	lineNum := 1
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return count, fmt.Errorf("read CSV line %d: %w", lineNum+1, err)
-		}
-		lineNum++
-
-		if len(record) != 8 {
-			return count, fmt.Errorf("line %d: expected 8 columns, got %d", lineNum, len(record))
-		}
-
-		// Parse fields
-		title := strings.TrimSpace(record[0])
-		trackID, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
-		if err != nil {
-			return count, fmt.Errorf("line %d: invalid track_id: %w", lineNum, err)
-		}
-		startDate := strings.TrimSpace(record[2])
-		endDate := strings.TrimSpace(record[3])
-
-		var driverFee, spectatorFee *float64
-		if df := strings.TrimSpace(record[4]); df != "" {
-			val, err := strconv.ParseFloat(df, 64)
-			if err != nil {
-				return count, fmt.Errorf("line %d: invalid driver_fee: %w", lineNum, err)
-			}
-			driverFee = &val
-		}
-		if sf := strings.TrimSpace(record[5]); sf != "" {
-			val, err := strconv.ParseFloat(sf, 64)
-			if err != nil {
-				return count, fmt.Errorf("line %d: invalid spectator_fee: %w", lineNum, err)
-			}
-			spectatorFee = &val
-		}
-
-		url := strings.TrimSpace(record[6])
-		description := strings.TrimSpace(record[7])
-
-		// Create event
-		_, err = CreateEvent(db, title, trackID, startDate, endDate, driverFee, spectatorFee, url, description)
-		if err != nil {
-			return count, fmt.Errorf("line %d: create event: %w", lineNum, err)
-		}
-		count++
-	}
-
-	return count, nil
-}
-
-// ImportEventClassesFromCSV imports event classes from a CSV file
-// Expected CSV columns: event_id,name,buyin_fee
-func ImportEventClassesFromCSV(db *sql.DB, filename string) (int, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return 0, fmt.Errorf("open CSV: %w", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	// Read header
-	header, err := reader.Read()
-	if err != nil {
-		return 0, fmt.Errorf("read CSV header: %w", err)
-	}
-
-	// Validate header
-	expectedHeaders := []string{"event_id", "name", "buyin_fee"}
-	if len(header) != len(expectedHeaders) {
-		return 0, fmt.Errorf("invalid CSV format: expected %d columns, got %d", len(expectedHeaders), len(header))
-	}
-
-	count := 0
-	lineNum := 1
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return count, fmt.Errorf("read CSV line %d: %w", lineNum+1, err)
-		}
-		lineNum++
-
-		if len(record) != 3 {
-			return count, fmt.Errorf("line %d: expected 3 columns, got %d", lineNum, len(record))
-		}
-
-		// Parse fields
-		eventID, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
-		if err != nil {
-			return count, fmt.Errorf("line %d: invalid event_id: %w", lineNum, err)
-		}
-		name := strings.TrimSpace(record[1])
-
-		var buyinFee *float64
-		if bf := strings.TrimSpace(record[2]); bf != "" {
-			val, err := strconv.ParseFloat(bf, 64)
-			if err != nil {
-				return count, fmt.Errorf("line %d: invalid buyin_fee: %w", lineNum, err)
-			}
-			buyinFee = &val
-		}
-
-		// Insert class
-		var buyinFeeVal interface{}
-		if buyinFee != nil {
-			buyinFeeVal = *buyinFee
-		}
-		_, err = db.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, ?, ?)`,
-			eventID, name, buyinFeeVal)
-		if err != nil {
-			return count, fmt.Errorf("line %d: insert class: %w", lineNum, err)
-		}
-		count++
-	}
-
-	return count, nil
-}
-
-// ImportEventClassRulesFromCSV imports event class rules from a CSV file
-// Expected CSV columns: event_class_id,rule
-func ImportEventClassRulesFromCSV(db *sql.DB, filename string) (int, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return 0, fmt.Errorf("open CSV: %w", err)
+		return err
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	// Read header
-	header, err := reader.Read()
+	_, err = tx.Exec(`DELETE FROM events WHERE id = ?`, eventID)
 	if err != nil {
-		return 0, fmt.Errorf("read CSV header: %w", err)
-	}
-
-	// Validate header
-	expectedHeaders := []string{"event_class_id", "rule"}
-	if len(header) != len(expectedHeaders) {
-		return 0, fmt.Errorf("invalid CSV format: expected %d columns, got %d", len(expectedHeaders), len(header))
-	}
-
-	count := 0
-	lineNum := 1
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return count, fmt.Errorf("read CSV line %d: %w", lineNum+1, err)
-		}
-		lineNum++
-
-		if len(record) != 2 {
-			return count, fmt.Errorf("line %d: expected 2 columns, got %d", lineNum, len(record))
-		}
-
-		// Parse fields
-		classID, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
-		if err != nil {
-			return count, fmt.Errorf("line %d: invalid event_class_id: %w", lineNum, err)
-		}
-		rule := strings.TrimSpace(record[1])
-
-		// Insert rule
-		_, err = db.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES(?, ?)`,
-			classID, rule)
-		if err != nil {
-			return count, fmt.Errorf("line %d: insert rule: %w", lineNum, err)
-		}
-		count++
+		return err
 	}
-
-	return count, nil
+	return tx.Commit()
 }
@@ -2,21 +2,36 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// setupTestDB returns a database with the legacy schema brought up to date
+// by the repository migrations.
 func setupTestDB(t *testing.T) *sql.DB {
+	db := setupLegacyTestDB(t)
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// setupLegacyTestDB builds the schema of migrations 001 and 002 by hand,
+// without a schema_migrations ledger, as databases created before the
+// migrator existed have.
+func setupLegacyTestDB(t *testing.T) *sql.DB {
 	// Create temp directory for test database
 	tmpDir := t.TempDir()
 	testDBPath := filepath.Join(tmpDir, "test.db")
 
-	db, err := sql.Open("sqlite", testDBPath)
+	db, err := Open(testDBPath)
 	if err != nil {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
@@ -75,6 +90,41 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpenCreatesParentDirectory(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "nested", "dir", "test.db")
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		t.Errorf("Failed to ping database: %v", err)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Errorf("Database file was not created at %s: %v", dbPath, err)
+	}
+}
+
+func TestOpenPathWithQueryCharacters(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "what?#now", "test.db")
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	var fk int
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&fk); err != nil || fk != 1 {
+		t.Errorf("Expected foreign keys on, got %d (%v)", fk, err)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Errorf("Database file was not created at %s: %v", dbPath, err)
+	}
+}
+
 func TestMigrate(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -1380,3 +1430,276 @@ func TestCreateEventWithError(t *testing.T) {
 		t.Error("Expected error when creating event with closed database")
 	}
 }
+
+func TestDeleteTrack(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, err := CreateTrack(db, "Unused Track", "Test City", "123 Test St", "")
+	if err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	deleted, err := DeleteTrack(db, trackID, false)
+	if err != nil {
+		t.Fatalf("DeleteTrack failed: %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("Expected 0 events deleted, got %d", deleted)
+	}
+
+	tracks, _ := ListTracks(db)
+	if len(tracks) != 0 {
+		t.Errorf("Expected 0 tracks after deletion, got %d", len(tracks))
+	}
+
+	if _, err := DeleteTrack(db, trackID, false); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for missing track, got %v", err)
+	}
+}
+
+func TestDeleteTrackInUse(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := Seed(db); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+
+	// Track 1 has one event with two classes and five rules
+	_, err := DeleteTrack(db, 1, false)
+	if !errors.Is(err, ErrTrackInUse) {
+		t.Fatalf("Expected ErrTrackInUse, got %v", err)
+	}
+	tracks, _ := ListTracks(db)
+	if len(tracks) != 2 {
+		t.Errorf("Expected track to be kept, got %d tracks", len(tracks))
+	}
+
+	deleted, err := DeleteTrack(db, 1, true)
+	if err != nil {
+		t.Fatalf("DeleteTrack with cascade failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 event deleted, got %d", deleted)
+	}
+
+	events, _ := ListEvents(db)
+	if len(events) != 1 {
+		t.Errorf("Expected 1 remaining event, got %d", len(events))
+	}
+	classes, _ := ListEventClasses(db)
+	if len(classes) != 1 {
+		t.Errorf("Expected 1 remaining class, got %d", len(classes))
+	}
+	rules, _ := ListEventClassRules(db)
+	if len(rules) != 2 {
+		t.Errorf("Expected 2 remaining rules, got %d", len(rules))
+	}
+}
+
+func TestMergeTracks(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := Seed(db); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	dupID, err := CreateTrack(db, "XRP", "Ferris", "1800 S Interstate 45, Ferris, TX", "")
+	if err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := CreateEvent(db, "Test and Tune", dupID, "2026-03-06 18:00:00", "", nil, nil, "", ""); err != nil {
+			t.Fatalf("CreateEvent failed: %v", err)
+		}
+	}
+
+	moved, err := MergeTracks(db, dupID, 2)
+	if err != nil {
+		t.Fatalf("MergeTracks failed: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("Expected 2 events moved, got %d", moved)
+	}
+
+	if _, err := GetTrack(db, dupID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected merged track to be deleted, got %v", err)
+	}
+	events, _ := ListEvents(db)
+	for _, e := range events {
+		if e.TrackID == dupID {
+			t.Errorf("Event %d still references merged track", e.ID)
+		}
+	}
+	if len(events) != 4 {
+		t.Errorf("Expected 4 events, got %d", len(events))
+	}
+}
+
+func TestMergeTracksInvalid(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "")
+
+	if _, err := MergeTracks(db, trackID, trackID); err == nil {
+		t.Error("Expected error merging a track into itself")
+	}
+	if _, err := MergeTracks(db, trackID, 99999); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for missing target, got %v", err)
+	}
+	if _, err := GetTrack(db, trackID); err != nil {
+		t.Errorf("Expected source track to be kept after failed merge: %v", err)
+	}
+}
+
+func TestMergeEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Xtreme Raceway Park", "Ferris", "", "")
+	keep, _ := CreateEvent(db, "TMCCC Race #2", trackID, "2026-04-12 08:00:00", "", nil, nil, "", "")
+	dup, _ := CreateEvent(db, " TMCCC Race #2 Xtreme", trackID, "2026-04-12 09:00:00", "", nil, nil, "", "")
+	if _, err := db.Exec(`UPDATE events SET source = ? WHERE id IN (?, ?)`, SourceAggregator, keep, dup); err != nil {
+		t.Fatalf("Failed to mark events as imported: %v", err)
+	}
+	addClass := func(eventID int64, name string, rules ...string) {
+		r, err := db.Exec(`INSERT INTO event_classes(event_id, name) VALUES(?, ?)`, eventID, name)
+		if err != nil {
+			t.Fatalf("Failed to add class: %v", err)
+		}
+		classID, _ := r.LastInsertId()
+		for _, rule := range rules {
+			if _, err := db.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES(?, ?)`, classID, rule); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+		}
+	}
+	addClass(keep, "Stock Muscle", "DOT tires")
+	addClass(dup, "stock muscle", "DOT tires", "9.40 & slower")
+	addClass(dup, "Street Muscle", "Helmet required")
+
+	res, err := MergeEvents(db, keep, []int64{dup})
+	if err != nil {
+		t.Fatalf("MergeEvents failed: %v", err)
+	}
+	if res != (EventMergeResult{Classes: 1, Rules: 2, Suppressed: 1}) {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if ev, _ := GetEvent(db, keep); ev.Source != SourceLocal {
+		t.Errorf("Expected the kept event to count as local, got %q", ev.Source)
+	}
+	if _, err := GetEvent(db, dup); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected the duplicate to be deleted, got %v", err)
+	}
+	classes, _ := ListEventClasses(db)
+	rules, _ := ListEventClassRules(db)
+	if len(classes) != 2 || classes[0].EventID != keep || classes[1].EventID != keep {
+		t.Errorf("Expected both classes on the kept event, got %+v", classes)
+	}
+	if len(rules) != 3 {
+		t.Errorf("Expected the shared rule once and 2 moved rules, got %+v", rules)
+	}
+	suppressed, _ := ListSuppressed(db)
+	if len(suppressed) != 1 || !strings.HasPrefix(suppressed[0].Reason, "duplicate of ") {
+		t.Errorf("Expected the imported duplicate to be suppressed, got %+v", suppressed)
+	}
+
+	if _, err := MergeEvents(db, keep, []int64{keep}); err == nil {
+		t.Error("Expected error merging an event into itself")
+	}
+	if _, err := MergeEvents(db, keep, []int64{99999}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing event, got %v", err)
+	}
+}
+
+func TestOpenEnforcesForeignKeys(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	var enabled int
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		t.Fatalf("Failed to read foreign_keys pragma: %v", err)
+	}
+	if enabled != 1 {
+		t.Error("Expected foreign keys to be enabled")
+	}
+
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatalf("Failed to read journal_mode pragma: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("Expected WAL journal mode, got %s", mode)
+	}
+
+	if _, err := CreateEvent(db, "Orphan", 99999, "2025-12-01 10:00:00", "", nil, nil, "", ""); err == nil {
+		t.Error("Expected foreign key error for event with unknown track_id")
+	}
+	if _, err := db.Exec("INSERT INTO event_classes(event_id, name) VALUES(?, ?)", 99999, "Orphan Class"); err == nil {
+		t.Error("Expected foreign key error for class with unknown event_id")
+	}
+}
+
+func TestImportEventsFromCSVIsAllOrNothing(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "events.csv")
+
+	// The third row references a track that does not exist
+	csvContent := `title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description
+Event 1,` + strconv.FormatInt(trackID, 10) + `,2025-12-01 10:00:00,,,,,
+Event 2,` + strconv.FormatInt(trackID, 10) + `,2025-12-02 10:00:00,,,,,
+Event 3,99999,2025-12-03 10:00:00,,,,,`
+
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("Failed to create CSV file: %v", err)
+	}
+
+	count, err := ImportEventsFromCSV(db, csvFile)
+	if err == nil {
+		t.Fatal("Expected error for unknown track_id")
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 events reported on failure, got %d", count)
+	}
+
+	events, _ := ListEvents(db)
+	if len(events) != 0 {
+		t.Errorf("Expected no events to be committed, got %d", len(events))
+	}
+}
+
+func TestImportEventClassRulesFromCSVIsAllOrNothing(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := Seed(db); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	classes, _ := ListEventClasses(db)
+
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "rules.csv")
+	csvContent := `event_class_id,rule
+` + strconv.FormatInt(classes[0].ID, 10) + `,New rule
+99999,Orphan rule`
+
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("Failed to create CSV file: %v", err)
+	}
+
+	if _, err := ImportEventClassRulesFromCSV(db, csvFile); err == nil {
+		t.Fatal("Expected error for unknown event_class_id")
+	}
+
+	rules, _ := ListEventClassRules(db)
+	if len(rules) != 7 {
+		t.Errorf("Expected only the 7 seeded rules, got %d", len(rules))
+	}
+}
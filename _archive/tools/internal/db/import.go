@@ -0,0 +1,509 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ImportOptions controls how a CSV file is imported.
+type ImportOptions struct {
+	// DryRun validates and inserts every row inside a transaction that is
+	// always rolled back, so nothing is committed.
+	DryRun bool
+	// Upsert updates rows that already exist instead of inserting them
+	// again. Events are matched by external_id, or else by track, start
+	// date and normalized title; classes by event and name; rules by class
+	// and text.
+	Upsert bool
+}
+
+// ImportResult counts what an import did. Without Upsert every imported row
+// is Created.
+type ImportResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+// Total is the number of rows imported.
+func (r ImportResult) Total() int {
+	return r.Created + r.Updated + r.Unchanged
+}
+
+// rowOutcome is what importing one row did.
+type rowOutcome int
+
+const (
+	rowCreated rowOutcome = iota
+	rowUpdated
+	rowUnchanged
+)
+
+func (r *ImportResult) add(o rowOutcome) {
+	switch o {
+	case rowCreated:
+		r.Created++
+	case rowUpdated:
+		r.Updated++
+	case rowUnchanged:
+		r.Unchanged++
+	}
+}
+
+// LineError is a problem with a single CSV line.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+// ImportError reports every CSV line that failed to import. When it is
+// returned, nothing from the file was committed.
+type ImportError struct {
+	Lines []LineError
+}
+
+func (e *ImportError) Error() string {
+	if len(e.Lines) == 1 {
+		return e.Lines[0].Error()
+	}
+	msgs := make([]string, len(e.Lines))
+	for i, l := range e.Lines {
+		msgs[i] = l.Error()
+	}
+	return fmt.Sprintf("%d lines failed:\n  %s", len(e.Lines), strings.Join(msgs, "\n  "))
+}
+
+// csvImport describes one CSV format: its expected header and how to insert
+// a single record.
+type csvImport struct {
+	columns []string
+	insert  func(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error)
+}
+
+// ImportEventsFromCSV imports events from a CSV file in a single transaction;
+// if any line fails, nothing is imported.
+// Expected CSV columns: title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description
+// with optional trailing external_id and series columns. With both, the
+// track_id column may instead be a track column holding a track ID or name,
+// and then a status column may follow, as CSV exports write them. An empty
+// status is scheduled for a new event and keeps an existing event's status.
+func ImportEventsFromCSV(db *sql.DB, filename string) (int, error) {
+	res, err := ImportEventsFromCSVWithOptions(db, filename, ImportOptions{})
+	return res.Total(), err
+}
+
+// ImportEventsFromCSVWithOptions is ImportEventsFromCSV with options.
+func ImportEventsFromCSVWithOptions(db *sql.DB, filename string, opts ImportOptions) (ImportResult, error) {
+	return runCSVImport(db, filename, opts,
+		csvImport{
+			columns: []string{"title", "track_id", "start_date", "end_date", "driver_fee", "spectator_fee", "url", "description"},
+			insert:  insertEventRecord,
+		},
+		csvImport{
+			columns: []string{"title", "track_id", "start_date", "end_date", "driver_fee", "spectator_fee", "url", "description", "external_id"},
+			insert:  insertEventRecord,
+		},
+		csvImport{
+			columns: []string{"title", "track_id", "start_date", "end_date", "driver_fee", "spectator_fee", "url", "description", "external_id", "series"},
+			insert:  insertEventRecord,
+		},
+		csvImport{
+			columns: []string{"title", "track", "start_date", "end_date", "driver_fee", "spectator_fee", "url", "description", "external_id", "series"},
+			insert:  insertEventRecordByTrack,
+		},
+		csvImport{
+			columns: []string{"title", "track", "start_date", "end_date", "driver_fee", "spectator_fee", "url", "description", "external_id", "series", "status"},
+			insert:  insertEventRecordByTrack,
+		},
+	)
+}
+
+// ImportEventClassesFromCSV imports event classes from a CSV file in a single
+// transaction; if any line fails, nothing is imported.
+// Expected CSV columns, either:
+//
+//	event_id,name,buyin_fee
+//	event_title,event_start_date,track,name,buyin_fee
+//
+// The second form names the event instead of using its ID; track is a track
+// ID or name.
+func ImportEventClassesFromCSV(db *sql.DB, filename string) (int, error) {
+	res, err := ImportEventClassesFromCSVWithOptions(db, filename, ImportOptions{})
+	return res.Total(), err
+}
+
+// ImportEventClassesFromCSVWithOptions is ImportEventClassesFromCSV with options.
+func ImportEventClassesFromCSVWithOptions(db *sql.DB, filename string, opts ImportOptions) (ImportResult, error) {
+	return runCSVImport(db, filename, opts,
+		csvImport{
+			columns: []string{"event_id", "name", "buyin_fee"},
+			insert:  insertEventClassRecord,
+		},
+		csvImport{
+			columns: []string{"event_title", "event_start_date", "track", "name", "buyin_fee"},
+			insert:  insertEventClassRecordByKey,
+		},
+	)
+}
+
+// ImportEventClassRulesFromCSV imports event class rules from a CSV file in a
+// single transaction; if any line fails, nothing is imported.
+// Expected CSV columns, either:
+//
+//	event_class_id,rule
+//	event_title,event_start_date,track,class_name,rule
+//
+// The second form names the class by its event and class name instead of
+// using its ID.
+func ImportEventClassRulesFromCSV(db *sql.DB, filename string) (int, error) {
+	res, err := ImportEventClassRulesFromCSVWithOptions(db, filename, ImportOptions{})
+	return res.Total(), err
+}
+
+// ImportEventClassRulesFromCSVWithOptions is ImportEventClassRulesFromCSV with options.
+func ImportEventClassRulesFromCSVWithOptions(db *sql.DB, filename string, opts ImportOptions) (ImportResult, error) {
+	return runCSVImport(db, filename, opts,
+		csvImport{
+			columns: []string{"event_class_id", "rule"},
+			insert:  insertEventClassRuleRecord,
+		},
+		csvImport{
+			columns: []string{"event_title", "event_start_date", "track", "class_name", "rule"},
+			insert:  insertEventClassRuleRecordByKey,
+		},
+	)
+}
+
+// runCSVImport inserts every record of filename in one transaction. Bad lines
+// do not stop the import: every line is checked so that all failures can be
+// reported together in an *ImportError, and then the transaction is rolled
+// back. It is also rolled back in dry-run mode.
+//
+// The header picks which of formats is used. The first format is the
+// original ID-based one and is also used for headers that only match its
+// column count, as older files were never checked by name.
+func runCSVImport(db *sql.DB, filename string, opts ImportOptions, formats ...csvImport) (ImportResult, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("open CSV: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// column counts are checked per record so one short line does not hide the rest
+	reader.FieldsPerRecord = -1
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("read CSV header: %w", err)
+	}
+
+	// Validate header
+	spec, err := pickCSVFormat(header, formats)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer tx.Rollback()
+
+	var failed []LineError
+	var res ImportResult
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		lineNum, _ := reader.FieldPos(0)
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				failed = append(failed, LineError{Line: perr.StartLine, Err: perr.Err})
+				continue
+			}
+			return ImportResult{}, fmt.Errorf("read CSV line %d: %w", lineNum, err)
+		}
+
+		if len(record) != len(spec.columns) {
+			failed = append(failed, LineError{Line: lineNum, Err: fmt.Errorf("expected %d columns, got %d", len(spec.columns), len(record))})
+			continue
+		}
+		outcome, err := spec.insert(tx, record, opts)
+		if err != nil {
+			failed = append(failed, LineError{Line: lineNum, Err: err})
+			continue
+		}
+		res.add(outcome)
+	}
+
+	if len(failed) > 0 {
+		return ImportResult{}, &ImportError{Lines: failed}
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, err
+	}
+	return res, nil
+}
+
+// pickCSVFormat returns the format whose columns match header.
+func pickCSVFormat(header []string, formats []csvImport) (csvImport, error) {
+	for _, f := range formats {
+		if headerMatches(header, f.columns) {
+			return f, nil
+		}
+	}
+	if len(header) == len(formats[0].columns) {
+		return formats[0], nil
+	}
+	if len(formats) == 1 {
+		return csvImport{}, fmt.Errorf("invalid CSV format: expected %d columns, got %d", len(formats[0].columns), len(header))
+	}
+	expected := make([]string, len(formats))
+	for i, f := range formats {
+		expected[i] = strings.Join(f.columns, ",")
+	}
+	return csvImport{}, fmt.Errorf("invalid CSV header %q: expected one of %s",
+		strings.Join(header, ","), strings.Join(expected, " or "))
+}
+
+func headerMatches(header, columns []string) bool {
+	if len(header) != len(columns) {
+		return false
+	}
+	for i, col := range columns {
+		// strip a UTF-8 BOM left by spreadsheet exports
+		if !strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")), col) {
+			return false
+		}
+	}
+	return true
+}
+
+func insertEventRecord(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error) {
+	// Parse fields
+	f := eventFields{Title: strings.TrimSpace(record[0])}
+	trackID, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid track_id: %w", err)
+	}
+	f.TrackID = trackID
+	f.StartDate = strings.TrimSpace(record[2])
+	f.EndDate = strings.TrimSpace(record[3])
+
+	if f.DriverFee, err = parseOptionalFee(record[4], "driver_fee"); err != nil {
+		return 0, err
+	}
+	if f.SpectatorFee, err = parseOptionalFee(record[5], "spectator_fee"); err != nil {
+		return 0, err
+	}
+
+	f.URL = strings.TrimSpace(record[6])
+	f.Description = strings.TrimSpace(record[7])
+	if len(record) > 8 {
+		f.ExternalID = strings.TrimSpace(record[8])
+	}
+	if len(record) > 9 {
+		f.Series = strings.TrimSpace(record[9])
+	}
+	if len(record) > 10 {
+		if f.Status, err = parseStatus(record[10]); err != nil {
+			return 0, err
+		}
+	}
+
+	if opts.Upsert {
+		return upsertEvent(tx, f)
+	}
+	// Create event
+	if _, err := insertEvent(tx, f); err != nil {
+		return 0, fmt.Errorf("create event: %w", err)
+	}
+	return rowCreated, nil
+}
+
+func insertEventRecordByTrack(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error) {
+	trackID, err := ResolveTrack(tx, record[1])
+	if err != nil {
+		return 0, err
+	}
+	record = append([]string(nil), record...)
+	record[1] = strconv.FormatInt(trackID, 10)
+	return insertEventRecord(tx, record, opts)
+}
+
+// ImportEventReschedulesFromCSV links events to their replacements, as
+// RescheduleEvent does, from a CSV file in a single transaction; if any line
+// fails, nothing is imported.
+// Expected CSV columns:
+//
+//	event_title,event_start_date,track,replacement_title,replacement_start_date,replacement_track
+//
+// Both events must already exist, so this runs after the events import. The
+// event keeps the status it was imported with, which must not be scheduled,
+// and RescheduleEvent's checks apply: the event must not be cancelled, and
+// the replacement must be scheduled.
+func ImportEventReschedulesFromCSV(db *sql.DB, filename string) (int, error) {
+	res, err := ImportEventReschedulesFromCSVWithOptions(db, filename, ImportOptions{})
+	return res.Total(), err
+}
+
+// ImportEventReschedulesFromCSVWithOptions is ImportEventReschedulesFromCSV with options.
+func ImportEventReschedulesFromCSVWithOptions(db *sql.DB, filename string, opts ImportOptions) (ImportResult, error) {
+	return runCSVImport(db, filename, opts,
+		csvImport{
+			columns: []string{"event_title", "event_start_date", "track", "replacement_title", "replacement_start_date", "replacement_track"},
+			insert:  insertEventRescheduleRecord,
+		},
+	)
+}
+
+func insertEventRescheduleRecord(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error) {
+	eventID, err := ResolveEvent(tx, EventKey{Title: record[0], StartDate: record[1], Track: record[2]})
+	if err != nil {
+		return 0, err
+	}
+	replacementID, err := ResolveEvent(tx, EventKey{Title: record[3], StartDate: record[4], Track: record[5]})
+	if err != nil {
+		return 0, fmt.Errorf("replacement: %w", err)
+	}
+
+	var current sql.NullInt64
+	if err := tx.QueryRow(`SELECT rescheduled_to FROM events WHERE id = ?`, eventID).Scan(&current); err != nil {
+		return 0, fmt.Errorf("event %d: %w", eventID, err)
+	}
+	outcome := rowCreated
+	if opts.Upsert && current.Valid {
+		if current.Int64 == replacementID {
+			return rowUnchanged, nil
+		}
+		outcome = rowUpdated
+	}
+	status, err := checkReplacement(tx, eventID, replacementID)
+	if err != nil {
+		return 0, err
+	}
+	if status == StatusScheduled {
+		return 0, fmt.Errorf("event %d is scheduled; import its status first", eventID)
+	}
+	if _, err := tx.Exec(`UPDATE events SET rescheduled_to = ? WHERE id = ?`, replacementID, eventID); err != nil {
+		return 0, fmt.Errorf("reschedule event %d: %w", eventID, err)
+	}
+	return outcome, markEventsLocal(tx, []string{LocalStatus}, eventID)
+}
+
+func insertEventClassRecord(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error) {
+	// Parse fields
+	eventID, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event_id: %w", err)
+	}
+	name := strings.TrimSpace(record[1])
+	buyinFee, err := parseOptionalFee(record[2], "buyin_fee")
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Upsert {
+		outcome, err := upsertEventClass(tx, eventID, name, buyinFee)
+		if err != nil || outcome == rowUnchanged {
+			return outcome, err
+		}
+		return outcome, markEventsLocal(tx, []string{LocalClasses}, eventID)
+	}
+	// Insert class
+	var buyinFeeVal interface{}
+	if buyinFee != nil {
+		buyinFeeVal = *buyinFee
+	}
+	if _, err := tx.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, ?, ?)`,
+		eventID, name, buyinFeeVal); err != nil {
+		return 0, fmt.Errorf("insert class: %w", err)
+	}
+	return rowCreated, markEventsLocal(tx, []string{LocalClasses}, eventID)
+}
+
+func insertEventClassRecordByKey(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error) {
+	eventID, err := ResolveEvent(tx, EventKey{Title: record[0], StartDate: record[1], Track: record[2]})
+	if err != nil {
+		return 0, err
+	}
+	return insertEventClassRecord(tx, append([]string{strconv.FormatInt(eventID, 10)}, record[3:]...), opts)
+}
+
+func insertEventClassRuleRecordByKey(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error) {
+	eventID, err := ResolveEvent(tx, EventKey{Title: record[0], StartDate: record[1], Track: record[2]})
+	if err != nil {
+		return 0, err
+	}
+	classID, err := ResolveEventClass(tx, eventID, record[3])
+	if err != nil {
+		return 0, err
+	}
+	return insertEventClassRuleRecord(tx, []string{strconv.FormatInt(classID, 10), record[4]}, opts)
+}
+
+func insertEventClassRuleRecord(tx *sql.Tx, record []string, opts ImportOptions) (rowOutcome, error) {
+	// Parse fields
+	classID, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event_class_id: %w", err)
+	}
+	rule := strings.TrimSpace(record[1])
+
+	if opts.Upsert {
+		outcome, err := upsertEventClassRule(tx, classID, rule)
+		if err != nil || outcome == rowUnchanged {
+			return outcome, err
+		}
+		return outcome, markClassEventsLocal(tx, classID)
+	}
+	// Insert rule
+	if _, err := tx.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES(?, ?)`,
+		classID, rule); err != nil {
+		return 0, fmt.Errorf("insert rule: %w", err)
+	}
+	return rowCreated, markClassEventsLocal(tx, classID)
+}
+
+// parseStatus parses a status column; an empty value means none was given.
+func parseStatus(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s != "" && !ValidStatus(s) {
+		return "", fmt.Errorf("invalid status %q: expected %s, %s, %s or %s",
+			s, StatusScheduled, StatusPostponed, StatusCancelled, StatusRainedOut)
+	}
+	return s, nil
+}
+
+// parseOptionalFee parses a fee column; an empty value means no fee.
+func parseOptionalFee(s, column string) (*float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	val, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", column, err)
+	}
+	return &val, nil
+}
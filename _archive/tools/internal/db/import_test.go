@@ -0,0 +1,449 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func writeTestCSV(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create CSV file: %v", err)
+	}
+	return path
+}
+
+func TestImportReportsEveryBadLine(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	track := strconv.FormatInt(trackID, 10)
+
+	csvFile := writeTestCSV(t, "events.csv", `title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description
+Good Event,`+track+`,2025-12-01 10:00:00,,,,,
+Bad Track,abc,2025-12-02 10:00:00,,,,,
+Bad Fee,`+track+`,2025-12-03 10:00:00,,lots,,,
+Short Line,`+track+`
+Another Good Event,`+track+`,2025-12-05 10:00:00,,,,,`)
+
+	count, err := ImportEventsFromCSV(db, csvFile)
+	if count != 0 {
+		t.Errorf("Expected 0 events reported on failure, got %d", count)
+	}
+
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("Expected *ImportError, got %v", err)
+	}
+	wantLines := []int{3, 4, 5}
+	if len(importErr.Lines) != len(wantLines) {
+		t.Fatalf("Expected %d line errors, got %d: %v", len(wantLines), len(importErr.Lines), err)
+	}
+	for i, want := range wantLines {
+		if got := importErr.Lines[i].Line; got != want {
+			t.Errorf("Error %d: expected line %d, got %d (%v)", i, want, got, importErr.Lines[i])
+		}
+	}
+
+	events, _ := ListEvents(db)
+	if len(events) != 0 {
+		t.Errorf("Expected no events to be committed, got %d", len(events))
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	track := strconv.FormatInt(trackID, 10)
+
+	csvFile := writeTestCSV(t, "events.csv", `title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description
+Event 1,`+track+`,2025-12-01 10:00:00,,,,,
+Event 2,`+track+`,2025-12-02 10:00:00,,,,,`)
+
+	res, err := ImportEventsFromCSVWithOptions(db, csvFile, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if res.Created != 2 {
+		t.Errorf("Expected dry run to report 2 events, got %d", res.Created)
+	}
+
+	events, _ := ListEvents(db)
+	if len(events) != 0 {
+		t.Errorf("Expected dry run to commit nothing, got %d events", len(events))
+	}
+}
+
+func TestImportDryRunChecksForeignKeys(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	csvFile := writeTestCSV(t, "classes.csv", `event_id,name,buyin_fee
+99999,Pro Mod,100`)
+
+	_, err := ImportEventClassesFromCSVWithOptions(db, csvFile, ImportOptions{DryRun: true})
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("Expected *ImportError for unknown event_id, got %v", err)
+	}
+	if importErr.Lines[0].Line != 2 {
+		t.Errorf("Expected error on line 2, got %d", importErr.Lines[0].Line)
+	}
+}
+
+func TestImportClassesAndRulesByNaturalKey(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := CreateTrack(db, "Xtreme Raceway Park", "Ferris", "1800 S I-45", "https://xrp.example"); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	eventsFile := writeTestCSV(t, "events.csv", `title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description
+TMCCC Series,1,2026-03-22 09:00:00,2026-03-22 23:00:00,40,20,https://tmccc.org,
+TMCCC Series,1,2026-06-28 09:00:00,2026-06-28 23:00:00,40,20,https://tmccc.org,`)
+	classesFile := writeTestCSV(t, "classes.csv", `event_title,event_start_date,track,name,buyin_fee
+TMCCC Series,2026-03-22,Xtreme Raceway Park,Stock Muscle,40
+TMCCC Series,2026-06-28 09:00:00,xtreme raceway park,Stock Muscle,40`)
+	rulesFile := writeTestCSV(t, "rules.csv", `event_title,event_start_date,track,class_name,rule
+TMCCC Series,2026-03-22,1,Stock Muscle,1/8 mile- 9.40 & Slower
+TMCCC Series,2026-06-28,1,stock muscle,1/4 mile- 14.69 & Slower`)
+
+	for _, step := range []struct {
+		name     string
+		file     string
+		importFn func(*sql.DB, string) (int, error)
+	}{
+		{"events", eventsFile, ImportEventsFromCSV},
+		{"classes", classesFile, ImportEventClassesFromCSV},
+		{"rules", rulesFile, ImportEventClassRulesFromCSV},
+	} {
+		count, err := step.importFn(db, step.file)
+		if err != nil {
+			t.Fatalf("Importing %s failed: %v", step.name, err)
+		}
+		if count != 2 {
+			t.Errorf("Expected 2 %s imported, got %d", step.name, count)
+		}
+	}
+
+	events, _ := ListEvents(db)
+	classes, _ := ListEventClasses(db)
+	rules, _ := ListEventClassRules(db)
+	if len(classes) != 2 || classes[0].EventID == classes[1].EventID {
+		t.Fatalf("Expected one class per event, got %+v", classes)
+	}
+	if classes[0].EventID != events[0].ID {
+		t.Errorf("Expected first class on event %d, got %d", events[0].ID, classes[0].EventID)
+	}
+	if len(rules) != 2 || rules[0].EventClassID != classes[0].ID || rules[1].EventClassID != classes[1].ID {
+		t.Errorf("Expected one rule per class, got %+v", rules)
+	}
+}
+
+func TestImportClassesByNaturalKeyReportsUnknownEvent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com"); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	classesFile := writeTestCSV(t, "classes.csv", `event_title,event_start_date,track,name,buyin_fee
+Missing Event,2026-03-22,Test Track,Stock,40
+Missing Event,2026-03-22,No Such Track,Stock,40`)
+
+	_, err := ImportEventClassesFromCSV(db, classesFile)
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("Expected *ImportError, got %v", err)
+	}
+	if len(importErr.Lines) != 2 {
+		t.Fatalf("Expected 2 line errors, got %v", err)
+	}
+	for _, l := range importErr.Lines {
+		if !errors.Is(l, sql.ErrNoRows) {
+			t.Errorf("Expected not-found error, got %v", l)
+		}
+	}
+}
+
+func TestResolveEventAmbiguous(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	CreateEvent(db, "Test N Tune", trackID, "2026-03-22 09:00:00", "", nil, nil, "", "")
+	CreateEvent(db, "Test N Tune", trackID, "2026-03-22 18:00:00", "", nil, nil, "", "")
+
+	if _, err := ResolveEvent(db, EventKey{Title: "Test N Tune", StartDate: "2026-03-22", Track: "Test Track"}); err == nil {
+		t.Error("Expected an ambiguous match error")
+	}
+	id, err := ResolveEvent(db, EventKey{Title: "Test N Tune", StartDate: "2026-03-22 18:00:00", Track: "Test Track"})
+	if err != nil {
+		t.Fatalf("ResolveEvent with full start time failed: %v", err)
+	}
+	if id != 2 {
+		t.Errorf("Expected event 2, got %d", id)
+	}
+}
+
+func TestImportEventsUpsert(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	track := strconv.FormatInt(trackID, 10)
+	header := "title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description\n"
+
+	first := writeTestCSV(t, "events.csv", header+
+		"Fall Nationals,"+track+",2025-10-03 08:00:00,,50,20,https://a.example,NHRA fall event\n"+
+		"Friday Night Drags,"+track+",2025-10-24 18:00:00,,30,,https://b.example,Test and tune")
+	res, err := ImportEventsFromCSVWithOptions(db, first, ImportOptions{Upsert: true})
+	if err != nil {
+		t.Fatalf("First upsert failed: %v", err)
+	}
+	if res != (ImportResult{Created: 2}) {
+		t.Errorf("Expected 2 created, got %+v", res)
+	}
+
+	// same events with a re-spaced title and a changed fee, plus a new one
+	second := writeTestCSV(t, "events.csv", header+
+		"Fall Nationals,"+track+",2025-10-03 08:00:00,,50,20,https://a.example,NHRA fall event\n"+
+		"friday night  drags!,"+track+",2025-10-24 18:00:00,,35,,https://b.example,Test and tune\n"+
+		"Street Legal Drags,"+track+",2025-11-15 09:00:00,,,,,")
+	res, err = ImportEventsFromCSVWithOptions(db, second, ImportOptions{Upsert: true})
+	if err != nil {
+		t.Fatalf("Second upsert failed: %v", err)
+	}
+	if res != (ImportResult{Created: 1, Updated: 1, Unchanged: 1}) {
+		t.Errorf("Expected 1 created, 1 updated, 1 unchanged, got %+v", res)
+	}
+
+	events, _ := ListEvents(db)
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	if events[1].DriverFee == nil || *events[1].DriverFee != 35 {
+		t.Errorf("Expected driver fee to be updated to 35, got %v", events[1].DriverFee)
+	}
+}
+
+func TestImportEventsUpsertByExternalID(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	track := strconv.FormatInt(trackID, 10)
+	header := "title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description,external_id\n"
+
+	first := writeTestCSV(t, "events.csv", header+
+		"Fall Nationals,"+track+",2025-10-03 08:00:00,,,,,,nhra-fall-2025")
+	if _, err := ImportEventsFromCSVWithOptions(db, first, ImportOptions{Upsert: true}); err != nil {
+		t.Fatalf("First upsert failed: %v", err)
+	}
+
+	// renamed and moved a week: only the external_id still matches
+	second := writeTestCSV(t, "events.csv", header+
+		"NHRA Fall Nationals,"+track+",2025-10-10 08:00:00,,,,,,nhra-fall-2025")
+	res, err := ImportEventsFromCSVWithOptions(db, second, ImportOptions{Upsert: true})
+	if err != nil {
+		t.Fatalf("Second upsert failed: %v", err)
+	}
+	if res != (ImportResult{Updated: 1}) {
+		t.Errorf("Expected 1 updated, got %+v", res)
+	}
+
+	events, _ := ListEvents(db)
+	if len(events) != 1 || events[0].Title != "NHRA Fall Nationals" || events[0].ExternalID != "nhra-fall-2025" {
+		t.Errorf("Expected the event to be updated in place, got %+v", events)
+	}
+}
+
+func TestImportClassesAndRulesUpsert(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := Seed(db); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	events, _ := ListEvents(db)
+	classesBefore, _ := ListEventClasses(db)
+	rulesBefore, _ := ListEventClassRules(db)
+	event := strconv.FormatInt(events[0].ID, 10)
+
+	classes := writeTestCSV(t, "classes.csv", "event_id,name,buyin_fee\n"+
+		event+",Pro Street,125\n"+
+		event+",Street,50\n"+
+		event+",Bracket,75")
+	res, err := ImportEventClassesFromCSVWithOptions(db, classes, ImportOptions{Upsert: true})
+	if err != nil {
+		t.Fatalf("Class upsert failed: %v", err)
+	}
+	if res != (ImportResult{Created: 1, Updated: 1, Unchanged: 1}) {
+		t.Errorf("Expected 1 created, 1 updated, 1 unchanged, got %+v", res)
+	}
+	if after, _ := ListEventClasses(db); len(after) != len(classesBefore)+1 {
+		t.Errorf("Expected one new class, got %d classes", len(after))
+	}
+
+	class := strconv.FormatInt(rulesBefore[0].EventClassID, 10)
+	rules := writeTestCSV(t, "rules.csv", "event_class_id,rule\n"+
+		class+","+rulesBefore[0].Rule+"\n"+
+		class+",Roll cage required")
+	res, err = ImportEventClassRulesFromCSVWithOptions(db, rules, ImportOptions{Upsert: true})
+	if err != nil {
+		t.Fatalf("Rule upsert failed: %v", err)
+	}
+	if res != (ImportResult{Created: 1, Unchanged: 1}) {
+		t.Errorf("Expected 1 created, 1 unchanged, got %+v", res)
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"TMCCC Series":                 "tmccc series",
+		"  TMCCC   Series! ":           "tmccc series",
+		"Pennington Bros. SuperBuck$":  "pennington bros superbuck",
+		"T&E Promotions Showdown 2026": "t e promotions showdown 2026",
+	}
+	for in, want := range tests {
+		if got := NormalizeTitle(in); got != want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestImportEventsWithSeries(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+
+	filename := writeTestCSV(t, "events.csv", "title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description,external_id,series\n"+
+		"TMCCC Race #1,"+strconv.FormatInt(trackID, 10)+",2026-03-22 09:00:00,,,,,,tmccc-1,TMCCC\n")
+	if _, err := ImportEventsFromCSVWithOptions(db, filename, ImportOptions{}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	events, _ := ListEvents(db)
+	if len(events) != 1 || events[0].Series != "TMCCC" {
+		t.Fatalf("Expected one TMCCC event, got %+v", events)
+	}
+
+	// an upsert without the series column keeps the series
+	filename = writeTestCSV(t, "events.csv", "title,track_id,start_date,end_date,driver_fee,spectator_fee,url,description,external_id\n"+
+		"TMCCC Race #1,"+strconv.FormatInt(trackID, 10)+",2026-03-22 09:00:00,,40,,,,tmccc-1\n")
+	if _, err := ImportEventsFromCSVWithOptions(db, filename, ImportOptions{Upsert: true}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	ev, _ := GetEvent(db, events[0].ID)
+	if ev.Series != "TMCCC" {
+		t.Errorf("Expected the series to be kept, got %q", ev.Series)
+	}
+
+	empty := ""
+	if err := UpdateEvent(db, ev.ID, EventUpdate{Series: &empty}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	if ev, _ = GetEvent(db, ev.ID); ev.Series != "" {
+		t.Errorf("Expected the series to be cleared, got %q", ev.Series)
+	}
+}
+
+func TestImportEventsWithStatus(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	const header = "title,track,start_date,end_date,driver_fee,spectator_fee,url,description,external_id,series,status\n"
+
+	filename := writeTestCSV(t, "events.csv", header+
+		"Spring Race,Test Track,2026-03-22 09:00:00,,,,,,,,Cancelled\n"+
+		"Summer Race,Test Track,2026-06-22 09:00:00,,,,,,,,called off\n")
+	_, err := ImportEventsFromCSV(db, filename)
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || len(importErr.Lines) != 1 || importErr.Lines[0].Line != 3 {
+		t.Fatalf("Expected the unknown status on line 3 to be rejected, got %v", err)
+	}
+
+	filename = writeTestCSV(t, "events.csv", header+
+		"Spring Race,Test Track,2026-03-22 09:00:00,,,,,,,,Cancelled\n"+
+		"Summer Race,Test Track,2026-06-22 09:00:00,,,,,,,,\n")
+	if _, err := ImportEventsFromCSV(db, filename); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	events, _ := ListEvents(db)
+	if len(events) != 2 || events[0].Status != StatusCancelled || events[1].Status != StatusScheduled {
+		t.Fatalf("Expected a cancelled and a scheduled event, got %+v", events)
+	}
+
+	// an upsert without a status keeps it; with one, it changes it
+	filename = writeTestCSV(t, "events.csv", header+
+		"Spring Race,Test Track,2026-03-22 09:00:00,,,,,,,,\n"+
+		"Summer Race,Test Track,2026-06-22 09:00:00,,,,,,,,rained_out\n")
+	res, err := ImportEventsFromCSVWithOptions(db, filename, ImportOptions{Upsert: true})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if res.Unchanged != 1 || res.Updated != 1 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	events, _ = ListEvents(db)
+	if events[0].Status != StatusCancelled || events[1].Status != StatusRainedOut {
+		t.Errorf("Expected cancelled and rained out events, got %+v", events)
+	}
+
+	// an upsert cannot undo a final status
+	filename = writeTestCSV(t, "events.csv", header+
+		"Summer Race,Test Track,2026-06-22 09:00:00,,,,,,,,scheduled\n")
+	_, err = ImportEventsFromCSVWithOptions(db, filename, ImportOptions{Upsert: true})
+	if !errors.As(err, &importErr) || len(importErr.Lines) != 1 || importErr.Lines[0].Line != 2 ||
+		!strings.Contains(importErr.Lines[0].Err.Error(), "rained out event cannot be marked scheduled") {
+		t.Fatalf("Expected the status change on line 2 to be rejected, got %v", err)
+	}
+	if ev, _ := GetEvent(db, events[1].ID); ev.Status != StatusRainedOut {
+		t.Errorf("Expected the event to stay rained out, got %q", ev.Status)
+	}
+}
+
+func TestImportEventReschedulesChecksStatuses(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	filename := writeTestCSV(t, "events.csv", "title,track,start_date,end_date,driver_fee,spectator_fee,url,description,external_id,series,status\n"+
+		"Spring Race,Test Track,2026-03-22 09:00:00,,,,,,,,postponed\n"+
+		"Spring Race,Test Track,2026-03-29 09:00:00,,,,,,,,\n"+
+		"Summer Race,Test Track,2026-06-22 09:00:00,,,,,,,,cancelled\n"+
+		"Fall Race,Test Track,2026-09-22 09:00:00,,,,,,,,rained_out\n")
+	if _, err := ImportEventsFromCSV(db, filename); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	const header = "event_title,event_start_date,track,replacement_title,replacement_start_date,replacement_track\n"
+	filename = writeTestCSV(t, "reschedules.csv", header+
+		"Summer Race,2026-06-22 09:00:00,Test Track,Spring Race,2026-03-29 09:00:00,Test Track\n"+
+		"Spring Race,2026-03-22 09:00:00,Test Track,Fall Race,2026-09-22 09:00:00,Test Track\n")
+	_, err := ImportEventReschedulesFromCSV(db, filename)
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || len(importErr.Lines) != 2 ||
+		!strings.Contains(importErr.Lines[0].Err.Error(), "is cancelled") ||
+		!strings.Contains(importErr.Lines[1].Err.Error(), "is rained out") {
+		t.Fatalf("Expected the cancelled event and the rained out replacement to be rejected, got %v", err)
+	}
+
+	filename = writeTestCSV(t, "reschedules.csv", header+
+		"Spring Race,2026-03-22 09:00:00,Test Track,Spring Race,2026-03-29 09:00:00,Test Track\n")
+	if _, err := ImportEventReschedulesFromCSV(db, filename); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	events, _ := ListEvents(db)
+	if events[0].RescheduledTo == nil || *events[0].RescheduledTo != events[1].ID {
+		t.Errorf("Expected the postponed event to point to its replacement, got %+v", events[0])
+	}
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	rowQuerier
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// EventKey identifies an event without its ID: the title, the start date and
+// the track it runs at. Track is a track ID or a track name.
+type EventKey struct {
+	Title     string
+	StartDate string
+	Track     string
+}
+
+func (k EventKey) String() string {
+	return fmt.Sprintf("event %q on %s at %s", k.Title, k.StartDate, k.Track)
+}
+
+// ResolveTrack returns the ID of the track given by ID or by name. Names are
+// matched case-insensitively.
+func ResolveTrack(q querier, track string) (int64, error) {
+	track = strings.TrimSpace(track)
+	if id, err := strconv.ParseInt(track, 10, 64); err == nil {
+		return singleID(q, fmt.Sprintf("track %d", id), `SELECT id FROM tracks WHERE id = ?`, id)
+	}
+	return singleID(q, fmt.Sprintf("track %q", track), `SELECT id FROM tracks WHERE name = ? COLLATE NOCASE`, track)
+}
+
+// ResolveEvent returns the ID of the event matching the key. The start date
+// is local time at the track. With a time it must match exactly; a date
+// alone matches any event starting that day, so "2026-03-22" finds an event
+// starting at "2026-03-22 09:00:00".
+func ResolveEvent(q querier, key EventKey) (int64, error) {
+	trackID, err := ResolveTrack(q, key.Track)
+	if err != nil {
+		return 0, err
+	}
+	loc, err := trackLocation(q, trackID)
+	if err != nil {
+		return 0, err
+	}
+	start, err := ParseLocalDate(key.StartDate, loc)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid start date: %w", key, err)
+	}
+	dateOnly := isDateOnly(key.StartDate)
+
+	rows, err := q.Query(`SELECT id, CAST(event_datetime AS TEXT) FROM events WHERE track_id = ? AND title = ? COLLATE NOCASE`,
+		trackID, strings.TrimSpace(key.Title))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var stored string
+		if err := rows.Scan(&id, &stored); err != nil {
+			return 0, err
+		}
+		t, err := parseStoredDate(stored, loc)
+		if err != nil {
+			continue
+		}
+		if t.Equal(start) || (dateOnly && sameDay(t, start)) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return oneID(key.String(), ids)
+}
+
+// ResolveEventClass returns the ID of the class with the given name within
+// an event.
+func ResolveEventClass(q querier, eventID int64, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	return singleID(q, fmt.Sprintf("class %q in event %d", name, eventID),
+		`SELECT id FROM event_classes WHERE event_id = ? AND name = ? COLLATE NOCASE`, eventID, name)
+}
+
+// singleID runs a query that should return exactly one ID; see oneID.
+func singleID(q querier, what, query string, args ...any) (int64, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return oneID(what, ids)
+}
+
+// oneID returns the only ID in ids. No IDs is reported as sql.ErrNoRows;
+// more than one as an ambiguous reference.
+func oneID(what string, ids []int64) (int64, error) {
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("%s: %w", what, sql.ErrNoRows)
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, fmt.Errorf("%s is ambiguous: matches IDs %v", what, ids)
+	}
+}
@@ -0,0 +1,447 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"dfw-dragevents/tools/db/migrate"
+)
+
+// legacyVersions are the migrations that existed before the schema_migrations
+// ledger was introduced. Databases initialized back then already have them
+// applied, so they are recorded in the ledger instead of being re-run.
+var legacyVersions = []string{"001", "002"}
+
+// dataSteps are data changes that SQL alone cannot make, keyed by
+// migration version and name. Up runs after the migration's up script and
+// Down before its down script, in the same transaction.
+var dataSteps = map[string]dataStep{
+	"004_track_timezones": {Up: eventTimesToUTC, Down: eventTimesToLocal},
+}
+
+type dataStep struct {
+	Up, Down func(tx *sql.Tx) error
+}
+
+// Migration is a single versioned migration from db/migrate.
+// It is either a plain NNN_name.sql file (forward only) or a NNN_name.up.sql /
+// NNN_name.down.sql pair. Checksum covers the up script only.
+type Migration struct {
+	Version    string
+	Name       string
+	Checksum   string
+	SQL        string
+	Down       string
+	Reversible bool
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt *time.Time
+}
+
+// Migrate applies all pending migrations embedded in the binary.
+func Migrate(db *sql.DB) error {
+	return MigrateFS(db, migrate.FS)
+}
+
+// MigrateFS applies pending migrations from fsys, each in its own transaction.
+// It refuses to run if a previously applied migration file has been modified.
+func MigrateFS(db *sql.DB, fsys fs.FS) error {
+	_, err := migrateUp(db, fsys, "")
+	return err
+}
+
+// MigrateTo moves the schema to the given version using the embedded migrations.
+func MigrateTo(db *sql.DB, version string) ([]Migration, error) {
+	return MigrateToFS(db, migrate.FS, version)
+}
+
+// MigrateToFS applies or rolls back migrations from fsys until version is the
+// newest applied migration. Version "0" rolls back every migration. It returns
+// the migrations that were applied or rolled back, in the order they ran.
+func MigrateToFS(db *sql.DB, fsys fs.FS, version string) ([]Migration, error) {
+	migrations, applied, err := loadState(db, fsys)
+	if err != nil {
+		return nil, err
+	}
+	if version != "0" && !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == version }) {
+		return nil, fmt.Errorf("unknown migration version %s", version)
+	}
+	var down []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; ok && m.Version > version {
+			down = append(down, m)
+		}
+	}
+	if len(down) > 0 {
+		return rollbackMigrations(db, down)
+	}
+	return migrateUp(db, fsys, version)
+}
+
+// Rollback reverts the most recently applied steps embedded migrations.
+func Rollback(db *sql.DB, steps int) ([]Migration, error) {
+	return RollbackFS(db, migrate.FS, steps)
+}
+
+// RollbackFS reverts the most recently applied steps migrations from fsys,
+// newest first, each in its own transaction. It fails before changing anything
+// if one of them has no down script.
+func RollbackFS(db *sql.DB, fsys fs.FS, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("rollback steps must be at least 1, got %d", steps)
+	}
+	migrations, applied, err := loadState(db, fsys)
+	if err != nil {
+		return nil, err
+	}
+	var down []Migration
+	for i := len(migrations) - 1; i >= 0 && len(down) < steps; i-- {
+		if _, ok := applied[migrations[i].Version]; ok {
+			down = append(down, migrations[i])
+		}
+	}
+	return rollbackMigrations(db, down)
+}
+
+// Status lists applied and pending embedded migrations.
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	return StatusFS(db, migrate.FS)
+}
+
+// StatusFS lists applied and pending migrations from fsys in version order.
+// It only reads: a database without the schema_migrations table, which
+// migrating would create, has every migration pending.
+func StatusFS(db *sql.DB, fsys fs.FS) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := ledgerExists(db)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[string]appliedMigration{}
+	if exists {
+		if applied, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Migration: m}
+		if a, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.AppliedAt
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// LoadMigrations reads all migration files in the root of fsys. The version is
+// the file name prefix before the first underscore (001_init.sql -> "001").
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[string]*Migration)
+	seen := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		kind := "up"
+		if b, ok := strings.CutSuffix(base, ".down"); ok {
+			base, kind = b, "down"
+		} else if b, ok := strings.CutSuffix(base, ".up"); ok {
+			base = b
+		}
+		version, name, _ := strings.Cut(base, "_")
+		key := version + "/" + kind
+		if prev, ok := seen[key]; ok {
+			return nil, fmt.Errorf("duplicate migration version %s: %s and %s", version, prev, e.Name())
+		}
+		seen[key] = e.Name()
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %s: up and down files have different names (%s, %s)", version, m.Name, name)
+		}
+		if kind == "down" {
+			m.Down = string(b)
+			m.Reversible = true
+			continue
+		}
+		sum := sha256.Sum256(b)
+		m.Checksum = hex.EncodeToString(sum[:])
+		m.SQL = string(b)
+	}
+	var out []Migration
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %s_%s has a down file but no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	// simple lexicographic order ensures sequence (001_, 002_, ...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func loadState(db *sql.DB, fsys fs.FS) ([]Migration, map[string]appliedMigration, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ensureLedger(db, migrations); err != nil {
+		return nil, nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrations, applied, nil
+}
+
+// migrateUp applies pending migrations up to and including target, or all of
+// them when target is empty.
+func migrateUp(db *sql.DB, fsys fs.FS, target string) ([]Migration, error) {
+	migrations, applied, err := loadState(db, fsys)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range migrations {
+		if target != "" && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func rollbackMigrations(db *sql.DB, down []Migration) ([]Migration, error) {
+	for _, m := range down {
+		if !m.Reversible {
+			return nil, fmt.Errorf("migration %s_%s has no down script", m.Version, m.Name)
+		}
+	}
+	var done []Migration
+	for _, m := range down {
+		if err := revertMigration(db, m); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func ledgerExists(db *sql.DB) (bool, error) {
+	var exists int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations'`).Scan(&exists)
+	return exists == 1, err
+}
+
+func ensureLedger(db *sql.DB, migrations []Migration) error {
+	exists, err := ledgerExists(db)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.Exec(`CREATE TABLE schema_migrations (
+		version TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return adoptLegacySchema(db, migrations)
+}
+
+// adoptLegacySchema records the pre-ledger migrations as applied when the
+// database already has their schema (the end_date column added by 002).
+func adoptLegacySchema(db *sql.DB, migrations []Migration) error {
+	var hasEndDate int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('events') WHERE name='end_date'`).Scan(&hasEndDate)
+	if err != nil || hasEndDate == 0 {
+		return err
+	}
+	for _, m := range migrations {
+		if !slices.Contains(legacyVersions, m.Version) {
+			continue
+		}
+		if _, err := db.Exec(`INSERT INTO schema_migrations(version, name, checksum) VALUES(?, ?, ?)`,
+			m.Version, m.Name, m.Checksum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appliedMigrations(db *sql.DB) (map[string]appliedMigration, error) {
+	rows, err := db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]appliedMigration)
+	for rows.Next() {
+		var version string
+		var a appliedMigration
+		var appliedAt sql.NullString
+		if err := rows.Scan(&version, &a.Checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		if appliedAt.Valid {
+			if ts, err := time.Parse("2006-01-02 15:04:05", appliedAt.String); err == nil {
+				a.AppliedAt = &ts
+			} else if ts, err := time.Parse(time.RFC3339, appliedAt.String); err == nil {
+				a.AppliedAt = &ts
+			}
+		}
+		out[version] = a
+	}
+	return out, rows.Err()
+}
+
+func verifyChecksums(migrations []Migration, applied map[string]appliedMigration) error {
+	for _, m := range migrations {
+		a, ok := applied[m.Version]
+		if !ok {
+			continue
+		}
+		if a.Checksum != m.Checksum {
+			return fmt.Errorf("migration %s_%s has changed since it was applied (checksum %s, recorded %s)",
+				m.Version, m.Name, m.Checksum[:12], a.Checksum[:12])
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, m Migration) error {
+	return runMigrationStep(db, func(tx *sql.Tx) error {
+		if err := execScript(tx, m.SQL); err != nil {
+			return fmt.Errorf("migrate %s_%s: %w", m.Version, m.Name, err)
+		}
+		if step, ok := dataSteps[m.Version+"_"+m.Name]; ok && step.Up != nil {
+			if err := step.Up(tx); err != nil {
+				return fmt.Errorf("migrate %s_%s: %w", m.Version, m.Name, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES(?, ?, ?, ?)`,
+			m.Version, m.Name, m.Checksum, time.Now().UTC().Format("2006-01-02 15:04:05")); err != nil {
+			return fmt.Errorf("record migration %s_%s: %w", m.Version, m.Name, err)
+		}
+		return nil
+	})
+}
+
+func revertMigration(db *sql.DB, m Migration) error {
+	return runMigrationStep(db, func(tx *sql.Tx) error {
+		if step, ok := dataSteps[m.Version+"_"+m.Name]; ok && step.Down != nil {
+			if err := step.Down(tx); err != nil {
+				return fmt.Errorf("rollback %s_%s: %w", m.Version, m.Name, err)
+			}
+		}
+		if err := execScript(tx, m.Down); err != nil {
+			return fmt.Errorf("rollback %s_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version); err != nil {
+			return fmt.Errorf("unrecord migration %s_%s: %w", m.Version, m.Name, err)
+		}
+		return nil
+	})
+}
+
+// runMigrationStep runs fn in a transaction on a single connection with
+// foreign key enforcement switched off, as SQLite requires for table rebuilds
+// (otherwise dropping the old table would cascade into its children). Foreign
+// keys are checked before commit and the previous setting is restored.
+func runMigrationStep(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var fkEnabled int
+	if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&fkEnabled); err != nil {
+		return err
+	}
+	if fkEnabled == 1 {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys=OFF`); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys=ON`)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if fkEnabled == 1 {
+		if err := checkForeignKeys(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation: %s row %d references missing %s", table, rowid.Int64, parent)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,392 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"dfw-dragevents/tools/db/migrate"
+
+	_ "modernc.org/sqlite"
+)
+
+func openEmptyTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "empty.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	return db
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"001_init.sql":      {Data: []byte(`CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`)},
+		"002_add_color.sql": {Data: []byte(`ALTER TABLE widgets ADD COLUMN color TEXT;`)},
+		"README.md":         {Data: []byte(`not a migration`)},
+	}
+}
+
+func TestMigrateRepositoryMigrationsTwice(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("First migrate failed: %v", err)
+	}
+	// 002 uses ALTER TABLE ADD COLUMN, so a second run only succeeds if it is skipped
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Second migrate failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatalf("Failed to count ledger rows: %v", err)
+	}
+	migrations, err := LoadMigrations(migrate.FS)
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+	if count != len(migrations) {
+		t.Errorf("Expected %d ledger rows, got %d", len(migrations), count)
+	}
+}
+
+func TestMigrateFSAppliesOnlyPending(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	fsys := testMigrations()
+	delete(fsys, "002_add_color.sql")
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS failed: %v", err)
+	}
+
+	fsys = testMigrations()
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS with new migration failed: %v", err)
+	}
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('widgets') WHERE name='color'").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to check color column: %v", err)
+	}
+	if count != 1 {
+		t.Error("Expected color column to be added by pending migration")
+	}
+}
+
+func TestMigrateFSChecksumMismatch(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	fsys := testMigrations()
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS failed: %v", err)
+	}
+
+	fsys["001_init.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE widgets (id INTEGER PRIMARY KEY);`)}
+	err := MigrateFS(db, fsys)
+	if err == nil {
+		t.Fatal("Expected error for modified migration")
+	}
+	if !strings.Contains(err.Error(), "001_init") {
+		t.Errorf("Expected error to name the modified migration, got %v", err)
+	}
+}
+
+func TestMigrateFSRollsBackFailedMigration(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	fsys := testMigrations()
+	fsys["003_broken.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE gadgets (id INTEGER PRIMARY KEY);
+INSERT INTO no_such_table VALUES (1);`)}
+
+	if err := MigrateFS(db, fsys); err == nil {
+		t.Fatal("Expected error for broken migration")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='gadgets'").Scan(&count); err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	if count != 0 {
+		t.Error("Expected gadgets table to be rolled back")
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = '003'").Scan(&count); err != nil {
+		t.Fatalf("Failed to query ledger: %v", err)
+	}
+	if count != 0 {
+		t.Error("Expected failed migration not to be recorded")
+	}
+}
+
+func TestMigrateFSAdoptsLegacySchema(t *testing.T) {
+	db := setupLegacyTestDB(t)
+	defer db.Close()
+
+	fsys := migrate.FS
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS on legacy database failed: %v", err)
+	}
+
+	statuses, err := StatusFS(db, fsys)
+	if err != nil {
+		t.Fatalf("StatusFS failed: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			t.Errorf("Expected migration %s to be recorded as applied", s.Version)
+		}
+	}
+}
+
+func TestStatusFS(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	fsys := testMigrations()
+	delete(fsys, "002_add_color.sql")
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS failed: %v", err)
+	}
+
+	statuses, err := StatusFS(db, testMigrations())
+	if err != nil {
+		t.Fatalf("StatusFS failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(statuses))
+	}
+	if !statuses[0].Applied || statuses[0].Version != "001" || statuses[0].Name != "init" {
+		t.Errorf("Expected 001_init applied, got %+v", statuses[0])
+	}
+	if statuses[0].AppliedAt == nil {
+		t.Error("Expected applied_at for applied migration")
+	}
+	if statuses[1].Applied || statuses[1].Version != "002" {
+		t.Errorf("Expected 002 pending, got %+v", statuses[1])
+	}
+}
+
+func TestStatusFSDoesNotCreateLedger(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	statuses, err := StatusFS(db, testMigrations())
+	if err != nil {
+		t.Fatalf("StatusFS failed: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("Expected 2 pending migrations, got %+v", statuses)
+	}
+	if exists, _ := ledgerExists(db); exists {
+		t.Error("Expected StatusFS not to create schema_migrations")
+	}
+}
+
+func TestLoadMigrationsDuplicateVersion(t *testing.T) {
+	fsys := testMigrations()
+	fsys["002_other.sql"] = &fstest.MapFile{Data: []byte(`SELECT 1;`)}
+
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Error("Expected error for duplicate migration version")
+	}
+}
+
+func TestRollbackFSRepositoryMigrations(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+	// a single connection keeps the foreign_keys pragma in effect for the test
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA foreign_keys=ON"); err != nil {
+		t.Fatalf("Failed to enable foreign keys: %v", err)
+	}
+
+	fsys := migrate.FS
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS failed: %v", err)
+	}
+
+	trackID, err := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	if err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	eventID, err := CreateEvent(db, "Test Event", trackID, "2025-12-01 10:00:00", "2025-12-01 18:00:00", nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO event_classes(event_id, name) VALUES(?, ?)", eventID, "Test Class"); err != nil {
+		t.Fatalf("Failed to insert class: %v", err)
+	}
+
+	reverted, err := MigrateToFS(db, fsys, "001")
+	if err != nil {
+		t.Fatalf("MigrateToFS 001 failed: %v", err)
+	}
+	if len(reverted) == 0 || reverted[len(reverted)-1].Version != "002" {
+		t.Fatalf("Expected 002 to be rolled back last, got %+v", reverted)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('events') WHERE name='end_date'").Scan(&count); err != nil {
+		t.Fatalf("Failed to check end_date column: %v", err)
+	}
+	if count != 0 {
+		t.Error("Expected end_date column to be dropped")
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM events").Scan(&count); err != nil {
+		t.Fatalf("Failed to count events: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected event to survive the table rebuild, got %d events", count)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM event_classes WHERE event_id = ?", eventID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count classes: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected class to survive the table rebuild, got %d classes", count)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND name='idx_events_track_id'").Scan(&count); err != nil {
+		t.Fatalf("Failed to check indexes: %v", err)
+	}
+	if count != 1 {
+		t.Error("Expected idx_events_track_id to be recreated")
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM pragma_foreign_key_list('events')").Scan(&count); err != nil {
+		t.Fatalf("Failed to check foreign keys: %v", err)
+	}
+	if count != 1 {
+		t.Error("Expected events foreign key to be preserved")
+	}
+
+	// migrating forward again re-adds the column
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS after rollback failed: %v", err)
+	}
+	if _, err := MigrateToFS(db, fsys, "0"); err != nil {
+		t.Fatalf("Rolling back all migrations failed: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='events'").Scan(&count); err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	if count != 0 {
+		t.Error("Expected events table to be dropped")
+	}
+}
+
+func TestRollbackKeepsTriggers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "", "", "")
+	eventID, _ := CreateEvent(db, "Test Event", trackID, "2025-12-01 10:00:00", "", nil, nil, "", "")
+	// 008 drops a column from events, which rebuilds the table that 007's
+	// triggers are on and refer to
+	if _, err := MigrateTo(db, "007"); err != nil {
+		t.Fatalf("MigrateTo 007 failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='trigger'").Scan(&count); err != nil {
+		t.Fatalf("Failed to count triggers: %v", err)
+	}
+	if count != 8 {
+		t.Errorf("Expected 007's 8 triggers to survive the rebuild, got %d", count)
+	}
+	if _, err := db.Exec("UPDATE events SET title = 'Renamed' WHERE id = ?", eventID); err != nil {
+		t.Fatalf("Failed to rename event: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM event_changes WHERE event_id = ? AND summary = 'renamed'", eventID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count changes: %v", err)
+	}
+	if count != 1 {
+		t.Error("Expected the rename to be logged after the rebuild")
+	}
+}
+
+func TestRollbackFSIrreversibleMigration(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	fsys := testMigrations()
+	if err := MigrateFS(db, fsys); err != nil {
+		t.Fatalf("MigrateFS failed: %v", err)
+	}
+
+	if _, err := RollbackFS(db, fsys, 1); err == nil {
+		t.Fatal("Expected error rolling back migration without down script")
+	}
+
+	statuses, err := StatusFS(db, fsys)
+	if err != nil {
+		t.Fatalf("StatusFS failed: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			t.Errorf("Expected migration %s to remain applied", s.Version)
+		}
+	}
+}
+
+func TestMigrateToFS(t *testing.T) {
+	db := openEmptyTestDB(t)
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"001_init.up.sql":        {Data: []byte(`CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`)},
+		"001_init.down.sql":      {Data: []byte(`DROP TABLE widgets;`)},
+		"002_add_color.up.sql":   {Data: []byte(`ALTER TABLE widgets ADD COLUMN color TEXT;`)},
+		"002_add_color.down.sql": {Data: []byte(`ALTER TABLE widgets DROP COLUMN color;`)},
+	}
+
+	ran, err := MigrateToFS(db, fsys, "001")
+	if err != nil {
+		t.Fatalf("MigrateToFS 001 failed: %v", err)
+	}
+	if len(ran) != 1 || ran[0].Version != "001" {
+		t.Errorf("Expected only 001 to run, got %+v", ran)
+	}
+
+	if _, err := MigrateToFS(db, fsys, "002"); err != nil {
+		t.Fatalf("MigrateToFS 002 failed: %v", err)
+	}
+
+	ran, err = MigrateToFS(db, fsys, "0")
+	if err != nil {
+		t.Fatalf("MigrateToFS 0 failed: %v", err)
+	}
+	if len(ran) != 2 || ran[0].Version != "002" || ran[1].Version != "001" {
+		t.Errorf("Expected 002 then 001 to be rolled back, got %+v", ran)
+	}
+
+	if _, err := MigrateToFS(db, fsys, "999"); err == nil {
+		t.Error("Expected error for unknown version")
+	}
+}
+
+func TestLoadMigrationsDownWithoutUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_init.down.sql": {Data: []byte(`DROP TABLE widgets;`)},
+	}
+
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Error("Expected error for down migration without up migration")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- comment; with semicolon
+INSERT INTO t VALUES ('a;b');
+/* block; comment */ DELETE FROM t;`
+
+	stmts := splitStatements(script)
+	if len(stmts) != 2 {
+		t.Fatalf("Expected 2 statements, got %d: %q", len(stmts), stmts)
+	}
+	if stmts[0] != "INSERT INTO t VALUES ('a;b')" {
+		t.Errorf("Unexpected first statement: %q", stmts[0])
+	}
+}
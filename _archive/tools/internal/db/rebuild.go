@@ -0,0 +1,329 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// alterDropRE detects scripts that may contain an ALTER TABLE ... DROP.
+var alterDropRE = regexp.MustCompile(`(?is)\bALTER\s+TABLE\b[^;]*\bDROP\b`)
+
+// dropColumnRE matches "ALTER TABLE <table> DROP [COLUMN] <column>".
+var dropColumnRE = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+["\x60\[]?(\w+)["\x60\]]?\s+DROP\s+(?:COLUMN\s+)?["\x60\[]?(\w+)["\x60\]]?$`)
+
+// execScript runs a migration script inside tx. SQLite's native DROP COLUMN
+// refuses to drop indexed, foreign key or primary key columns, so any
+// "ALTER TABLE ... DROP COLUMN ..." statement is executed as a table rebuild
+// instead. Scripts without such a statement are passed through unchanged.
+func execScript(tx *sql.Tx, script string) error {
+	if !alterDropRE.MatchString(script) {
+		_, err := tx.Exec(script)
+		return err
+	}
+	for _, stmt := range splitStatements(script) {
+		if m := dropColumnRE.FindStringSubmatch(stmt); m != nil {
+			if err := rebuildTableWithout(tx, m[1], m[2]); err != nil {
+				return fmt.Errorf("drop column %s.%s: %w", m[1], m[2], err)
+			}
+			continue
+		}
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on top-level semicolons, skipping comments
+// and quoted text. It does not understand CREATE TRIGGER bodies, so scripts
+// that drop columns should keep triggers in a separate migration.
+func splitStatements(script string) []string {
+	var out []string
+	var cur strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			out = append(out, s)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			cur.WriteByte('\n')
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+			cur.WriteByte(' ')
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(script[i+1:], c)
+			if end < 0 {
+				cur.WriteString(script[i:])
+				i = len(script)
+			} else {
+				cur.WriteString(script[i : i+end+2])
+				i += end + 1
+			}
+		case c == ';':
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return out
+}
+
+type columnInfo struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default sql.NullString
+	PK      int
+}
+
+type foreignKeyInfo struct {
+	ID       int
+	Table    string
+	From     []string
+	To       []string
+	OnUpdate string
+	OnDelete string
+}
+
+// rebuildTableWithout drops column from table using SQLite's documented
+// rebuild procedure: create a copy of the table without the column, copy the
+// rows, drop the original, rename the copy and recreate the indexes that do
+// not reference the dropped column. Columns, NOT NULL, defaults, primary keys,
+// AUTOINCREMENT and foreign keys are preserved; CHECK constraints are not.
+// Triggers are dropped for the rebuild, since SQLite rejects the rename while
+// a trigger refers to the missing table, and recreated afterwards; one that
+// uses the dropped column fails to recreate and so fails the rebuild.
+func rebuildTableWithout(tx *sql.Tx, table, column string) error {
+	cols, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		return fmt.Errorf("no such table: %s", table)
+	}
+	idx := slices.IndexFunc(cols, func(c columnInfo) bool { return strings.EqualFold(c.Name, column) })
+	if idx < 0 {
+		return fmt.Errorf("no such column: %s", column)
+	}
+	if cols[idx].PK > 0 {
+		return fmt.Errorf("cannot drop primary key column %s", column)
+	}
+	cols = slices.Delete(cols, idx, idx+1)
+
+	fks, err := tableForeignKeys(tx, table)
+	if err != nil {
+		return err
+	}
+	indexes, err := tableIndexes(tx, table, column)
+	if err != nil {
+		return err
+	}
+	triggers, err := schemaTriggers(tx)
+	if err != nil {
+		return err
+	}
+	var createSQL string
+	if err := tx.QueryRow(`SELECT sql FROM sqlite_master WHERE type='table' AND name=?`, table).Scan(&createSQL); err != nil {
+		return err
+	}
+	autoincrement := strings.Contains(strings.ToUpper(createSQL), "AUTOINCREMENT")
+
+	tmp := table + "__rebuild"
+	var defs, names []string
+	var pkCols []string
+	for _, c := range cols {
+		if c.PK > 0 {
+			pkCols = append(pkCols, quoteIdent(c.Name))
+		}
+	}
+	for _, c := range cols {
+		def := quoteIdent(c.Name)
+		if c.Type != "" {
+			def += " " + c.Type
+		}
+		if c.PK > 0 && len(pkCols) == 1 {
+			def += " PRIMARY KEY"
+			if autoincrement {
+				def += " AUTOINCREMENT"
+			}
+		}
+		if c.NotNull {
+			def += " NOT NULL"
+		}
+		if c.Default.Valid {
+			def += " DEFAULT " + c.Default.String
+		}
+		defs = append(defs, def)
+		names = append(names, quoteIdent(c.Name))
+	}
+	if len(pkCols) > 1 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(pkCols, ", ")+")")
+	}
+	for _, fk := range fks {
+		if slices.ContainsFunc(fk.From, func(f string) bool { return strings.EqualFold(f, column) }) {
+			continue
+		}
+		def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", joinIdents(fk.From), quoteIdent(fk.Table))
+		if len(fk.To) > 0 {
+			def += "(" + joinIdents(fk.To) + ")"
+		}
+		if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+			def += " ON UPDATE " + fk.OnUpdate
+		}
+		if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+			def += " ON DELETE " + fk.OnDelete
+		}
+		defs = append(defs, def)
+	}
+
+	var stmts []string
+	for _, tr := range triggers {
+		stmts = append(stmts, "DROP TRIGGER "+quoteIdent(tr.name))
+	}
+	stmts = append(stmts,
+		fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", quoteIdent(tmp), strings.Join(defs, ",\n  ")),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			quoteIdent(tmp), strings.Join(names, ", "), strings.Join(names, ", "), quoteIdent(table)),
+		fmt.Sprintf("DROP TABLE %s", quoteIdent(table)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(tmp), quoteIdent(table)),
+	)
+	stmts = append(stmts, indexes...)
+	for _, s := range stmts {
+		if _, err := tx.Exec(s); err != nil {
+			return err
+		}
+	}
+	for _, tr := range triggers {
+		if _, err := tx.Exec(tr.sql); err != nil {
+			return fmt.Errorf("recreate trigger %s: %w", tr.name, err)
+		}
+	}
+	return nil
+}
+
+type triggerInfo struct{ name, sql string }
+
+// schemaTriggers returns every trigger in the database, in creation order.
+func schemaTriggers(tx *sql.Tx) ([]triggerInfo, error) {
+	rows, err := tx.Query(`SELECT name, sql FROM sqlite_master WHERE type='trigger' ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []triggerInfo
+	for rows.Next() {
+		var tr triggerInfo
+		if err := rows.Scan(&tr.name, &tr.sql); err != nil {
+			return nil, err
+		}
+		out = append(out, tr)
+	}
+	return out, rows.Err()
+}
+
+func tableColumns(tx *sql.Tx, table string) ([]columnInfo, error) {
+	rows, err := tx.Query(`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []columnInfo
+	for rows.Next() {
+		var c columnInfo
+		if err := rows.Scan(&c.Name, &c.Type, &c.NotNull, &c.Default, &c.PK); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func tableForeignKeys(tx *sql.Tx, table string) ([]foreignKeyInfo, error) {
+	rows, err := tx.Query(`SELECT id, "table", "from", "to", on_update, on_delete FROM pragma_foreign_key_list(?) ORDER BY id, seq`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []foreignKeyInfo
+	for rows.Next() {
+		var id int
+		var parent, from, onUpdate, onDelete string
+		var to sql.NullString
+		if err := rows.Scan(&id, &parent, &from, &to, &onUpdate, &onDelete); err != nil {
+			return nil, err
+		}
+		if len(out) == 0 || out[len(out)-1].ID != id {
+			out = append(out, foreignKeyInfo{ID: id, Table: parent, OnUpdate: onUpdate, OnDelete: onDelete})
+		}
+		fk := &out[len(out)-1]
+		fk.From = append(fk.From, from)
+		if to.Valid {
+			fk.To = append(fk.To, to.String)
+		}
+	}
+	return out, rows.Err()
+}
+
+// tableIndexes returns the CREATE INDEX statements for explicit indexes on
+// table that do not reference column.
+func tableIndexes(tx *sql.Tx, table, column string) ([]string, error) {
+	rows, err := tx.Query(`SELECT name, sql FROM sqlite_master WHERE type='index' AND tbl_name=? AND sql IS NOT NULL`, table)
+	if err != nil {
+		return nil, err
+	}
+	type index struct{ name, sql string }
+	var all []index
+	for rows.Next() {
+		var ix index
+		if err := rows.Scan(&ix.name, &ix.sql); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		all = append(all, ix)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, ix := range all {
+		var uses int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_index_info(?) WHERE name = ? COLLATE NOCASE`, ix.name, column).Scan(&uses)
+		if err != nil {
+			return nil, err
+		}
+		if uses == 0 {
+			out = append(out, ix.sql)
+		}
+	}
+	return out, nil
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func joinIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quoteIdent(n)
+	}
+	return strings.Join(quoted, ", ")
+}
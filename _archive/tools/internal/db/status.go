@@ -0,0 +1,212 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Event statuses, stored in events.status.
+const (
+	StatusScheduled = "scheduled"
+	StatusPostponed = "postponed"  // put off, with or without a new date
+	StatusCancelled = "cancelled"  // called off before it ran
+	StatusRainedOut = "rained_out" // called off on the day for weather
+)
+
+// statusTransitions lists the statuses each status may change to. A
+// postponed event goes back on the schedule or is called off; a cancelled
+// one can be reinstated; a rained out event is final and can only be
+// rescheduled to a replacement event.
+var statusTransitions = map[string][]string{
+	StatusScheduled: {StatusPostponed, StatusCancelled, StatusRainedOut},
+	StatusPostponed: {StatusScheduled, StatusCancelled, StatusRainedOut},
+	StatusCancelled: {StatusScheduled},
+	StatusRainedOut: nil,
+}
+
+// ValidStatus reports whether status is one of the event statuses.
+func ValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// CheckStatusTransition returns an error unless an event may change from
+// status from to status to.
+func CheckStatusTransition(from, to string) error {
+	if !ValidStatus(to) {
+		return fmt.Errorf("unknown status %q", to)
+	}
+	if from == to {
+		return fmt.Errorf("already %s", StatusLabel(to))
+	}
+	if !slices.Contains(statusTransitions[from], to) {
+		return fmt.Errorf("a %s event cannot be marked %s", StatusLabel(from), StatusLabel(to))
+	}
+	return nil
+}
+
+// StatusLabel is status as words, e.g. "rained out".
+func StatusLabel(status string) string {
+	return strings.ReplaceAll(status, "_", " ")
+}
+
+// SetEventStatus changes an event's status if CheckStatusTransition allows
+// it. An event put back on the schedule loses its link to a replacement.
+// Like UpdateEvent, this marks the event as edited here.
+func SetEventStatus(db *sql.DB, id int64, status string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow(`SELECT status FROM events WHERE id = ?`, id).Scan(&current); err != nil {
+		return fmt.Errorf("event %d: %w", id, err)
+	}
+	if err := CheckStatusTransition(current, status); err != nil {
+		return fmt.Errorf("event %d: %w", id, err)
+	}
+	q := `UPDATE events SET status = ?`
+	if status == StatusScheduled {
+		q += `, rescheduled_to = NULL`
+	}
+	if _, err := tx.Exec(q+` WHERE id = ?`, status, id); err != nil {
+		return err
+	}
+	if err := markEventsLocal(tx, []string{LocalStatus}, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RescheduleEvent links event id to replacementID, the event that takes its
+// place. A scheduled event becomes postponed; a cancelled one cannot be
+// rescheduled. The replacement must be a different, scheduled event.
+func RescheduleEvent(db *sql.DB, id, replacementID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := linkReplacement(tx, id, replacementID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RescheduleEventTo copies event id, with its classes and rules, to the new
+// start and end dates (local time at the track, as CreateEvent takes them)
+// and links the event to the copy as RescheduleEvent does. It returns the
+// copy's ID.
+func RescheduleEventTo(db *sql.DB, id int64, startDate, endDate string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	f := eventFields{StartDate: startDate, EndDate: endDate}
+	var driverFee, spectatorFee sql.NullFloat64
+	err = tx.QueryRow(`SELECT title, track_id, event_driver_fee, event_spectator_fee, COALESCE(url, ''), COALESCE(description, ''), COALESCE(series, '')
+		FROM events WHERE id = ?`, id).
+		Scan(&f.Title, &f.TrackID, &driverFee, &spectatorFee, &f.URL, &f.Description, &f.Series)
+	if err != nil {
+		return 0, fmt.Errorf("event %d: %w", id, err)
+	}
+	f.DriverFee = feePtr(driverFee)
+	f.SpectatorFee = feePtr(spectatorFee)
+	newID, err := insertEvent(tx, f)
+	if err != nil {
+		return 0, err
+	}
+	if err := copyEventClasses(tx, id, newID); err != nil {
+		return 0, err
+	}
+	if err := linkReplacement(tx, id, newID); err != nil {
+		return 0, err
+	}
+	return newID, tx.Commit()
+}
+
+func linkReplacement(tx *sql.Tx, id, replacementID int64) error {
+	status, err := checkReplacement(tx, id, replacementID)
+	if err != nil {
+		return err
+	}
+	if status == StatusScheduled {
+		status = StatusPostponed
+	}
+	if _, err := tx.Exec(`UPDATE events SET status = ?, rescheduled_to = ? WHERE id = ?`, status, replacementID, id); err != nil {
+		return err
+	}
+	return markEventsLocal(tx, []string{LocalStatus}, id)
+}
+
+// checkReplacement checks that replacementID can take the place of event id,
+// and returns the status of event id: the replacement must be a different,
+// scheduled event, and event id must not be cancelled.
+func checkReplacement(tx *sql.Tx, id, replacementID int64) (string, error) {
+	if id == replacementID {
+		return "", fmt.Errorf("event %d cannot replace itself", id)
+	}
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM events WHERE id = ?`, id).Scan(&status); err != nil {
+		return "", fmt.Errorf("event %d: %w", id, err)
+	}
+	var replacementStatus string
+	if err := tx.QueryRow(`SELECT status FROM events WHERE id = ?`, replacementID).Scan(&replacementStatus); err != nil {
+		return "", fmt.Errorf("replacement event %d: %w", replacementID, err)
+	}
+	if replacementStatus != StatusScheduled {
+		return "", fmt.Errorf("replacement event %d is %s", replacementID, StatusLabel(replacementStatus))
+	}
+	if status == StatusCancelled {
+		return "", fmt.Errorf("event %d is cancelled; reinstate it first", id)
+	}
+	return status, nil
+}
+
+// copyEventClasses copies the classes and rules of event from to event to.
+func copyEventClasses(tx *sql.Tx, from, to int64) error {
+	rows, err := tx.Query(`SELECT id, name, buyin_fee FROM event_classes WHERE event_id = ? ORDER BY id`, from)
+	if err != nil {
+		return err
+	}
+	type class struct {
+		id   int64
+		name string
+		fee  sql.NullFloat64
+	}
+	var classes []class
+	for rows.Next() {
+		var c class
+		if err := rows.Scan(&c.id, &c.name, &c.fee); err != nil {
+			rows.Close()
+			return err
+		}
+		classes = append(classes, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range classes {
+		r, err := tx.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, ?, ?)`, to, c.name, c.fee)
+		if err != nil {
+			return fmt.Errorf("copy class %q: %w", c.name, err)
+		}
+		classID, err := r.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO event_class_rules(event_class_id, rule)
+			SELECT ?, rule FROM event_class_rules WHERE event_class_id = ? ORDER BY id`, classID, c.id); err != nil {
+			return fmt.Errorf("copy rules of class %q: %w", c.name, err)
+		}
+	}
+	return nil
+}
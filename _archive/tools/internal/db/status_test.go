@@ -0,0 +1,122 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSetEventStatus(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Xtreme Raceway Park", "Ferris", "", "")
+	eventID, _ := CreateEvent(db, "Friday Night Drags", trackID, "2026-04-17 18:00:00", "", nil, nil, "", "")
+	if ev, _ := GetEvent(db, eventID); ev.Status != StatusScheduled {
+		t.Fatalf("Expected new events to be scheduled, got %q", ev.Status)
+	}
+
+	steps := []struct {
+		status string
+		ok     bool
+	}{
+		{StatusPostponed, true},
+		{StatusPostponed, false}, // already postponed
+		{StatusCancelled, true},
+		{StatusRainedOut, false}, // a cancelled event did not run
+		{StatusScheduled, true},  // reinstated
+		{StatusRainedOut, true},
+		{StatusScheduled, false}, // rained out is final
+		{"called_off", false},
+	}
+	for _, s := range steps {
+		err := SetEventStatus(db, eventID, s.status)
+		if (err == nil) != s.ok {
+			t.Fatalf("SetEventStatus(%s): expected ok=%v, got %v", s.status, s.ok, err)
+		}
+	}
+	if ev, _ := GetEvent(db, eventID); ev.Status != StatusRainedOut {
+		t.Errorf("Expected the event to be rained out, got %q", ev.Status)
+	}
+
+	changes, err := ListEventChanges(db, 0)
+	if err != nil {
+		t.Fatalf("ListEventChanges failed: %v", err)
+	}
+	logged := make(map[string]bool)
+	for _, c := range changes {
+		for _, s := range c.Summaries {
+			logged[s] = true
+		}
+	}
+	for _, want := range []string{"postponed", "cancelled", "back on schedule", "rained out"} {
+		if !logged[want] {
+			t.Errorf("Expected %q to be logged, got %+v", want, changes)
+		}
+	}
+
+	if err := SetEventStatus(db, 99999, StatusCancelled); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing event, got %v", err)
+	}
+}
+
+func TestRescheduleEvent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Thunder Valley Raceway Park", "Lexington", "", "")
+	fee := 40.0
+	eventID, _ := CreateEvent(db, "TMCCC Race #2", trackID, "2026-04-12 08:00:00", "2026-04-12 23:59:59", &fee, nil, "", "1/4 Mile")
+	r, err := db.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, 'Stock Muscle', 40)`, eventID)
+	if err != nil {
+		t.Fatalf("Failed to add class: %v", err)
+	}
+	classID, _ := r.LastInsertId()
+	if _, err := db.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES(?, '9.40 & slower')`, classID); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	newID, err := RescheduleEventTo(db, eventID, "2026-04-26 08:00:00", "2026-04-26 23:59:59")
+	if err != nil {
+		t.Fatalf("RescheduleEventTo failed: %v", err)
+	}
+	ev, _ := GetEvent(db, eventID)
+	if ev.Status != StatusPostponed || ev.RescheduledTo == nil || *ev.RescheduledTo != newID {
+		t.Errorf("Expected the event to be postponed to %d, got %+v", newID, ev)
+	}
+	copied, _ := GetEvent(db, newID)
+	if copied.Title != ev.Title || copied.StartLocal != "2026-04-26T08:00:00" || copied.DriverFee == nil || *copied.DriverFee != 40 ||
+		copied.Status != StatusScheduled || copied.UUID == ev.UUID {
+		t.Errorf("Unexpected replacement: %+v", copied)
+	}
+	classes, _ := ListEventClasses(db)
+	rules, _ := ListEventClassRules(db)
+	if len(classes) != 2 || classes[1].EventID != newID || len(rules) != 2 {
+		t.Errorf("Expected the classes and rules to be copied, got %+v and %+v", classes, rules)
+	}
+
+	// deleting the replacement clears the link
+	if err := DeleteEvent(db, newID); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	if ev, _ := GetEvent(db, eventID); ev.RescheduledTo != nil {
+		t.Errorf("Expected the link to be cleared, got %d", *ev.RescheduledTo)
+	}
+
+	otherID, _ := CreateEvent(db, "TMCCC Race #2 (new date)", trackID, "2026-05-03 08:00:00", "", nil, nil, "", "")
+	if err := RescheduleEvent(db, eventID, eventID); err == nil {
+		t.Error("Expected error rescheduling an event to itself")
+	}
+	if err := RescheduleEvent(db, eventID, 99999); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing replacement, got %v", err)
+	}
+	if err := SetEventStatus(db, otherID, StatusCancelled); err != nil {
+		t.Fatalf("SetEventStatus failed: %v", err)
+	}
+	if err := RescheduleEvent(db, eventID, otherID); err == nil {
+		t.Error("Expected error rescheduling to a cancelled event")
+	}
+	if err := RescheduleEvent(db, otherID, eventID); err == nil {
+		t.Error("Expected error rescheduling a cancelled event")
+	}
+}
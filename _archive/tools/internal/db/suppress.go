@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Suppression hides an aggregator event from the merged events.json.
+type Suppression struct {
+	UUID      string // the aggregator's id for the event
+	Reason    string
+	CreatedAt string // UTC, RFC 3339
+}
+
+// SuppressEvent hides the aggregator event with the given id from the
+// merged events.json. Suppressing an event again replaces the reason.
+func SuppressEvent(db *sql.DB, uuid, reason string) error {
+	uuid = strings.ToLower(strings.TrimSpace(uuid))
+	if uuid == "" {
+		return fmt.Errorf("event id is required")
+	}
+	_, err := db.Exec(`INSERT INTO suppressed_events(uuid, reason) VALUES(?, ?)
+		ON CONFLICT(uuid) DO UPDATE SET reason = excluded.reason`, uuid, strings.TrimSpace(reason))
+	return err
+}
+
+// UnsuppressEvent lets the aggregator event with the given id back into the
+// merged events.json.
+func UnsuppressEvent(db *sql.DB, uuid string) error {
+	uuid = strings.ToLower(strings.TrimSpace(uuid))
+	res, err := db.Exec(`DELETE FROM suppressed_events WHERE uuid = ?`, uuid)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("suppressed event %s: %w", uuid, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// ListSuppressed returns every suppression, oldest first.
+func ListSuppressed(db *sql.DB) ([]Suppression, error) {
+	rows, err := db.Query(`SELECT uuid, reason, CAST(created_at AS TEXT) FROM suppressed_events ORDER BY created_at, uuid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Suppression
+	for rows.Next() {
+		var s Suppression
+		var createdAt string
+		if err := rows.Scan(&s.UUID, &s.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		if s.CreatedAt, err = formatStoredTimestamp(createdAt); err != nil {
+			return nil, fmt.Errorf("suppressed event %s: created_at: %w", s.UUID, err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSuppressEvent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	id := "57BC97B9-F865-436D-9D4F-190B56C92A25"
+	if err := SuppressEvent(db, id, "misread flyer"); err != nil {
+		t.Fatalf("SuppressEvent failed: %v", err)
+	}
+	if err := SuppressEvent(db, id, "duplicate of the TMCCC listing"); err != nil {
+		t.Fatalf("Suppressing again failed: %v", err)
+	}
+	list, err := ListSuppressed(db)
+	if err != nil {
+		t.Fatalf("ListSuppressed failed: %v", err)
+	}
+	if len(list) != 1 || list[0].UUID != "57bc97b9-f865-436d-9d4f-190b56c92a25" || list[0].Reason != "duplicate of the TMCCC listing" {
+		t.Errorf("Unexpected suppressions: %+v", list)
+	}
+
+	if err := UnsuppressEvent(db, id); err != nil {
+		t.Fatalf("UnsuppressEvent failed: %v", err)
+	}
+	if err := UnsuppressEvent(db, id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+	if err := SuppressEvent(db, " ", ""); err == nil {
+		t.Error("Expected an empty id to be rejected")
+	}
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	// zone data is compiled in so LoadLocation works on machines without it,
+	// such as Windows
+	_ "time/tzdata"
+)
+
+// DefaultTimeZone is the time zone of tracks that do not set one. Every DFW
+// track is in it.
+const DefaultTimeZone = "America/Chicago"
+
+// localLayout formats the local wall-clock time of an event for export,
+// without an offset.
+const localLayout = "2006-01-02T15:04:05"
+
+// LoadTimeZone loads an IANA time zone such as "America/Chicago". An empty
+// name means DefaultTimeZone.
+func LoadTimeZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = DefaultTimeZone
+	}
+	// "Local" is whatever zone the machine running the CLI is in
+	if name == "Local" {
+		return nil, fmt.Errorf("invalid time zone %q: use an IANA name such as %s", name, DefaultTimeZone)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: use an IANA name such as %s", name, DefaultTimeZone)
+	}
+	return loc, nil
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// trackLocation returns the time zone of a track.
+func trackLocation(q rowQuerier, trackID int64) (*time.Location, error) {
+	var name string
+	if err := q.QueryRow(`SELECT timezone FROM tracks WHERE id = ?`, trackID).Scan(&name); err != nil {
+		return nil, fmt.Errorf("track %d: %w", trackID, err)
+	}
+	return LoadTimeZone(name)
+}
+
+// inZone reads the wall-clock time of t as a time in loc. ParseDate returns
+// wall-clock times, which are local to the track the event runs at.
+func inZone(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// ParseLocalDate parses a date with ParseDate as a wall-clock time in loc.
+// A date with an explicit offset, such as an exported start_date, is an
+// instant and is converted to loc instead.
+func ParseLocalDate(s string, loc *time.Location) (time.Time, error) {
+	t, zoned, err := parseDate(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if zoned {
+		return t.In(loc), nil
+	}
+	return inZone(t, loc), nil
+}
+
+// parseStoredDate reads a stored UTC date and returns it in loc.
+func parseStoredDate(s string, loc *time.Location) (time.Time, error) {
+	t, err := ParseDate(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
+// formatStoredDate formats t as a stored date: UTC in DateLayout.
+func formatStoredDate(t time.Time) string {
+	return t.UTC().Format(DateLayout)
+}
+
+// formatStoredTimestamp converts a stored UTC timestamp such as created_at
+// to RFC 3339. An empty timestamp stays empty.
+func formatStoredTimestamp(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	t, err := ParseDate(s)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+// checkEventSpan rejects an end that is not after the start. Both are local
+// wall-clock times at the track; a zero end means the event has none.
+func checkEventSpan(start, end time.Time) error {
+	if !end.IsZero() && !end.After(start) {
+		return fmt.Errorf("end date %s is not after start date %s",
+			end.Format(DateLayout), start.Format(DateLayout))
+	}
+	return nil
+}
+
+// eventTimesToUTC converts every event's stored dates from track-local
+// wall-clock time, as they were stored before migration 004, to UTC.
+func eventTimesToUTC(tx *sql.Tx) error {
+	return convertEventTimes(tx, func(stored string, loc *time.Location) (string, error) {
+		t, err := ParseLocalDate(stored, loc)
+		if err != nil {
+			return "", err
+		}
+		return formatStoredDate(t), nil
+	})
+}
+
+// eventTimesToLocal undoes eventTimesToUTC.
+func eventTimesToLocal(tx *sql.Tx) error {
+	return convertEventTimes(tx, func(stored string, loc *time.Location) (string, error) {
+		t, err := parseStoredDate(stored, loc)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(DateLayout), nil
+	})
+}
+
+func convertEventTimes(tx *sql.Tx, convert func(stored string, loc *time.Location) (string, error)) error {
+	rows, err := tx.Query(`SELECT e.id, CAST(e.event_datetime AS TEXT), COALESCE(CAST(e.end_date AS TEXT), ''), t.timezone
+		FROM events e JOIN tracks t ON e.track_id = t.id`)
+	if err != nil {
+		return err
+	}
+	type eventTimes struct {
+		id             int64
+		start, end, tz string
+	}
+	var all []eventTimes
+	for rows.Next() {
+		var e eventTimes
+		if err := rows.Scan(&e.id, &e.start, &e.end, &e.tz); err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range all {
+		loc, err := LoadTimeZone(e.tz)
+		if err != nil {
+			return fmt.Errorf("event %d: %w", e.id, err)
+		}
+		start, err := convert(e.start, loc)
+		if err != nil {
+			return fmt.Errorf("event %d: start date: %w", e.id, err)
+		}
+		var end any
+		if e.end != "" {
+			v, err := convert(e.end, loc)
+			if err != nil {
+				return fmt.Errorf("event %d: end date: %w", e.id, err)
+			}
+			end = v
+		}
+		if _, err := tx.Exec(`UPDATE events SET event_datetime = ?, end_date = ? WHERE id = ?`, start, end, e.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func readStoredDates(t *testing.T, db *sql.DB, id int64) (string, string) {
+	t.Helper()
+	var start, end string
+	err := db.QueryRow(`SELECT CAST(event_datetime AS TEXT), COALESCE(CAST(end_date AS TEXT), '') FROM events WHERE id = ?`, id).
+		Scan(&start, &end)
+	if err != nil {
+		t.Fatalf("Failed to read stored dates: %v", err)
+	}
+	return start, end
+}
+
+func TestEventTimesAcrossDST(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	winter, _ := CreateEvent(db, "Winter", trackID, "2026-01-10 09:00:00", "2026-01-10 17:00:00", nil, nil, "", "")
+	summer, _ := CreateEvent(db, "Summer", trackID, "2026-07-10 09:00:00", "", nil, nil, "", "")
+
+	if start, end := readStoredDates(t, db, winter); start != "2026-01-10 15:00:00" || end != "2026-01-10 23:00:00" {
+		t.Errorf("Expected CST to be stored as UTC-6, got %q and %q", start, end)
+	}
+	if start, _ := readStoredDates(t, db, summer); start != "2026-07-10 14:00:00" {
+		t.Errorf("Expected CDT to be stored as UTC-5, got %q", start)
+	}
+
+	ev, err := GetEvent(db, summer)
+	if err != nil {
+		t.Fatalf("GetEvent failed: %v", err)
+	}
+	if ev.TimeZone != "America/Chicago" {
+		t.Errorf("Expected America/Chicago, got %q", ev.TimeZone)
+	}
+	if ev.StartUTC != "2026-07-10T14:00:00Z" || ev.StartLocal != "2026-07-10T09:00:00" {
+		t.Errorf("Expected UTC and local start, got %q and %q", ev.StartUTC, ev.StartLocal)
+	}
+	if got := ev.StartDate.Format("2006-01-02T15:04:05-07:00"); got != "2026-07-10T09:00:00-05:00" {
+		t.Errorf("Expected StartDate in track time, got %s", got)
+	}
+}
+
+func TestEventTimesInTrackZone(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, err := CreateTrackInZone(db, "Bandimere", "Morrison", "3051 S Rooney Rd", "", "America/Denver")
+	if err != nil {
+		t.Fatalf("CreateTrackInZone failed: %v", err)
+	}
+	id, err := CreateEvent(db, "Mile High Nationals", trackID, "2026-07-10 09:00:00", "", nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	if start, _ := readStoredDates(t, db, id); start != "2026-07-10 15:00:00" {
+		t.Errorf("Expected MDT to be stored as UTC-6, got %q", start)
+	}
+
+	if _, err := CreateTrackInZone(db, "Nowhere", "", "", "", "Mars/Olympus_Mons"); err == nil {
+		t.Error("Expected error for unknown time zone")
+	}
+}
+
+func TestEventEndMustFollowStart(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	if _, err := CreateEvent(db, "Backwards", trackID, "2026-03-22 18:00:00", "2026-03-22 09:00:00", nil, nil, "", ""); err == nil {
+		t.Error("Expected error for end before start")
+	}
+
+	id, err := CreateEvent(db, "Forwards", trackID, "2026-03-22 09:00:00", "2026-03-22 18:00:00", nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	// only the start changes, but it now falls after the stored end
+	start := "2026-03-23 09:00:00"
+	if err := UpdateEvent(db, id, EventUpdate{StartDate: &start}); err == nil {
+		t.Error("Expected error moving the start past the end")
+	}
+	end := "2026-03-23 18:00:00"
+	if err := UpdateEvent(db, id, EventUpdate{StartDate: &start, EndDate: &end}); err != nil {
+		t.Errorf("Moving both dates failed: %v", err)
+	}
+}
+
+func TestUpdateTrackTimeZoneKeepsLocalTimes(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	id, _ := CreateEvent(db, "Test Event", trackID, "2026-07-10 09:00:00", "2026-07-10 17:00:00", nil, nil, "", "")
+
+	tz := "America/Denver"
+	if err := UpdateTrack(db, trackID, TrackUpdate{TimeZone: &tz}); err != nil {
+		t.Fatalf("UpdateTrack failed: %v", err)
+	}
+	ev, _ := GetEvent(db, id)
+	if ev.StartLocal != "2026-07-10T09:00:00" || ev.EndLocal != "2026-07-10T17:00:00" {
+		t.Errorf("Expected local times to be kept, got %q to %q", ev.StartLocal, ev.EndLocal)
+	}
+	if ev.StartUTC != "2026-07-10T15:00:00Z" {
+		t.Errorf("Expected the instant to move with the zone, got %q", ev.StartUTC)
+	}
+
+	bad := "Central"
+	if err := UpdateTrack(db, trackID, TrackUpdate{TimeZone: &bad}); err == nil {
+		t.Error("Expected error for invalid time zone")
+	}
+}
+
+func TestMigrateConvertsLegacyTimesToUTC(t *testing.T) {
+	db := setupLegacyTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO tracks(name, city, address, url) VALUES('Test Track', 'Test City', '', '')`); err != nil {
+		t.Fatalf("Insert track failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, end_date, url, description)
+		VALUES('Legacy', 1, '2026-07-04 20:00:00', '2026-07-04 23:30:00', '', '')`); err != nil {
+		t.Fatalf("Insert event failed: %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if start, end := readStoredDates(t, db, 1); start != "2026-07-05 01:00:00" || end != "2026-07-05 04:30:00" {
+		t.Errorf("Expected local times converted to UTC, got %q and %q", start, end)
+	}
+
+	if _, err := MigrateTo(db, "003"); err != nil {
+		t.Fatalf("MigrateTo 003 failed: %v", err)
+	}
+	if start, end := readStoredDates(t, db, 1); start != "2026-07-04 20:00:00" || end != "2026-07-04 23:30:00" {
+		t.Errorf("Expected rollback to restore local times, got %q and %q", start, end)
+	}
+}
@@ -0,0 +1,514 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNoChanges is returned by the Update functions when no field is set.
+var ErrNoChanges = errors.New("no fields to update")
+
+// TrackUpdate lists the track fields to change. Nil fields are left as is.
+type TrackUpdate struct {
+	Name     *string
+	City     *string
+	Address  *string
+	URL      *string
+	TimeZone *string
+}
+
+// EventUpdate lists the event fields to change. Nil fields are left as is.
+// An EndDate of "" clears the end date; a fee with Valid=false clears the fee.
+type EventUpdate struct {
+	Title        *string
+	TrackID      *int64
+	StartDate    *string
+	EndDate      *string
+	DriverFee    *sql.NullFloat64
+	SpectatorFee *sql.NullFloat64
+	URL          *string
+	Description  *string
+	Series       *string // "" clears the series
+}
+
+// EventClassUpdate lists the class fields to change. Nil fields are left as is.
+type EventClassUpdate struct {
+	EventID  *int64
+	Name     *string
+	BuyinFee *sql.NullFloat64
+}
+
+// EventClassRuleUpdate lists the rule fields to change. Nil fields are left as is.
+type EventClassRuleUpdate struct {
+	EventClassID *int64
+	Rule         *string
+}
+
+// GetTrack returns the track with the given ID.
+func GetTrack(db *sql.DB, id int64) (Track, error) {
+	var t Track
+	err := db.QueryRow(`SELECT id, name, city, address, url, timezone, COALESCE(slug, '') FROM tracks WHERE id = ?`, id).
+		Scan(&t.ID, &t.Name, &t.City, &t.Address, &t.URL, &t.TimeZone, &t.Slug)
+	if err != nil {
+		return Track{}, fmt.Errorf("track %d: %w", id, err)
+	}
+	return t, nil
+}
+
+// GetEvent returns the event with the given ID, without classes.
+func GetEvent(db *sql.DB, id int64) (Event, error) {
+	events, err := queryEvents(db, "WHERE e.id = ?", id)
+	if err != nil {
+		return Event{}, err
+	}
+	if len(events) == 0 {
+		return Event{}, fmt.Errorf("event %d: %w", id, sql.ErrNoRows)
+	}
+	return events[0], nil
+}
+
+// GetEventClass returns the event class with the given ID, without rules.
+func GetEventClass(db *sql.DB, id int64) (EventClass, error) {
+	var ec EventClass
+	var buyinFee sql.NullFloat64
+	err := db.QueryRow(`SELECT id, event_id, name, buyin_fee FROM event_classes WHERE id = ?`, id).
+		Scan(&ec.ID, &ec.EventID, &ec.Name, &buyinFee)
+	if err != nil {
+		return EventClass{}, fmt.Errorf("event class %d: %w", id, err)
+	}
+	if buyinFee.Valid {
+		v := buyinFee.Float64
+		ec.BuyinFee = &v
+	}
+	return ec, nil
+}
+
+// GetEventClassRule returns the rule with the given ID.
+func GetEventClassRule(db *sql.DB, id int64) (EventClassRule, error) {
+	var r EventClassRule
+	err := db.QueryRow(`SELECT id, event_class_id, rule FROM event_class_rules WHERE id = ?`, id).
+		Scan(&r.ID, &r.EventClassID, &r.Rule)
+	if err != nil {
+		return EventClassRule{}, fmt.Errorf("rule %d: %w", id, err)
+	}
+	return r, nil
+}
+
+// UpdateTrack changes the given fields of a track. Changing the time zone
+// keeps the local wall-clock times of the track's events, so fixing a wrong
+// zone does not move events to other hours.
+func UpdateTrack(db *sql.DB, id int64, u TrackUpdate) error {
+	var set setClause
+	set.addString("name", u.Name)
+	set.addString("city", u.City)
+	set.addString("address", u.Address)
+	set.addString("url", u.URL)
+	if u.TimeZone == nil {
+		return set.exec(db, "tracks", "track", id)
+	}
+
+	newLoc, err := LoadTimeZone(*u.TimeZone)
+	if err != nil {
+		return err
+	}
+	set.add("timezone", newLoc.String())
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	oldLoc, err := trackLocation(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := set.exec(tx, "tracks", "track", id); err != nil {
+		return err
+	}
+	if err := moveEventsToZone(tx, id, oldLoc, newLoc); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// moveEventsToZone re-reads the stored dates of a track's events as local
+// times in newLoc instead of oldLoc.
+func moveEventsToZone(tx *sql.Tx, trackID int64, oldLoc, newLoc *time.Location) error {
+	stored, err := storedEventDates(tx, `WHERE track_id = ?`, trackID)
+	if err != nil {
+		return err
+	}
+	move := func(s string) (any, error) {
+		if s == "" {
+			return nil, nil
+		}
+		t, err := parseStoredDate(s, oldLoc)
+		if err != nil {
+			return nil, err
+		}
+		return formatStoredDate(inZone(t, newLoc)), nil
+	}
+	for _, d := range stored {
+		start, err := move(d.start)
+		if err != nil {
+			return fmt.Errorf("event %d: start date: %w", d.id, err)
+		}
+		end, err := move(d.end)
+		if err != nil {
+			return fmt.Errorf("event %d: end date: %w", d.id, err)
+		}
+		if _, err := tx.Exec(`UPDATE events SET event_datetime = ?, end_date = ? WHERE id = ?`, start, end, d.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateEvent changes the given fields of an event. Dates are local times at
+// the event's track (the new track, if TrackID changes too), and the end
+// must come after the start. An event imported from the aggregator becomes
+// a local one, so that importing the feed again leaves the edit alone, and
+// the fields that changed are recorded in its LocalFields, so the merged
+// events.json takes those from it.
+func UpdateEvent(db *sql.DB, id int64, u EventUpdate) error {
+	var set setClause
+	set.addString("title", u.Title)
+	if u.TrackID != nil {
+		set.add("track_id", *u.TrackID)
+	}
+	set.addFee("event_driver_fee", u.DriverFee)
+	set.addFee("event_spectator_fee", u.SpectatorFee)
+	set.addString("url", u.URL)
+	set.addString("description", u.Description)
+	if u.Series != nil {
+		set.add("series", nullIfEmpty(strings.TrimSpace(*u.Series)))
+	}
+	if len(set.cols) == 0 && u.StartDate == nil && u.EndDate == nil {
+		return ErrNoChanges
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if u.StartDate == nil && u.EndDate == nil {
+		if err := updateEventColumns(tx, &set, id); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	var trackID int64
+	var storedStart, storedEnd string
+	err = tx.QueryRow(`SELECT track_id, CAST(event_datetime AS TEXT), COALESCE(CAST(end_date AS TEXT), '') FROM events WHERE id = ?`, id).
+		Scan(&trackID, &storedStart, &storedEnd)
+	if err != nil {
+		return fmt.Errorf("event %d: %w", id, err)
+	}
+	if u.TrackID != nil {
+		trackID = *u.TrackID
+	}
+	loc, err := trackLocation(tx, trackID)
+	if err != nil {
+		return err
+	}
+
+	var start, end time.Time
+	if u.StartDate != nil {
+		if start, err = ParseLocalDate(*u.StartDate, loc); err != nil {
+			return fmt.Errorf("invalid start date: %w", err)
+		}
+		set.add("event_datetime", formatStoredDate(start))
+	} else if start, err = parseStoredDate(storedStart, loc); err != nil {
+		return fmt.Errorf("event %d: start date: %w", id, err)
+	}
+	if u.EndDate != nil {
+		if strings.TrimSpace(*u.EndDate) != "" {
+			if end, err = ParseLocalDate(*u.EndDate, loc); err != nil {
+				return fmt.Errorf("invalid end date: %w", err)
+			}
+		}
+		var v any
+		if !end.IsZero() {
+			v = formatStoredDate(end)
+		}
+		set.add("end_date", v)
+	} else if storedEnd != "" {
+		if end, err = parseStoredDate(storedEnd, loc); err != nil {
+			return fmt.Errorf("event %d: end date: %w", id, err)
+		}
+	}
+	if err := checkEventSpan(start, end); err != nil {
+		return err
+	}
+
+	if err := updateEventColumns(tx, &set, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateEventColumns runs the update of an event and marks the fields it
+// changed as edited here.
+func updateEventColumns(tx *sql.Tx, set *setClause, id int64) error {
+	changed, err := set.differing(tx, "events", "event", id)
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+	if err := set.exec(tx, "events", "event", id); err != nil {
+		return err
+	}
+	var fields []string
+	for _, col := range changed {
+		fields = append(fields, localFieldOf[col])
+	}
+	return markEventsLocal(tx, fields, id)
+}
+
+// UpdateEventClass changes the given fields of an event class. Like
+// UpdateEvent, a change makes the class's event (and the event it moves to)
+// local, so importing the aggregator's feed again does not undo it.
+func UpdateEventClass(db *sql.DB, id int64, u EventClassUpdate) error {
+	var set setClause
+	if u.EventID != nil {
+		set.add("event_id", *u.EventID)
+	}
+	set.addString("name", u.Name)
+	set.addFee("buyin_fee", u.BuyinFee)
+	if len(set.cols) == 0 {
+		return ErrNoChanges
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var eventID int64
+	if err := tx.QueryRow(`SELECT event_id FROM event_classes WHERE id = ?`, id).Scan(&eventID); err != nil {
+		return fmt.Errorf("event class %d: %w", id, err)
+	}
+	changed, err := set.update(tx, "event_classes", "event class", id)
+	if err != nil {
+		return err
+	}
+	if changed {
+		if err := markClassEventsLocal(tx, id); err != nil {
+			return err
+		}
+		if err := markEventsLocal(tx, []string{LocalClasses}, eventID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateEventClassRule changes the given fields of a class rule. A change
+// makes the events of the rule's old and new class local, as
+// UpdateEventClass does.
+func UpdateEventClassRule(db *sql.DB, id int64, u EventClassRuleUpdate) error {
+	var set setClause
+	if u.EventClassID != nil {
+		set.add("event_class_id", *u.EventClassID)
+	}
+	set.addString("rule", u.Rule)
+	if len(set.cols) == 0 {
+		return ErrNoChanges
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var classID int64
+	if err := tx.QueryRow(`SELECT event_class_id FROM event_class_rules WHERE id = ?`, id).Scan(&classID); err != nil {
+		return fmt.Errorf("rule %d: %w", id, err)
+	}
+	changed, err := set.update(tx, "event_class_rules", "rule", id)
+	if err != nil {
+		return err
+	}
+	if changed {
+		if u.EventClassID != nil {
+			if err := markClassEventsLocal(tx, *u.EventClassID); err != nil {
+				return err
+			}
+		}
+		if err := markClassEventsLocal(tx, classID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Event fields recorded in Event.LocalFields when they are edited here.
+const (
+	LocalTitle        = "title"
+	LocalTrack        = "track"
+	LocalStartDate    = "start_date"
+	LocalEndDate      = "end_date"
+	LocalDriverFee    = "driver_fee"
+	LocalSpectatorFee = "spectator_fee"
+	LocalURL          = "url"
+	LocalDescription  = "description"
+	LocalSeries       = "series"
+	LocalStatus       = "status"
+	LocalClasses      = "classes" // classes and their rules
+)
+
+// localFieldOf maps events columns to the fields they belong to.
+var localFieldOf = map[string]string{
+	"title":               LocalTitle,
+	"track_id":            LocalTrack,
+	"event_datetime":      LocalStartDate,
+	"end_date":            LocalEndDate,
+	"event_driver_fee":    LocalDriverFee,
+	"event_spectator_fee": LocalSpectatorFee,
+	"url":                 LocalURL,
+	"description":         LocalDescription,
+	"series":              LocalSeries,
+}
+
+// markEventsLocal makes events local, adds fields to their local fields and
+// stamps them as updated: the aggregator import only updates aggregator
+// events, and the merged events.json takes the local fields from them.
+func markEventsLocal(db execer, fields []string, ids ...int64) error {
+	for _, id := range ids {
+		var stored string
+		if err := db.QueryRow(`SELECT local_fields FROM events WHERE id = ?`, id).Scan(&stored); err != nil {
+			return fmt.Errorf("event %d: %w", id, err)
+		}
+		local := splitLocalFields(stored)
+		for _, f := range fields {
+			if !slices.Contains(local, f) {
+				local = append(local, f)
+			}
+		}
+		if _, err := db.Exec(`UPDATE events SET source = ?, local_fields = ?, updated_at = datetime('now') WHERE id = ?`,
+			SourceLocal, strings.Join(local, ","), id); err != nil {
+			return fmt.Errorf("event %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// markClassEventsLocal marks the classes of the events of the given classes
+// as edited here.
+func markClassEventsLocal(db execer, classIDs ...int64) error {
+	for _, id := range classIDs {
+		var eventID int64
+		if err := db.QueryRow(`SELECT event_id FROM event_classes WHERE id = ?`, id).Scan(&eventID); err != nil {
+			return fmt.Errorf("event of class %d: %w", id, err)
+		}
+		if err := markEventsLocal(db, []string{LocalClasses}, eventID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitLocalFields(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// setClause collects "column = ?" assignments for an UPDATE statement.
+type setClause struct {
+	cols []string
+	args []any
+}
+
+func (s *setClause) add(col string, v any) {
+	s.cols = append(s.cols, col)
+	s.args = append(s.args, v)
+}
+
+func (s *setClause) addString(col string, v *string) {
+	if v != nil {
+		s.add(col, *v)
+	}
+}
+
+func (s *setClause) addFee(col string, v *sql.NullFloat64) {
+	if v == nil {
+		return
+	}
+	var val any
+	if v.Valid {
+		val = v.Float64
+	}
+	s.add(col, val)
+}
+
+// differing returns the columns whose new value differs from the row's.
+func (s *setClause) differing(db execer, table, noun string, id int64) ([]string, error) {
+	differs := make([]string, len(s.cols))
+	for i, col := range s.cols {
+		differs[i] = col + " IS NOT ?"
+	}
+	flags := make([]bool, len(s.cols))
+	dest := make([]any, len(flags))
+	for i := range flags {
+		dest[i] = &flags[i]
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", strings.Join(differs, ", "), table)
+	if err := db.QueryRow(q, append(append([]any(nil), s.args...), id)...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("%s %d: %w", noun, id, err)
+	}
+	var out []string
+	for i, col := range s.cols {
+		if flags[i] {
+			out = append(out, col)
+		}
+	}
+	return out, nil
+}
+
+func (s *setClause) exec(db execer, table, noun string, id int64) error {
+	_, err := s.update(db, table, noun, id)
+	return err
+}
+
+// update runs the UPDATE and reports whether the row changed: an update
+// that sets every column to the value it already has changes nothing.
+func (s *setClause) update(db execer, table, noun string, id int64) (bool, error) {
+	if len(s.cols) == 0 {
+		return false, ErrNoChanges
+	}
+	assign := make([]string, len(s.cols))
+	differs := make([]string, len(s.cols))
+	for i, col := range s.cols {
+		assign[i] = col + " = ?"
+		differs[i] = col + " IS NOT ?"
+	}
+	q := fmt.Sprintf("UPDATE %s SET %s WHERE id = ? AND (%s)", table,
+		strings.Join(assign, ", "), strings.Join(differs, " OR "))
+	args := append(append(append([]any(nil), s.args...), id), s.args...)
+	res, err := db.Exec(q, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// nothing updated: either the row is missing or nothing differed
+	var exists int
+	if err := db.QueryRow(fmt.Sprintf("SELECT 1 FROM %s WHERE id = ?", table), id).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s %d: %w", noun, id, err)
+	}
+	return false, nil
+}
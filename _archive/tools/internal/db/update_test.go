@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestUpdateTrack(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	id, err := CreateTrack(db, "Xtreme Raceway", "Ferris", "1800 S I-45", "https://old.example")
+	if err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	name := "Xtreme Raceway Park"
+	if err := UpdateTrack(db, id, TrackUpdate{Name: &name}); err != nil {
+		t.Fatalf("UpdateTrack failed: %v", err)
+	}
+
+	track, err := GetTrack(db, id)
+	if err != nil {
+		t.Fatalf("GetTrack failed: %v", err)
+	}
+	if track.Name != name {
+		t.Errorf("Expected name %q, got %q", name, track.Name)
+	}
+	if track.City != "Ferris" || track.URL != "https://old.example" {
+		t.Errorf("Expected untouched fields to be preserved, got %+v", track)
+	}
+}
+
+func TestUpdateEventPatchesOnlyGivenFields(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	driverFee := 50.0
+	spectatorFee := 20.0
+	id, err := CreateEvent(db, "Fall Nationls", trackID, "2025-10-03 08:00:00", "2025-10-05 18:00:00",
+		&driverFee, &spectatorFee, "https://test.com/event", "NHRA fall event")
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	title := "Fall Nationals"
+	endDate := "2025-10-12 18:00:00"
+	err = UpdateEvent(db, id, EventUpdate{
+		Title:     &title,
+		EndDate:   &endDate,
+		DriverFee: &sql.NullFloat64{},
+	})
+	if err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+
+	ev, err := GetEvent(db, id)
+	if err != nil {
+		t.Fatalf("GetEvent failed: %v", err)
+	}
+	if ev.Title != title {
+		t.Errorf("Expected title %q, got %q", title, ev.Title)
+	}
+	if ev.EndDate == nil || ev.EndDate.Format("2006-01-02 15:04:05") != endDate {
+		t.Errorf("Expected end date %s, got %v", endDate, ev.EndDate)
+	}
+	if ev.DriverFee != nil {
+		t.Errorf("Expected driver fee to be cleared, got %v", *ev.DriverFee)
+	}
+	if ev.SpectatorFee == nil || *ev.SpectatorFee != 20.0 {
+		t.Errorf("Expected spectator fee to be preserved, got %v", ev.SpectatorFee)
+	}
+	if ev.Description != "NHRA fall event" {
+		t.Errorf("Expected description to be preserved, got %q", ev.Description)
+	}
+
+	empty := ""
+	if err := UpdateEvent(db, id, EventUpdate{EndDate: &empty}); err != nil {
+		t.Fatalf("UpdateEvent clearing end date failed: %v", err)
+	}
+	ev, _ = GetEvent(db, id)
+	if ev.EndDate != nil {
+		t.Errorf("Expected end date to be cleared, got %v", ev.EndDate)
+	}
+}
+
+func TestUpdateEventNotFound(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	title := "Missing"
+	err := UpdateEvent(db, 99999, EventUpdate{Title: &title})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+
+	if _, err := GetEvent(db, 99999); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows from GetEvent, got %v", err)
+	}
+}
+
+func TestUpdateEventNoChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := UpdateEvent(db, 1, EventUpdate{}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges, got %v", err)
+	}
+}
+
+func TestUpdateEventClassAndRule(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := Seed(db); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	classes, _ := ListEventClasses(db)
+	rules, _ := ListEventClassRules(db)
+
+	name := "Pro Street (Radial)"
+	fee := sql.NullFloat64{Float64: 125, Valid: true}
+	if err := UpdateEventClass(db, classes[0].ID, EventClassUpdate{Name: &name, BuyinFee: &fee}); err != nil {
+		t.Fatalf("UpdateEventClass failed: %v", err)
+	}
+	ec, err := GetEventClass(db, classes[0].ID)
+	if err != nil {
+		t.Fatalf("GetEventClass failed: %v", err)
+	}
+	if ec.Name != name || ec.BuyinFee == nil || *ec.BuyinFee != 125 {
+		t.Errorf("Expected updated class, got %+v", ec)
+	}
+	if ec.EventID != classes[0].EventID {
+		t.Errorf("Expected event ID %d to be preserved, got %d", classes[0].EventID, ec.EventID)
+	}
+
+	text := "DOT radial tires only"
+	if err := UpdateEventClassRule(db, rules[0].ID, EventClassRuleUpdate{Rule: &text}); err != nil {
+		t.Fatalf("UpdateEventClassRule failed: %v", err)
+	}
+	r, err := GetEventClassRule(db, rules[0].ID)
+	if err != nil {
+		t.Fatalf("GetEventClassRule failed: %v", err)
+	}
+	if r.Rule != text || r.EventClassID != rules[0].EventClassID {
+		t.Errorf("Expected updated rule, got %+v", r)
+	}
+}
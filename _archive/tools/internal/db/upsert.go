@@ -0,0 +1,334 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// eventFields are the columns an import writes for an event. StartDate and
+// EndDate are wall-clock times at the track until normalizeDates converts
+// them to stored UTC dates.
+type eventFields struct {
+	Title        string
+	TrackID      int64
+	StartDate    string
+	EndDate      string
+	DriverFee    *float64
+	SpectatorFee *float64
+	URL          string
+	Description  string
+	ExternalID   string
+	Series       string
+	Status       string // StatusScheduled when empty, or the stored status on update
+	UUID         string // generated when empty
+	Source       string // SourceLocal when empty
+
+	// localStart is the start in the track's time zone, set by normalizeDates
+	localStart time.Time
+}
+
+// normalizeDates reads the start and end dates as local times at the track,
+// checks that the end comes after the start, and rewrites both as stored
+// UTC dates.
+func (f *eventFields) normalizeDates(ex execer) error {
+	loc, err := trackLocation(ex, f.TrackID)
+	if err != nil {
+		return err
+	}
+	start, err := ParseLocalDate(f.StartDate, loc)
+	if err != nil {
+		return fmt.Errorf("invalid start date: %w", err)
+	}
+	var end time.Time
+	if strings.TrimSpace(f.EndDate) != "" {
+		if end, err = ParseLocalDate(f.EndDate, loc); err != nil {
+			return fmt.Errorf("invalid end date: %w", err)
+		}
+	}
+	if err := checkEventSpan(start, end); err != nil {
+		return err
+	}
+
+	f.localStart = start
+	f.StartDate = formatStoredDate(start)
+	f.EndDate = ""
+	if !end.IsZero() {
+		f.EndDate = formatStoredDate(end)
+	}
+	return nil
+}
+
+func insertEvent(ex execer, f eventFields) (int64, error) {
+	if err := f.normalizeDates(ex); err != nil {
+		return 0, err
+	}
+	return insertNormalizedEvent(ex, f)
+}
+
+func insertNormalizedEvent(ex execer, f eventFields) (int64, error) {
+	if f.UUID == "" {
+		id, err := NewUUID()
+		if err != nil {
+			return 0, err
+		}
+		f.UUID = id
+	}
+	if f.Source == "" {
+		f.Source = SourceLocal
+	}
+	if f.Status == "" {
+		f.Status = StatusScheduled
+	}
+	result, err := ex.Exec(`INSERT INTO events(title, track_id, event_datetime, end_date, event_driver_fee, event_spectator_fee, url, description, external_id,
+		series, status, uuid, source, created_at, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))`,
+		f.Title, f.TrackID, f.StartDate, nullIfEmpty(f.EndDate), nullFee(f.DriverFee), nullFee(f.SpectatorFee),
+		f.URL, f.Description, nullIfEmpty(f.ExternalID), nullIfEmpty(f.Series), f.Status, f.UUID, f.Source)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// NormalizeTitle reduces an event title to lower-case letters and digits
+// separated by single spaces, so that "TMCCC  Series" and "tmccc series!"
+// identify the same event.
+func NormalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// existingEvent is an events row as compared by upsertEvent.
+type existingEvent struct {
+	ID int64
+	eventFields
+}
+
+// findEventByIdentity finds the event f refers to: by external_id when it
+// has one, or else by track, local start date and normalized title. f must
+// have been through normalizeDates. An event that
+// already has a different external_id never matches by natural key.
+func findEventByIdentity(tx *sql.Tx, f eventFields) (*existingEvent, error) {
+	// the casts keep the driver from turning DATETIME columns into time.Time,
+	// so dates compare as the text that was written
+	const cols = `SELECT id, title, track_id, CAST(event_datetime AS TEXT), COALESCE(CAST(end_date AS TEXT), ''), event_driver_fee, event_spectator_fee,
+		COALESCE(url, ''), COALESCE(description, ''), COALESCE(external_id, ''), COALESCE(series, ''), status FROM events `
+	if f.ExternalID != "" {
+		found, err := scanExistingEvents(tx, cols+`WHERE external_id = ?`, f.ExternalID)
+		if err != nil || len(found) > 0 {
+			return first(found), err
+		}
+	}
+
+	candidates, err := scanExistingEvents(tx, cols+`WHERE track_id = ?`, f.TrackID)
+	if err != nil {
+		return nil, err
+	}
+	start := f.localStart
+	title := NormalizeTitle(f.Title)
+	var matches []existingEvent
+	for _, c := range candidates {
+		if f.ExternalID != "" && c.ExternalID != "" {
+			continue
+		}
+		// compare days at the track, not in UTC
+		cStart, err := parseStoredDate(c.StartDate, start.Location())
+		if err == nil && sameDay(cStart, start) && NormalizeTitle(c.Title) == title {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("event %q on %s matches %d existing events; add an external_id to tell them apart",
+			f.Title, start.Format("2006-01-02"), len(matches))
+	}
+	return first(matches), nil
+}
+
+func first(events []existingEvent) *existingEvent {
+	if len(events) == 0 {
+		return nil
+	}
+	return &events[0]
+}
+
+func scanExistingEvents(tx *sql.Tx, query string, args ...any) ([]existingEvent, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []existingEvent
+	for rows.Next() {
+		var e existingEvent
+		var driverFee, spectatorFee sql.NullFloat64
+		if err := rows.Scan(&e.ID, &e.Title, &e.TrackID, &e.StartDate, &e.EndDate, &driverFee, &spectatorFee,
+			&e.URL, &e.Description, &e.ExternalID, &e.Series, &e.Status); err != nil {
+			return nil, err
+		}
+		e.DriverFee = feePtr(driverFee)
+		e.SpectatorFee = feePtr(spectatorFee)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// upsertEvent inserts f, or updates the event it identifies if any field
+// differs. An empty external_id or series in f keeps the stored one. An
+// updated event becomes local, with the fields that changed, as with
+// UpdateEvent.
+func upsertEvent(tx *sql.Tx, f eventFields) (rowOutcome, error) {
+	if err := f.normalizeDates(tx); err != nil {
+		return 0, err
+	}
+	existing, err := findEventByIdentity(tx, f)
+	if err != nil {
+		return 0, err
+	}
+	if existing == nil {
+		if _, err := insertNormalizedEvent(tx, f); err != nil {
+			return 0, fmt.Errorf("create event: %w", err)
+		}
+		return rowCreated, nil
+	}
+
+	if f.ExternalID == "" {
+		f.ExternalID = existing.ExternalID
+	}
+	if f.Series == "" {
+		f.Series = existing.Series
+	}
+	outcome, err := updateExistingEvent(tx, *existing, f)
+	if err != nil || outcome == rowUnchanged {
+		return outcome, err
+	}
+	return outcome, markEventsLocal(tx, changedFields(*existing, f), existing.ID)
+}
+
+// changedFields returns the fields of existing that f changes, as recorded
+// in Event.LocalFields. An empty status in f keeps the stored one.
+func changedFields(existing existingEvent, f eventFields) []string {
+	var fields []string
+	changed := func(field string, differs bool) {
+		if differs {
+			fields = append(fields, field)
+		}
+	}
+	changed(LocalTitle, f.Title != existing.Title)
+	changed(LocalTrack, f.TrackID != existing.TrackID)
+	changed(LocalStartDate, f.StartDate != existing.StartDate)
+	changed(LocalEndDate, f.EndDate != existing.EndDate)
+	changed(LocalDriverFee, !feesEqual(f.DriverFee, existing.DriverFee))
+	changed(LocalSpectatorFee, !feesEqual(f.SpectatorFee, existing.SpectatorFee))
+	changed(LocalURL, f.URL != existing.URL)
+	changed(LocalDescription, f.Description != existing.Description)
+	changed(LocalSeries, f.Series != existing.Series)
+	changed(LocalStatus, f.Status != "" && f.Status != existing.Status)
+	return fields
+}
+
+// updateExistingEvent writes f over the existing event, unless no field
+// differs. f must have been through normalizeDates. A status change must be
+// one SetEventStatus allows, and an event put back on the schedule loses its
+// link to a replacement, as with SetEventStatus.
+func updateExistingEvent(tx *sql.Tx, existing existingEvent, f eventFields) (rowOutcome, error) {
+	if len(changedFields(existing, f)) == 0 && f.ExternalID == existing.ExternalID {
+		return rowUnchanged, nil
+	}
+	if f.Status == "" {
+		f.Status = existing.Status
+	} else if f.Status != existing.Status {
+		if err := CheckStatusTransition(existing.Status, f.Status); err != nil {
+			return 0, fmt.Errorf("event %d: %w", existing.ID, err)
+		}
+	}
+
+	_, err := tx.Exec(`UPDATE events SET title = ?, track_id = ?, event_datetime = ?, end_date = ?,
+		event_driver_fee = ?, event_spectator_fee = ?, url = ?, description = ?, external_id = ?, series = ?, status = ?,
+		rescheduled_to = CASE WHEN ? = ? THEN NULL ELSE rescheduled_to END, updated_at = datetime('now')
+		WHERE id = ?`,
+		f.Title, f.TrackID, f.StartDate, nullIfEmpty(f.EndDate), nullFee(f.DriverFee), nullFee(f.SpectatorFee),
+		f.URL, f.Description, nullIfEmpty(f.ExternalID), nullIfEmpty(f.Series), f.Status, f.Status, StatusScheduled, existing.ID)
+	if err != nil {
+		return 0, fmt.Errorf("update event %d: %w", existing.ID, err)
+	}
+	return rowUpdated, nil
+}
+
+// upsertEventClass inserts a class, or updates the buy-in fee of the class
+// with the same name in the event.
+func upsertEventClass(tx *sql.Tx, eventID int64, name string, buyinFee *float64) (rowOutcome, error) {
+	var id int64
+	var current sql.NullFloat64
+	err := tx.QueryRow(`SELECT id, buyin_fee FROM event_classes WHERE event_id = ? AND name = ? COLLATE NOCASE`,
+		eventID, name).Scan(&id, &current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, err := tx.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, ?, ?)`,
+			eventID, name, nullFee(buyinFee)); err != nil {
+			return 0, fmt.Errorf("insert class: %w", err)
+		}
+		return rowCreated, nil
+	case err != nil:
+		return 0, err
+	}
+
+	if feesEqual(buyinFee, feePtr(current)) {
+		return rowUnchanged, nil
+	}
+	if _, err := tx.Exec(`UPDATE event_classes SET buyin_fee = ? WHERE id = ?`, nullFee(buyinFee), id); err != nil {
+		return 0, fmt.Errorf("update class %d: %w", id, err)
+	}
+	return rowUpdated, nil
+}
+
+// upsertEventClassRule inserts a rule unless the class already has it.
+func upsertEventClassRule(tx *sql.Tx, classID int64, rule string) (rowOutcome, error) {
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM event_class_rules WHERE event_class_id = ? AND rule = ?)`,
+		classID, rule).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists {
+		return rowUnchanged, nil
+	}
+	if _, err := tx.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES(?, ?)`, classID, rule); err != nil {
+		return 0, fmt.Errorf("insert rule: %w", err)
+	}
+	return rowCreated, nil
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullFee(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func feePtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+func feesEqual(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"regexp"
+	"testing"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUID(t *testing.T) {
+	a, err := NewUUID()
+	if err != nil {
+		t.Fatalf("NewUUID failed: %v", err)
+	}
+	b, _ := NewUUID()
+	if !uuidV4.MatchString(a) {
+		t.Errorf("Expected a version 4 UUID, got %q", a)
+	}
+	if a == b {
+		t.Error("Expected two UUIDs to differ")
+	}
+}
+
+func TestEventsGetUUIDAndTimestamps(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	trackID, _ := CreateTrack(db, "Test Track", "Test City", "123 Test St", "https://test.com")
+	id, err := CreateEvent(db, "Test Event", trackID, "2026-03-22 09:00:00", "", nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	ev, _ := GetEvent(db, id)
+	if !uuidV4.MatchString(ev.UUID) {
+		t.Errorf("Expected a UUID, got %q", ev.UUID)
+	}
+	if ev.CreatedAt == "" || ev.CreatedAt != ev.UpdatedAt {
+		t.Errorf("Expected created_at = updated_at on insert, got %q and %q", ev.CreatedAt, ev.UpdatedAt)
+	}
+
+	if _, err := db.Exec(`UPDATE events SET created_at = '2026-01-01 00:00:00', updated_at = '2026-01-01 00:00:00'`); err != nil {
+		t.Fatalf("Failed to backdate event: %v", err)
+	}
+	title := "Renamed"
+	if err := UpdateEvent(db, id, EventUpdate{Title: &title}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	ev, _ = GetEvent(db, id)
+	if ev.CreatedAt != "2026-01-01T00:00:00Z" {
+		t.Errorf("Expected created_at to be kept, got %q", ev.CreatedAt)
+	}
+	if ev.UpdatedAt == "2026-01-01T00:00:00Z" {
+		t.Error("Expected updated_at to change on update")
+	}
+
+	// setting a field to the value it has is not a change
+	if _, err := db.Exec(`UPDATE events SET updated_at = '2026-01-01 00:00:00', source = ?`, SourceAggregator); err != nil {
+		t.Fatalf("Failed to backdate event: %v", err)
+	}
+	if err := UpdateEvent(db, id, EventUpdate{Title: &title}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	ev, _ = GetEvent(db, id)
+	if ev.UpdatedAt != "2026-01-01T00:00:00Z" || ev.Source != SourceAggregator {
+		t.Errorf("Expected an unchanged event to keep updated_at and source, got %q and %q", ev.UpdatedAt, ev.Source)
+	}
+}
+
+func TestMigrateBackfillsEventUUIDs(t *testing.T) {
+	db := setupLegacyTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO tracks(name, city, address, url) VALUES('Test Track', 'Test City', '', '')`); err != nil {
+		t.Fatalf("Insert track failed: %v", err)
+	}
+	for _, title := range []string{"One", "Two"} {
+		if _, err := db.Exec(`INSERT INTO events(title, track_id, event_datetime, url, description)
+			VALUES(?, 1, '2026-07-04 20:00:00', '', '')`, title); err != nil {
+			t.Fatalf("Insert event failed: %v", err)
+		}
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	events, err := ListEvents(db)
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].UUID == events[1].UUID {
+		t.Fatalf("Expected two events with distinct UUIDs, got %+v", events)
+	}
+	for _, ev := range events {
+		if !uuidV4.MatchString(ev.UUID) || ev.CreatedAt == "" || ev.UpdatedAt == "" {
+			t.Errorf("Expected UUID and timestamps for event %d, got %q, %q, %q", ev.ID, ev.UUID, ev.CreatedAt, ev.UpdatedAt)
+		}
+	}
+}
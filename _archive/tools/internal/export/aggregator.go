@@ -0,0 +1,291 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// AggregatorFile is the file the aggregator export writes. It is not the
+// site's events.json: the site reads the flat list that its sync script, and
+// the merge, write there, and events.schema.json describes the aggregator's
+// own nested records, which the sync script flattens.
+const AggregatorFile = "aggregator-events.json"
+
+// AggregatorEvent is an event in the shape of the aggregator's
+// events.schema.json. Nullable schema fields are pointers so they marshal as
+// null.
+type AggregatorEvent struct {
+	ID            string            `json:"id"`
+	Title         string            `json:"title"`
+	EventType     string            `json:"event_type"`
+	Series        *string           `json:"series"`
+	Track         AggregatorTrack   `json:"track"`
+	Dates         AggregatorDates   `json:"dates"`
+	Times         AggregatorTimes   `json:"times"`
+	Classes       []string          `json:"classes"`
+	Fees          AggregatorFees    `json:"fees"`
+	Contact       AggregatorContact `json:"contact"`
+	Confidence    float64           `json:"confidence"`
+	UnclearFields []string          `json:"unclear_fields"`
+	Notes         *string           `json:"notes"`
+	Flyers        []AggregatorFlyer `json:"flyers"`
+	CreatedAt     string            `json:"created_at"`
+	UpdatedAt     string            `json:"updated_at"`
+}
+
+type AggregatorTrack struct {
+	ID    *string `json:"id"` // slug, e.g. texas-motorplex-tx
+	Name  string  `json:"name"`
+	City  *string `json:"city"`
+	State *string `json:"state"`
+}
+
+// AggregatorDates are local calendar days at the track, YYYY-MM-DD.
+type AggregatorDates struct {
+	Start string  `json:"start"`
+	End   *string `json:"end"`
+}
+
+// AggregatorTimes are local times at the track, 24-hour HH:MM.
+type AggregatorTimes struct {
+	GatesOpen         *string `json:"gates_open"`
+	RegistrationOpens *string `json:"registration_opens"`
+	RaceStart         *string `json:"race_start"`
+}
+
+// AggregatorFees are fees as display strings, e.g. "$50".
+type AggregatorFees struct {
+	Entry     *string `json:"entry"`
+	Spectator *string `json:"spectator"`
+}
+
+type AggregatorContact struct {
+	Phone   *string `json:"phone"`
+	Email   *string `json:"email"`
+	Website *string `json:"website"`
+}
+
+type AggregatorFlyer struct {
+	File        string  `json:"file"`
+	Phash       *string `json:"phash"`
+	ProcessedAt string  `json:"processed_at"`
+}
+
+// curatedConfidence is the confidence of events entered by hand, which
+// unlike the aggregator's are not extracted from flyers.
+const curatedConfidence = 1.0
+
+// eventTypeKeywords infer the schema's event_type from a title, tried in
+// order; titles matching none are "unknown".
+var eventTypeKeywords = []struct {
+	eventType string
+	keywords  []string
+}{
+	{"test_n_tune", []string{"test n tune", "test and tune", "test & tune", "tnt"}},
+	{"test_day", []string{"test day", "testing"}},
+	{"no_prep", []string{"no prep", "noprep"}},
+	{"grudge", []string{"grudge"}},
+	{"points_race", []string{"points", "race #", "round", "series", "championship"}},
+	{"bracket", []string{"bracket", "eliminator"}},
+	{"specialty", []string{"nationals", "shootout", "invitational", "showdown"}},
+}
+
+// EventType infers the aggregator event_type of an event from its title.
+func EventType(title string) string {
+	t := " " + strings.ToLower(title) + " "
+	for _, k := range eventTypeKeywords {
+		for _, kw := range k.keywords {
+			if strings.Contains(t, kw) {
+				return k.eventType
+			}
+		}
+	}
+	return "unknown"
+}
+
+// stateRe finds a two-letter state in an address, as in "Ennis, TX" or
+// "Ennis, TX 75119".
+var stateRe = regexp.MustCompile(`,\s*([A-Z]{2})(?:\s+\d{5}(?:-\d{4})?)?\s*$`)
+
+// TrackState returns the two-letter state at the end of a track's address,
+// or "" if it has none.
+func TrackState(address string) string {
+	if m := stateRe.FindStringSubmatch(strings.TrimSpace(address)); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// TrackSlug returns the aggregator's track ID for a track: its name and
+// state in lower case, joined by hyphens, e.g. "texas-motorplex-tx".
+func TrackSlug(name, state string) string {
+	return slugify(name + " " + state)
+}
+
+// trackSlug returns the track's slug: the aggregator's, if the track was
+// imported from it, or else the one TrackSlug derives.
+func trackSlug(t db.Track) string {
+	if t.Slug != "" {
+		return t.Slug
+	}
+	return TrackSlug(t.Name, TrackState(t.Address))
+}
+
+// checkTrackSlugs returns an error if two tracks share a slug, as their
+// per-track files would overwrite each other.
+func checkTrackSlugs(tracks []db.Track) error {
+	names := make(map[string]string, len(tracks))
+	for _, t := range tracks {
+		slug := trackSlug(t)
+		if other, ok := names[slug]; ok {
+			return fmt.Errorf("tracks %q and %q share the slug %q", other, t.Name, slug)
+		}
+		names[slug] = t.Name
+	}
+	return nil
+}
+
+// slugify reduces s to lower-case letters and digits joined by hyphens.
+func slugify(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// formatFee formats a fee as a display string: "$50" or "$12.50".
+func formatFee(v *float64) *string {
+	if v == nil {
+		return nil
+	}
+	s := "$" + strconv.FormatFloat(*v, 'f', 2, 64)
+	s = strings.TrimSuffix(s, ".00")
+	return &s
+}
+
+func optional(s string) *string {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	return &s
+}
+
+// ToAggregator converts an event to the aggregator shape. Dates and times
+// are local to the track; the start time is the race start unless the event
+// starts at midnight, which means no time was given. The schema has no
+// status, so an event that is not scheduled says so at the start of its
+// notes.
+func ToAggregator(ev db.Event, track db.Track) (AggregatorEvent, error) {
+	if ev.UUID == "" {
+		return AggregatorEvent{}, fmt.Errorf("event %d has no uuid; run 'db migrate'", ev.ID)
+	}
+	state := TrackState(track.Address)
+	out := AggregatorEvent{
+		ID:        ev.UUID,
+		Title:     ev.Title,
+		EventType: EventType(ev.Title),
+		Series:    optional(ev.Series),
+		Track: AggregatorTrack{
+			ID:    optional(trackSlug(track)),
+			Name:  track.Name,
+			City:  optional(track.City),
+			State: optional(state),
+		},
+		Dates:   AggregatorDates{Start: ev.StartDate.Format("2006-01-02")},
+		Classes: []string{},
+		Fees: AggregatorFees{
+			Entry:     formatFee(ev.DriverFee),
+			Spectator: formatFee(ev.SpectatorFee),
+		},
+		Contact:       AggregatorContact{Website: optional(ev.URL)},
+		Confidence:    curatedConfidence,
+		UnclearFields: []string{},
+		Notes:         aggregatorNotes(ev),
+		Flyers:        []AggregatorFlyer{},
+		CreatedAt:     ev.CreatedAt,
+		UpdatedAt:     ev.UpdatedAt,
+	}
+	if h, m, _ := ev.StartDate.Clock(); h != 0 || m != 0 {
+		start := ev.StartDate.Format("15:04")
+		out.Times.RaceStart = &start
+	}
+	if ev.EndDate != nil {
+		end := ev.EndDate.Format("2006-01-02")
+		out.Dates.End = &end
+	}
+	for _, c := range ev.Classes {
+		out.Classes = append(out.Classes, c.Name)
+	}
+	return out, nil
+}
+
+// aggregatorNotes is an event's description, led by its status unless it is
+// scheduled: "Cancelled. Rain date TBA".
+func aggregatorNotes(ev db.Event) *string {
+	label := statusLabel(ev)
+	if label == "" {
+		return optional(ev.Description)
+	}
+	if desc := strings.TrimSpace(ev.Description); desc != "" {
+		label += ". " + desc
+	}
+	return &label
+}
+
+// ToAggregatorEvents converts events to the aggregator shape.
+func ToAggregatorEvents(tracks []db.Track, events []db.Event) ([]AggregatorEvent, error) {
+	byID := make(map[int64]db.Track, len(tracks))
+	for _, t := range tracks {
+		byID[t.ID] = t
+	}
+	out := make([]AggregatorEvent, 0, len(events))
+	for _, ev := range events {
+		track, ok := byID[ev.TrackID]
+		if !ok {
+			return nil, fmt.Errorf("event %d: track %d not found", ev.ID, ev.TrackID)
+		}
+		a, err := ToAggregator(ev, track)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// Aggregator writes AggregatorFile in the aggregator schema to dataDir. With a
+// schema, the events are validated first and nothing is written if they
+// fail; the error is then a *ValidationError.
+func Aggregator(dataDir string, tracks []db.Track, events []db.Event, schema *Schema) (Result, error) {
+	var res Result
+	out, err := ToAggregatorEvents(tracks, events)
+	if err != nil {
+		return res, err
+	}
+	if schema != nil {
+		violations, err := schema.Validate(out)
+		if err != nil {
+			return res, err
+		}
+		if len(violations) > 0 {
+			return res, &ValidationError{File: AggregatorFile, Violations: violations}
+		}
+	}
+	if err := EnsureDir(dataDir); err != nil {
+		return res, err
+	}
+	if err := res.writeEvents(dataDir, AggregatorFile, out); err != nil {
+		return res, err
+	}
+	return res, nil
+}
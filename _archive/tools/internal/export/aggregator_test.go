@@ -0,0 +1,133 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+func TestEventType(t *testing.T) {
+	tests := map[string]string{
+		"Friday Night Test N Tune":   "test_n_tune",
+		"Outlaw No Prep Shootout":    "no_prep",
+		"Grudge Night":               "grudge",
+		"TMCCC Race #3 Little River": "points_race",
+		"Saturday Bracket Race":      "bracket",
+		"Fall Nationals":             "specialty",
+		"Cars and Coffee":            "unknown",
+	}
+	for title, want := range tests {
+		if got := EventType(title); got != want {
+			t.Errorf("EventType(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestTrackSlug(t *testing.T) {
+	if got := TrackState("7500 US-287, Ennis, TX"); got != "TX" {
+		t.Errorf("Expected TX, got %q", got)
+	}
+	if got := TrackState("1800 S Interstate 45, Ferris, TX 75125"); got != "TX" {
+		t.Errorf("Expected TX with a ZIP code, got %q", got)
+	}
+	if got := TrackState("123 Test St"); got != "" {
+		t.Errorf("Expected no state, got %q", got)
+	}
+	if got := TrackSlug("Texas Motorplex", "TX"); got != "texas-motorplex-tx" {
+		t.Errorf("Expected texas-motorplex-tx, got %q", got)
+	}
+	if got := TrackSlug("Xtreme Raceway Park (XRP)", ""); got != "xtreme-raceway-park-xrp" {
+		t.Errorf("Expected xtreme-raceway-park-xrp, got %q", got)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+	loc, _ := time.LoadLocation("America/Chicago")
+	start := time.Date(2025, 10, 3, 8, 0, 0, 0, loc)
+	end := time.Date(2025, 10, 12, 18, 0, 0, 0, loc)
+	fee := 50.0
+	tracks := []db.Track{{ID: 1, Name: "Texas Motorplex", City: "Ennis", Address: "7500 US-287, Ennis, TX"}}
+	events := []db.Event{{
+		ID: 1, Title: "Fall Nationals", TrackID: 1, StartDate: start, EndDate: &end,
+		DriverFee: &fee, URL: "https://texasmotorplex.com/events",
+		UUID:      "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e",
+		CreatedAt: "2025-09-01T12:00:00Z", UpdatedAt: "2025-09-02T12:00:00Z",
+		Classes: []db.EventClass{{Name: "Pro Street"}, {Name: "Super Pro"}},
+	}}
+
+	if _, err := Aggregator(dataDir, tracks, events, nil); err != nil {
+		t.Fatalf("Aggregator failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dataDir, AggregatorFile))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", AggregatorFile, err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("Invalid %s: %v", AggregatorFile, err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(got))
+	}
+	ev := got[0]
+	if ev["id"] != "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e" || ev["event_type"] != "specialty" {
+		t.Errorf("Unexpected id or event_type: %v, %v", ev["id"], ev["event_type"])
+	}
+	track := ev["track"].(map[string]any)
+	if track["id"] != "texas-motorplex-tx" || track["state"] != "TX" || track["city"] != "Ennis" {
+		t.Errorf("Unexpected track: %v", track)
+	}
+	dates := ev["dates"].(map[string]any)
+	if dates["start"] != "2025-10-03" || dates["end"] != "2025-10-12" {
+		t.Errorf("Unexpected dates: %v", dates)
+	}
+	if times := ev["times"].(map[string]any); times["race_start"] != "08:00" {
+		t.Errorf("Expected race_start 08:00, got %v", times["race_start"])
+	}
+	fees := ev["fees"].(map[string]any)
+	if fees["entry"] != "$50" || fees["spectator"] != nil {
+		t.Errorf("Unexpected fees: %v", fees)
+	}
+	if ev["notes"] != nil || ev["series"] != nil {
+		t.Errorf("Expected null notes and series, got %v and %v", ev["notes"], ev["series"])
+	}
+	if classes := ev["classes"].([]any); len(classes) != 2 || classes[0] != "Pro Street" {
+		t.Errorf("Unexpected classes: %v", classes)
+	}
+	if flyers, ok := ev["flyers"].([]any); !ok || len(flyers) != 0 {
+		t.Errorf("Expected an empty flyers list, got %v", ev["flyers"])
+	}
+}
+
+func TestAggregatorNotesCarryStatus(t *testing.T) {
+	tracks := []db.Track{{ID: 1, Name: "Test Track"}}
+	events := []db.Event{
+		{ID: 1, Title: "Rained Out", TrackID: 1, StartDate: time.Now(), UUID: "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e",
+			Status: db.StatusRainedOut, Description: "Rain date TBA"},
+		{ID: 2, Title: "Cancelled", TrackID: 1, StartDate: time.Now(), UUID: "1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f",
+			Status: db.StatusCancelled},
+	}
+	out, err := ToAggregatorEvents(tracks, events)
+	if err != nil {
+		t.Fatalf("ToAggregatorEvents failed: %v", err)
+	}
+	if out[0].Notes == nil || *out[0].Notes != "Rained out. Rain date TBA" {
+		t.Errorf("Expected the status ahead of the description, got %v", out[0].Notes)
+	}
+	if out[1].Notes == nil || *out[1].Notes != "Cancelled" {
+		t.Errorf("Expected the status as the notes, got %v", out[1].Notes)
+	}
+}
+
+func TestAggregatorRequiresUUID(t *testing.T) {
+	tracks := []db.Track{{ID: 1, Name: "Test Track"}}
+	events := []db.Event{{ID: 7, Title: "No UUID", TrackID: 1, StartDate: time.Now()}}
+	if _, err := ToAggregatorEvents(tracks, events); err == nil {
+		t.Error("Expected error for an event without a uuid")
+	}
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// SiteURL is the address the site is published at.
+const SiteURL = "https://dfw-dragevents.com"
+
+// FeedLimit is the default number of entries in the Atom feed.
+const FeedLimit = 50
+
+// atomTagAuthority is the tag URI (RFC 4151) authority that feed and entry
+// IDs are minted under.
+const atomTagAuthority = "tag:dfw-dragevents.com,2025:"
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID       string         `xml:"id"`
+	Title    string         `xml:"title"`
+	Updated  string         `xml:"updated"`
+	Link     atomLink       `xml:"link"`
+	Summary  string         `xml:"summary"`
+	Category []atomCategory `xml:"category,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// EventURL is the address of an event's pre-rendered page on the site, as
+// EventPages writes it.
+func EventURL(ev db.Event) string {
+	return EventPageURL(eventSlug(ev))
+}
+
+// Feed renders an Atom feed with an entry per change, newest first, as
+// returned by db.ListEventChanges. Changes to events not in events are
+// skipped. The feed's updated time is that of the newest change, so an
+// unchanged log renders the same bytes.
+func Feed(events []db.Event, changes []db.EventChange) ([]byte, error) {
+	byID := make(map[int64]db.Event, len(events))
+	for _, ev := range events {
+		byID[ev.ID] = ev
+	}
+
+	feed := atomFeed{
+		ID:    atomTagAuthority + "events",
+		Title: calendarName + ": new and changed events",
+		Links: []atomLink{
+			{Href: SiteURL + "/data/events.atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: SiteURL + "/events.html", Rel: "alternate", Type: "text/html"},
+		},
+		Author:  atomAuthor{Name: calendarName},
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+	}
+	for _, c := range changes {
+		ev, ok := byID[c.EventID]
+		if !ok {
+			continue
+		}
+		if len(feed.Entries) == 0 {
+			feed.Updated = c.ChangedAt
+		}
+		entry := atomEntry{
+			ID:      fmt.Sprintf("%sevent-changes/%d", atomTagAuthority, c.ID),
+			Title:   changeTitle(ev, c),
+			Updated: c.ChangedAt,
+			Link:    atomLink{Href: EventURL(ev), Rel: "alternate", Type: "text/html"},
+			Summary: changeSummary(ev, c),
+		}
+		if ev.Series != "" {
+			entry.Category = []atomCategory{{Term: ev.Series}}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+// changeTitle titles an entry "New: <event>" or "Updated: <event> (what changed)".
+func changeTitle(ev db.Event, c db.EventChange) string {
+	if c.Added() {
+		return "New: " + ev.Title
+	}
+	return fmt.Sprintf("Updated: %s (%s)", ev.Title, strings.Join(c.Summaries, ", "))
+}
+
+// changeSummary describes the change and the event as it is now, e.g.
+// "Date moved, fees changed. Fall Nationals at Texas Motorplex, Fri Oct 3,
+// 2025 8:00 AM to Sun Oct 12, 2025 6:00 PM."
+func changeSummary(ev db.Event, c db.EventChange) string {
+	what := "Added"
+	if !c.Added() {
+		what = strings.Join(c.Summaries, ", ")
+		what = strings.ToUpper(what[:1]) + what[1:]
+	}
+	when := ev.StartDate.Format("Mon Jan 2, 2006 3:04 PM")
+	if ev.EndDate != nil {
+		when += " to " + ev.EndDate.Format("Mon Jan 2, 2006 3:04 PM")
+	}
+	return fmt.Sprintf("%s. %s at %s, %s.", what, ev.Title, ev.TrackName, when)
+}
+
+// Atom writes the feed of recent changes to dataDir/events.atom.
+func Atom(dataDir string, events []db.Event, changes []db.EventChange) (Result, error) {
+	var res Result
+	b, err := Feed(events, changes)
+	if err != nil {
+		return res, err
+	}
+	if err := EnsureDir(dataDir); err != nil {
+		return res, err
+	}
+	if err := res.writeFile(dataDir, "events.atom", b); err != nil {
+		return res, err
+	}
+	return res, nil
+}
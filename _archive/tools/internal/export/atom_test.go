@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+func TestFeed(t *testing.T) {
+	_, events := icsTestData()
+	events[0].UUID = "0b7e3c52-1f0a-4c55-9d67-3b2a4c1e5f60"
+	events[0].TrackName = "Texas Motorplex"
+	changes := []db.EventChange{
+		{ID: 12, EventID: 7, ChangedAt: "2026-02-03T04:05:06Z", Summaries: []string{"date moved", "classes added"}},
+		{ID: 9, EventID: 99, ChangedAt: "2026-02-02T00:00:00Z", Summaries: []string{"added"}}, // deleted since
+		{ID: 3, EventID: 7, ChangedAt: "2026-01-02T03:04:05Z", Summaries: []string{"added"}},
+	}
+
+	b, err := Feed(events, changes)
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(b, &feed); err != nil {
+		t.Fatalf("Feed is not valid XML: %v\n%s", err, b)
+	}
+	if feed.Updated != "2026-02-03T04:05:06Z" {
+		t.Errorf("Expected the feed to be updated at the newest change, got %q", feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(feed.Entries))
+	}
+
+	changed := feed.Entries[0]
+	if changed.ID != "tag:dfw-dragevents.com,2025:event-changes/12" {
+		t.Errorf("Unexpected entry ID %q", changed.ID)
+	}
+	if changed.Title != "Updated: TMCCC Race #1 (date moved, classes added)" {
+		t.Errorf("Unexpected title %q", changed.Title)
+	}
+	if want := "https://dfw-dragevents.com/events/" + events[0].UUID + ".html"; changed.Link.Href != want {
+		t.Errorf("Expected link %q, got %q", want, changed.Link.Href)
+	}
+	if want := "Date moved, classes added. TMCCC Race #1 at Texas Motorplex, Sun Mar 22, 2026 9:00 AM to Sun Mar 22, 2026 11:00 PM."; changed.Summary != want {
+		t.Errorf("Expected summary %q, got %q", want, changed.Summary)
+	}
+	if len(changed.Category) != 1 || changed.Category[0].Term != "TMCCC" {
+		t.Errorf("Expected the series as a category, got %+v", changed.Category)
+	}
+	if feed.Entries[1].Title != "New: TMCCC Race #1" {
+		t.Errorf("Unexpected title %q", feed.Entries[1].Title)
+	}
+
+	again, _ := Feed(events, changes)
+	if string(again) != string(b) {
+		t.Error("Expected the same changes to render the same feed")
+	}
+}
+
+func TestFeedWithoutChanges(t *testing.T) {
+	b, err := Feed(nil, nil)
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	if !strings.Contains(string(b), "<updated>1970-01-01T00:00:00Z</updated>") {
+		t.Errorf("Expected a fixed updated time for an empty feed:\n%s", b)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	dir := t.TempDir()
+	res, err := Atom(dir, nil, nil)
+	if err != nil {
+		t.Fatalf("Atom failed: %v", err)
+	}
+	if len(res.Written) != 1 || res.Written[0] != "events.atom" {
+		t.Errorf("Expected events.atom to be written, got %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "events.atom")); err != nil {
+		t.Errorf("Expected events.atom: %v", err)
+	}
+	res, _ = Atom(dir, nil, nil)
+	if len(res.Written) != 0 || len(res.Unchanged) != 1 {
+		t.Errorf("Expected an unchanged feed to be skipped, got %+v", res)
+	}
+}
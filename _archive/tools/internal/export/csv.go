@@ -0,0 +1,180 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// Files written by CSV, in the order they must be imported.
+const (
+	EventsCSV           = "events.csv"
+	EventClassesCSV     = "event_classes.csv"
+	EventClassRulesCSV  = "event_class_rules.csv"
+	EventReschedulesCSV = "event_reschedules.csv"
+)
+
+// CSV writes events, with their classes and rules nested, to dir in the
+// formats the CSV importers read: events.csv with track, external_id, series
+// and status columns, and event_classes.csv, event_class_rules.csv and
+// event_reschedules.csv naming each event by title, start date and track.
+// Tracks are named rather than given by ID, so the files can be imported
+// into a database whose track and event IDs differ as long as it has tracks
+// of the same names. Rows are sorted by start date and ID, classes and rules
+// by ID.
+//
+// Events that share a track, title and start date, classes that share a
+// name within an event, or tracks whose names differ only in case cannot be
+// told apart by the importers and are an error.
+func CSV(dir string, tracks []db.Track, events []db.Event) (Result, error) {
+	var res Result
+	names, err := csvTrackNames(tracks, events)
+	if err != nil {
+		return res, err
+	}
+	events = append([]db.Event(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].StartDate.Equal(events[j].StartDate) {
+			return events[i].StartDate.Before(events[j].StartDate)
+		}
+		return events[i].ID < events[j].ID
+	})
+	if err := checkCSVKeys(events); err != nil {
+		return res, err
+	}
+
+	byID := make(map[int64]db.Event, len(events))
+	for _, ev := range events {
+		byID[ev.ID] = ev
+	}
+
+	eventRows := [][]string{{"title", "track", "start_date", "end_date", "driver_fee", "spectator_fee", "url", "description", "external_id", "series", "status"}}
+	classRows := [][]string{{"event_title", "event_start_date", "track", "name", "buyin_fee"}}
+	ruleRows := [][]string{{"event_title", "event_start_date", "track", "class_name", "rule"}}
+	rescheduleRows := [][]string{{"event_title", "event_start_date", "track", "replacement_title", "replacement_start_date", "replacement_track"}}
+	for _, ev := range events {
+		start := ev.StartDate.Format(db.DateLayout)
+		track := names[ev.TrackID]
+		end := ""
+		if ev.EndDate != nil {
+			end = ev.EndDate.Format(db.DateLayout)
+		}
+		eventRows = append(eventRows, []string{ev.Title, track, start, end,
+			csvFee(ev.DriverFee), csvFee(ev.SpectatorFee), ev.URL, ev.Description, ev.ExternalID, ev.Series, ev.Status})
+		if ev.RescheduledTo != nil {
+			r, ok := byID[*ev.RescheduledTo]
+			if !ok {
+				return res, fmt.Errorf("event %d: replacement event %d not found", ev.ID, *ev.RescheduledTo)
+			}
+			rescheduleRows = append(rescheduleRows, []string{ev.Title, start, track,
+				r.Title, r.StartDate.Format(db.DateLayout), names[r.TrackID]})
+		}
+
+		classes := append([]db.EventClass(nil), ev.Classes...)
+		sort.SliceStable(classes, func(i, j int) bool { return classes[i].ID < classes[j].ID })
+		for _, c := range classes {
+			classRows = append(classRows, []string{ev.Title, start, track, c.Name, csvFee(c.BuyinFee)})
+			rules := append([]db.EventClassRule(nil), c.Rules...)
+			sort.SliceStable(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+			for _, r := range rules {
+				ruleRows = append(ruleRows, []string{ev.Title, start, track, c.Name, r.Rule})
+			}
+		}
+	}
+
+	if err := EnsureDir(dir); err != nil {
+		return res, err
+	}
+	for _, f := range []struct {
+		name string
+		rows [][]string
+	}{
+		{EventsCSV, eventRows},
+		{EventClassesCSV, classRows},
+		{EventClassRulesCSV, ruleRows},
+		{EventReschedulesCSV, rescheduleRows},
+	} {
+		b, err := encodeCSV(f.rows)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", f.name, err)
+		}
+		if err := res.writeFile(dir, f.name, b); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// csvTrackNames maps the IDs of the tracks events run at to their names,
+// which must identify them in the database the files are imported into.
+func csvTrackNames(tracks []db.Track, events []db.Event) (map[int64]string, error) {
+	byID := make(map[int64]string, len(tracks))
+	byName := make(map[string]string, len(tracks))
+	for _, t := range tracks {
+		name := strings.TrimSpace(t.Name)
+		key := strings.ToLower(name)
+		if other, ok := byName[key]; ok {
+			return nil, fmt.Errorf("cannot export to CSV: tracks %q and %q have the same name", other, t.Name)
+		}
+		byName[key] = t.Name
+		byID[t.ID] = name
+	}
+	for _, ev := range events {
+		if _, ok := byID[ev.TrackID]; !ok {
+			return nil, fmt.Errorf("event %d: track %d not found", ev.ID, ev.TrackID)
+		}
+	}
+	return byID, nil
+}
+
+// checkCSVKeys reports events and classes the importers could not resolve
+// unambiguously by name.
+func checkCSVKeys(events []db.Event) error {
+	var dups []string
+	seen := make(map[string]int64)
+	for _, ev := range events {
+		key := fmt.Sprintf("%d|%s|%s", ev.TrackID, strings.ToLower(strings.TrimSpace(ev.Title)), ev.StartDate.Format(db.DateLayout))
+		if id, ok := seen[key]; ok {
+			dups = append(dups, fmt.Sprintf("events %d and %d have the same track, title and start date", id, ev.ID))
+		}
+		seen[key] = ev.ID
+		classes := make(map[string]int64)
+		for _, c := range ev.Classes {
+			name := strings.ToLower(strings.TrimSpace(c.Name))
+			if id, ok := classes[name]; ok {
+				dups = append(dups, fmt.Sprintf("classes %d and %d of event %d have the same name", id, c.ID, ev.ID))
+			}
+			classes[name] = c.ID
+		}
+	}
+	if len(dups) > 0 {
+		return fmt.Errorf("cannot export to CSV:\n  %s", strings.Join(dups, "\n  "))
+	}
+	return nil
+}
+
+// csvFee formats a fee the way the templates write it (50.0), or "" for none.
+func csvFee(fee *float64) string {
+	if fee == nil {
+		return ""
+	}
+	s := strconv.FormatFloat(*fee, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+func encodeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,190 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// csvTestTracks are the tracks of the CSV test databases.
+var csvTestTracks = []struct{ name, zone string }{
+	{"Texas Motorplex", "America/Chicago"},
+	{"Bandimere Speedway", "America/Denver"},
+}
+
+// openCSVTestDB opens a migrated database with csvTestTracks, created in
+// reverse order if reversed, so their IDs differ from the other order's.
+func openCSVTestDB(t *testing.T, name string, reversed bool) *sql.DB {
+	t.Helper()
+	dbx, err := db.Open(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { dbx.Close() })
+	if err := db.Migrate(dbx); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	tracks := append(csvTestTracks[:0:0], csvTestTracks...)
+	if reversed {
+		slices.Reverse(tracks)
+	}
+	for _, tr := range tracks {
+		if _, err := db.CreateTrackInZone(dbx, tr.name, "", "", "", tr.zone); err != nil {
+			t.Fatalf("CreateTrackInZone failed: %v", err)
+		}
+	}
+	return dbx
+}
+
+// loadCSVTestEvents lists tracks, and events with their classes and rules
+// nested.
+func loadCSVTestEvents(t *testing.T, dbx *sql.DB) ([]db.Track, []db.Event) {
+	t.Helper()
+	tracks, err := db.ListTracks(dbx)
+	if err != nil {
+		t.Fatalf("ListTracks failed: %v", err)
+	}
+	events, err := db.ListEvents(dbx)
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	classes, _ := db.ListEventClasses(dbx)
+	rules, _ := db.ListEventClassRules(dbx)
+	for i := range classes {
+		for _, r := range rules {
+			if r.EventClassID == classes[i].ID {
+				classes[i].Rules = append(classes[i].Rules, r)
+			}
+		}
+	}
+	for i := range events {
+		for _, c := range classes {
+			if c.EventID == events[i].ID {
+				events[i].Classes = append(events[i].Classes, c)
+			}
+		}
+	}
+	return tracks, events
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	src := openCSVTestDB(t, "src.db", false)
+	fee := func(v float64) *float64 { return &v }
+	// the deleted event leaves a gap, so event IDs differ after the round
+	// trip; the destination's tracks are created in the other order
+	gone, _ := db.CreateEvent(src, "Cancelled", 1, "2026-01-10 09:00:00", "", nil, nil, "", "")
+	first, _ := db.CreateEvent(src, "Spring Nationals", 1, "2026-03-20 08:00:00", "2026-03-22 18:00:00", fee(50), fee(20.5), "https://texasmotorplex.com", `NHRA "national" event, with commas`)
+	second, _ := db.CreateEvent(src, "Mile High Nationals", 2, "2026-07-17 08:00:00", "", nil, nil, "", "Line one\nLine two")
+	if err := db.DeleteEvent(src, gone); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	external, series := "nhra-2026-01", "NHRA"
+	if _, err := src.Exec(`UPDATE events SET external_id = ?, series = ? WHERE id = ?`, external, series, first); err != nil {
+		t.Fatalf("Failed to set external ID and series: %v", err)
+	}
+	for _, c := range []struct {
+		event int64
+		name  string
+		fee   any
+		rules []string
+	}{
+		{first, "Pro Street", 100.0, []string{"DOT tires only", `Maximum 10.5" tire width`}},
+		{first, "Street", nil, nil},
+		{second, "Pro Street", 75.0, []string{"Helmet required"}},
+	} {
+		r, err := src.Exec(`INSERT INTO event_classes(event_id, name, buyin_fee) VALUES(?, ?, ?)`, c.event, c.name, c.fee)
+		if err != nil {
+			t.Fatalf("Failed to add class: %v", err)
+		}
+		classID, _ := r.LastInsertId()
+		for _, rule := range c.rules {
+			if _, err := src.Exec(`INSERT INTO event_class_rules(event_class_id, rule) VALUES(?, ?)`, classID, rule); err != nil {
+				t.Fatalf("Failed to add rule: %v", err)
+			}
+		}
+	}
+
+	// the status and the link to the replacement survive as well
+	if err := db.RescheduleEvent(src, first, second); err != nil {
+		t.Fatalf("RescheduleEvent failed: %v", err)
+	}
+
+	exported := t.TempDir()
+	srcTracks, events := loadCSVTestEvents(t, src)
+	if _, err := CSV(exported, srcTracks, events); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+
+	dst := openCSVTestDB(t, "dst.db", true)
+	if _, err := db.ImportEventsFromCSV(dst, filepath.Join(exported, EventsCSV)); err != nil {
+		t.Fatalf("Import events failed: %v", err)
+	}
+	if _, err := db.ImportEventClassesFromCSV(dst, filepath.Join(exported, EventClassesCSV)); err != nil {
+		t.Fatalf("Import classes failed: %v", err)
+	}
+	if _, err := db.ImportEventClassRulesFromCSV(dst, filepath.Join(exported, EventClassRulesCSV)); err != nil {
+		t.Fatalf("Import rules failed: %v", err)
+	}
+	if _, err := db.ImportEventReschedulesFromCSV(dst, filepath.Join(exported, EventReschedulesCSV)); err != nil {
+		t.Fatalf("Import reschedules failed: %v", err)
+	}
+
+	dstTracks, restored := loadCSVTestEvents(t, dst)
+	if len(restored) != 2 || restored[0].ID == first {
+		t.Fatalf("Expected 2 events with new IDs, got %+v", restored)
+	}
+	if restored[0].TrackName != "Texas Motorplex" || restored[0].TrackID == 1 || restored[1].TrackName != "Bandimere Speedway" {
+		t.Errorf("Expected the events at the same tracks under new IDs, got %+v", restored)
+	}
+	if ev := restored[0]; ev.ExternalID != external || ev.Series != series || ev.Description != `NHRA "national" event, with commas` {
+		t.Errorf("Expected the event's fields to survive, got %+v", ev)
+	}
+	if ev := restored[1]; ev.StartUTC != "2026-07-17T14:00:00Z" || ev.Description != "Line one\nLine two" {
+		t.Errorf("Expected the Denver event at 8 AM local time, got %+v", ev)
+	}
+	if ev := restored[0]; ev.Status != db.StatusPostponed || ev.RescheduledTo == nil || *ev.RescheduledTo != restored[1].ID {
+		t.Errorf("Expected the event to be postponed to the Denver event, got %+v", ev)
+	}
+
+	again := t.TempDir()
+	if _, err := CSV(again, dstTracks, restored); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+	for _, name := range []string{EventsCSV, EventClassesCSV, EventClassRulesCSV, EventReschedulesCSV} {
+		a, _ := os.ReadFile(filepath.Join(exported, name))
+		b, _ := os.ReadFile(filepath.Join(again, name))
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s differs after the round trip:\n%s\nvs\n%s", name, a, b)
+		}
+	}
+	rules, _ := os.ReadFile(filepath.Join(exported, EventClassRulesCSV))
+	if got := strings.Count(string(rules), "\n"); got != 4 {
+		t.Errorf("Expected a header and 3 rules, got %d lines:\n%s", got, rules)
+	}
+}
+
+func TestCSVRejectsAmbiguousEvents(t *testing.T) {
+	src := openCSVTestDB(t, "src.db", false)
+	db.CreateEvent(src, "Test and Tune", 1, "2026-03-20 18:00:00", "", nil, nil, "", "")
+	db.CreateEvent(src, "Test and Tune", 1, "2026-03-20 18:00:00", "", nil, nil, "", "")
+
+	tracks, events := loadCSVTestEvents(t, src)
+	_, err := CSV(t.TempDir(), tracks, events)
+	if err == nil || !strings.Contains(err.Error(), "same track, title and start date") {
+		t.Errorf("Expected duplicate events to be rejected, got %v", err)
+	}
+}
+
+func TestCSVRejectsTracksWithTheSameName(t *testing.T) {
+	tracks := []db.Track{{ID: 1, Name: "Texas Motorplex"}, {ID: 2, Name: "texas motorplex"}}
+	_, err := CSV(t.TempDir(), tracks, nil)
+	if err == nil || !strings.Contains(err.Error(), "same name") {
+		t.Errorf("Expected tracks with the same name to be rejected, got %v", err)
+	}
+}
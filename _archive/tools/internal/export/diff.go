@@ -0,0 +1,126 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EventRef names an event in an exported events file.
+type EventRef struct {
+	ID    string
+	Title string
+}
+
+func (r EventRef) String() string {
+	return fmt.Sprintf("%s [%s]", r.Title, r.ID)
+}
+
+// EventChange is an event present before and after an export whose
+// exported fields differ.
+type EventChange struct {
+	EventRef
+	Fields []string
+}
+
+// EventDiff compares the events in an exported file before and after an
+// export. Events are matched by their "id" field.
+type EventDiff struct {
+	Added   []EventRef
+	Removed []EventRef
+	Changed []EventChange
+}
+
+// Empty reports whether the diff has no added, removed or changed events.
+func (d EventDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String summarizes the diff for people, one line per event.
+func (d EventDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed", len(d.Added), len(d.Removed), len(d.Changed))
+	for _, r := range d.Added {
+		fmt.Fprintf(&b, "\n  + %s", r)
+	}
+	for _, r := range d.Removed {
+		fmt.Fprintf(&b, "\n  - %s", r)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "\n  ~ %s: %s", c.EventRef, strings.Join(c.Fields, ", "))
+	}
+	return b.String()
+}
+
+// DiffEvents compares two events files, each a JSON array of event objects.
+// An old file that is empty or unreadable, such as one truncated by an
+// interrupted export, counts as having no events.
+func DiffEvents(old, new []byte) (EventDiff, error) {
+	var d EventDiff
+	before, _ := decodeEvents(old)
+	after, err := decodeEvents(new)
+	if err != nil {
+		return d, err
+	}
+
+	byID := make(map[string]map[string]any, len(before))
+	for _, ev := range before {
+		byID[eventRef(ev).ID] = ev
+	}
+	seen := make(map[string]bool, len(after))
+	for _, ev := range after {
+		ref := eventRef(ev)
+		seen[ref.ID] = true
+		prev, ok := byID[ref.ID]
+		if !ok {
+			d.Added = append(d.Added, ref)
+			continue
+		}
+		if fields := changedFields(prev, ev); len(fields) > 0 {
+			d.Changed = append(d.Changed, EventChange{EventRef: ref, Fields: fields})
+		}
+	}
+	for _, ev := range before {
+		if ref := eventRef(ev); !seen[ref.ID] {
+			d.Removed = append(d.Removed, ref)
+		}
+	}
+	return d, nil
+}
+
+func decodeEvents(b []byte) ([]map[string]any, error) {
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return nil, nil
+	}
+	var events []map[string]any
+	if err := json.Unmarshal(b, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// eventRef reads the id and title of a decoded event. IDs are compared as
+// text, so integer IDs of the flat export and UUIDs both work.
+func eventRef(ev map[string]any) EventRef {
+	title, _ := ev["title"].(string)
+	return EventRef{ID: fmt.Sprint(ev["id"]), Title: title}
+}
+
+// changedFields lists the top-level fields that differ between a and b.
+func changedFields(a, b map[string]any) []string {
+	var fields []string
+	for k, v := range b {
+		if !reflect.DeepEqual(a[k], v) {
+			fields = append(fields, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
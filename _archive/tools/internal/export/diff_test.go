@@ -0,0 +1,55 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffEvents(t *testing.T) {
+	old := []byte(`[
+		{"id": 1, "title": "Kept", "url": "a"},
+		{"id": 2, "title": "Changed", "url": "a", "description": "old"},
+		{"id": 3, "title": "Removed"}
+	]`)
+	new := []byte(`[
+		{"id": 1, "title": "Kept", "url": "a"},
+		{"id": 2, "title": "Changed", "url": "b"},
+		{"id": 4, "title": "Added"}
+	]`)
+
+	d, err := DiffEvents(old, new)
+	if err != nil {
+		t.Fatalf("DiffEvents failed: %v", err)
+	}
+	if len(d.Added) != 1 || d.Added[0].ID != "4" {
+		t.Errorf("Expected event 4 added, got %v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Title != "Removed" {
+		t.Errorf("Expected event 3 removed, got %v", d.Removed)
+	}
+	if len(d.Changed) != 1 || strings.Join(d.Changed[0].Fields, ",") != "description,url" {
+		t.Errorf("Expected description and url changed on event 2, got %v", d.Changed)
+	}
+
+	want := "1 added, 1 removed, 1 changed\n  + Added [4]\n  - Removed [3]\n  ~ Changed [2]: description, url"
+	if d.String() != want {
+		t.Errorf("Unexpected summary:\n%s\nwant:\n%s", d, want)
+	}
+}
+
+func TestDiffEventsWithoutExistingFile(t *testing.T) {
+	d, err := DiffEvents(nil, []byte(`[{"id": "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e", "title": "New"}]`))
+	if err != nil {
+		t.Fatalf("DiffEvents failed: %v", err)
+	}
+	if len(d.Added) != 1 || d.Empty() {
+		t.Errorf("Expected one added event, got %v", d)
+	}
+	if d, _ := DiffEvents([]byte("[]"), []byte("[]")); !d.Empty() {
+		t.Errorf("Expected an empty diff, got %v", d)
+	}
+	d, err = DiffEvents([]byte(`[{"id": 1, "ti`), []byte(`[{"id": 1, "title": "New"}]`))
+	if err != nil || len(d.Added) != 1 {
+		t.Errorf("Expected a truncated file to count as empty, got %v, %v", d, err)
+	}
+}
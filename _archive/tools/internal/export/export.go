@@ -1,8 +1,11 @@
 package export
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -13,27 +16,131 @@ type ExportPaths struct {
 	DataDir string // e.g. ../site/data
 }
 
+// Result reports what an export did: the files it wrote, the files it left
+// alone because their content had not changed, the stale files it removed,
+// and for events.json how the events differ from the ones that were on disk.
+type Result struct {
+	Written   []string
+	Unchanged []string
+	Removed   []string
+	Events    *EventDiff // nil unless events.json was exported
+}
+
 func EnsureDir(dir string) error {
 	return os.MkdirAll(dir, 0o755)
 }
 
-func WriteJSON(path string, v any) error {
+func marshalJSON(v any) ([]byte, error) {
 	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(path, append(b, '\n'), 0o644)
+	return append(b, '\n'), nil
 }
 
-func All(dataDir string, tracks []db.Track, events []db.Event) error {
-	if err := EnsureDir(dataDir); err != nil {
+// WriteJSON writes v to path as indented JSON; see WriteFile.
+func WriteJSON(path string, v any) error {
+	b, err := marshalJSON(v)
+	if err != nil {
 		return err
 	}
-	if err := WriteJSON(filepath.Join(dataDir, "tracks.json"), tracks); err != nil {
-		return fmt.Errorf("tracks.json: %w", err)
+	_, err = WriteFile(path, b)
+	return err
+}
+
+// WriteFile replaces path with data atomically: it writes a temporary file
+// in the same directory and renames it into place, so a reader sees either
+// the old file or the new one, never a partial write. A file that already
+// holds data is left alone; changed reports whether it was written.
+func WriteFile(path string, data []byte) (changed bool, err error) {
+	old, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
 	}
-	if err := WriteJSON(filepath.Join(dataDir, "events.json"), events); err != nil {
-		return fmt.Errorf("events.json: %w", err)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeJSON writes v to dir/name and records the outcome in r.
+func (r *Result) writeJSON(dir, name string, v any) error {
+	b, err := marshalJSON(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return r.writeFile(dir, name, b)
+}
+
+func (r *Result) writeFile(dir, name string, data []byte) error {
+	changed, err := WriteFile(filepath.Join(dir, name), data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if changed {
+		r.Written = append(r.Written, name)
+	} else {
+		r.Unchanged = append(r.Unchanged, name)
 	}
 	return nil
 }
+
+// writeEvents writes events to dir/name like writeJSON, first recording in
+// r.Events how they differ from the events already in the file.
+func (r *Result) writeEvents(dir, name string, events any) error {
+	b, err := marshalJSON(events)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	old, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	diff, err := DiffEvents(old, b)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	r.Events = &diff
+	return r.writeFile(dir, name, b)
+}
+
+func All(dataDir string, tracks []db.Track, events []db.Event) (Result, error) {
+	var res Result
+	if err := EnsureDir(dataDir); err != nil {
+		return res, err
+	}
+	if err := res.writeJSON(dataDir, "tracks.json", tracks); err != nil {
+		return res, err
+	}
+	if err := res.writeEvents(dataDir, "events.json", events); err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -88,7 +88,7 @@ func TestAll(t *testing.T) {
 		},
 	}
 
-	err := All(dataDir, tracks, events)
+	_, err := All(dataDir, tracks, events)
 	if err != nil {
 		t.Fatalf("All failed: %v", err)
 	}
@@ -143,7 +143,7 @@ func TestAllWithEmptyData(t *testing.T) {
 	tmpDir := t.TempDir()
 	dataDir := filepath.Join(tmpDir, "data")
 
-	err := All(dataDir, []db.Track{}, []db.Event{})
+	_, err := All(dataDir, []db.Track{}, []db.Event{})
 	if err != nil {
 		t.Fatalf("All with empty data failed: %v", err)
 	}
@@ -224,7 +224,7 @@ func TestAllWithMultipleItems(t *testing.T) {
 		{ID: 2, Title: "Event 2", TrackID: 2, TrackName: "Track 2", StartDate: now, URL: "https://event2.com", Description: "Desc 2"},
 	}
 
-	err := All(dataDir, tracks, events)
+	_, err := All(dataDir, tracks, events)
 	if err != nil {
 		t.Fatalf("All with multiple items failed: %v", err)
 	}
@@ -310,7 +310,7 @@ func TestAllWithTracksError(t *testing.T) {
 	}
 	events := []db.Event{}
 
-	err := All(dataDir, tracks, events)
+	_, err := All(dataDir, tracks, events)
 	if err == nil {
 		t.Error("Expected error when tracks.json is a directory, got nil")
 	}
@@ -341,7 +341,7 @@ func TestAllWithEventsError(t *testing.T) {
 		{ID: 1, Title: "Test Event", TrackID: 1, TrackName: "Test Track", StartDate: time.Now()},
 	}
 
-	err := All(dataDir, tracks, events)
+	_, err := All(dataDir, tracks, events)
 	if err == nil {
 		t.Error("Expected error when events.json is a directory, got nil")
 	}
@@ -351,3 +351,69 @@ func TestAllWithEventsError(t *testing.T) {
 		t.Errorf("Expected error message to contain 'events.json', got: %v", err)
 	}
 }
+
+func TestWriteFileSkipsUnchangedContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "events.json")
+
+	changed, err := WriteFile(path, []byte("[]\n"))
+	if err != nil || !changed {
+		t.Fatalf("Expected first write to change the file, got %v, %v", changed, err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+
+	changed, err = WriteFile(path, []byte("[]\n"))
+	if err != nil || changed {
+		t.Fatalf("Expected identical content to be skipped, got %v, %v", changed, err)
+	}
+	info, _ := os.Stat(path)
+	if !info.ModTime().Equal(old) {
+		t.Error("Expected the unchanged file not to be rewritten")
+	}
+
+	if changed, err = WriteFile(path, []byte("[1]\n")); err != nil || !changed {
+		t.Fatalf("Expected new content to be written, got %v, %v", changed, err)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "[1]\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	// the temporary file is renamed into place, not left behind
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only events.json in the directory, got %d entries", len(entries))
+	}
+}
+
+func TestAllReportsUnchangedFiles(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+	tracks := []db.Track{{ID: 1, Name: "Test Track"}}
+	events := []db.Event{{ID: 1, Title: "Test Event", TrackID: 1, StartDate: time.Date(2026, 3, 22, 9, 0, 0, 0, time.UTC)}}
+
+	res, err := All(dataDir, tracks, events)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(res.Written) != 2 || len(res.Events.Added) != 1 {
+		t.Errorf("Expected both files written and one event added, got %+v", res)
+	}
+
+	events[0].Title = "Renamed Event"
+	res, err = All(dataDir, tracks, events)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(res.Unchanged) != 1 || res.Unchanged[0] != "tracks.json" {
+		t.Errorf("Expected tracks.json to be unchanged, got %v", res.Unchanged)
+	}
+	if len(res.Written) != 1 || res.Written[0] != "events.json" {
+		t.Errorf("Expected only events.json to be written, got %v", res.Written)
+	}
+	if len(res.Events.Changed) != 1 || res.Events.Changed[0].Fields[0] != "title" {
+		t.Errorf("Expected the title change, got %+v", res.Events)
+	}
+}
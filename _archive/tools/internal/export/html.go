@@ -0,0 +1,368 @@
+package export
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// EventPageDir is the directory, within the site, that EventPages writes
+// its pages to.
+const EventPageDir = "events"
+
+// bannerImage is the picture link previews show for every page.
+const bannerImage = SiteURL + "/assets/images/banner.jpg"
+
+//go:embed templates/event.html
+var templateFS embed.FS
+
+var eventPageTemplate = template.Must(template.ParseFS(templateFS, "templates/event.html"))
+
+// eventPage is the data the event page template renders.
+type eventPage struct {
+	Event          db.Event
+	Track          db.Track
+	URL            string // canonical address of the page
+	Image          string
+	Description    string // for search results and link previews
+	Location       string
+	Dates          string
+	Fees           string
+	Website        string // the event's own site, if it is http(s)
+	Status         string // e.g. "Cancelled", empty for scheduled events
+	Replacement    string // page of the event that replaces this one
+	Classes        []pageClass
+	StructuredData template.JS
+}
+
+type pageClass struct {
+	Name  string
+	BuyIn string
+	Rules []string
+}
+
+// sportsEvent is the JSON-LD (https://schema.org/SportsEvent) of an event
+// page, matching what event.html builds in the browser.
+type sportsEvent struct {
+	Context             string  `json:"@context"`
+	Type                string  `json:"@type"`
+	Name                string  `json:"name"`
+	Description         string  `json:"description"`
+	StartDate           string  `json:"startDate"`
+	EndDate             string  `json:"endDate"`
+	EventStatus         string  `json:"eventStatus"`
+	EventAttendanceMode string  `json:"eventAttendanceMode"`
+	Location            place   `json:"location"`
+	Offers              []offer `json:"offers,omitempty"`
+	URL                 string  `json:"url,omitempty"`
+}
+
+type place struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Address postalAddress `json:"address"`
+}
+
+type postalAddress struct {
+	Type            string `json:"@type"`
+	StreetAddress   string `json:"streetAddress,omitempty"`
+	AddressLocality string `json:"addressLocality,omitempty"`
+	AddressRegion   string `json:"addressRegion"`
+	AddressCountry  string `json:"addressCountry"`
+}
+
+type offer struct {
+	Type          string  `json:"@type"`
+	Name          string  `json:"name"`
+	Price         float64 `json:"price"`
+	PriceCurrency string  `json:"priceCurrency"`
+}
+
+// EventPages pre-renders a page per event to siteDir/events/<uuid>.html,
+// so search engines and link previews see the event without running the
+// site's JavaScript, and adds the pages to siteDir/sitemap.xml. Pages of
+// events that no longer exist are removed. Events must have their classes
+// and rules nested.
+func EventPages(siteDir string, tracks []db.Track, events []db.Event) (Result, error) {
+	var res Result
+	dir := filepath.Join(siteDir, EventPageDir)
+	if err := EnsureDir(dir); err != nil {
+		return res, err
+	}
+
+	byID := make(map[int64]db.Track, len(tracks))
+	for _, t := range tracks {
+		byID[t.ID] = t
+	}
+	slugs := make(map[int64]string, len(events))
+	for _, ev := range events {
+		if ev.UUID == "" {
+			return res, fmt.Errorf("event %d has no uuid; run 'db migrate'", ev.ID)
+		}
+		slugs[ev.ID] = eventSlug(ev)
+	}
+	pages := make(map[string][]byte, len(events))
+	var urls []sitemapURL
+	for _, ev := range events {
+		track, ok := byID[ev.TrackID]
+		if !ok {
+			return res, fmt.Errorf("event %d: track %d not found", ev.ID, ev.TrackID)
+		}
+		name := slugs[ev.ID] + ".html"
+		var replacement string
+		if ev.RescheduledTo != nil {
+			if slug, ok := slugs[*ev.RescheduledTo]; ok {
+				replacement = EventPageURL(slug)
+			}
+		}
+		b, err := renderEventPage(ev, track, EventPageURL(slugs[ev.ID]), replacement)
+		if err != nil {
+			return res, fmt.Errorf("event %d: %w", ev.ID, err)
+		}
+		if err := res.writeFile(siteDir, filepath.Join(EventPageDir, name), b); err != nil {
+			return res, err
+		}
+		pages[name] = b
+		u := sitemapURL{Loc: EventPageURL(slugs[ev.ID]), ChangeFreq: "weekly", Priority: "0.7"}
+		if t, err := time.Parse(time.RFC3339, ev.UpdatedAt); err == nil {
+			u.LastMod = t.Format("2006-01-02")
+		}
+		urls = append(urls, u)
+	}
+	if err := res.removeStale(siteDir, EventPageDir, ".html", pages); err != nil {
+		return res, err
+	}
+
+	sitemap, err := updateSitemap(filepath.Join(siteDir, "sitemap.xml"), urls)
+	if err != nil {
+		return res, err
+	}
+	if err := res.writeFile(siteDir, "sitemap.xml", sitemap); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// EventPageURL is the address of the pre-rendered page with the given slug.
+func EventPageURL(slug string) string {
+	return SiteURL + "/" + EventPageDir + "/" + slug + ".html"
+}
+
+// eventSlug names an event's page after its UUID, which unlike its title
+// and dates never changes, so links to the page keep working.
+func eventSlug(ev db.Event) string {
+	return strings.ToLower(ev.UUID)
+}
+
+func renderEventPage(ev db.Event, track db.Track, url, replacement string) ([]byte, error) {
+	dates := formatDateRange(ev)
+	description := ev.Description
+	if description == "" {
+		description = "Drag racing event in Dallas-Fort Worth."
+	}
+	status := statusLabel(ev)
+	page := eventPage{
+		Event:       ev,
+		Track:       track,
+		URL:         url,
+		Image:       bannerImage,
+		Description: fmt.Sprintf("%s at %s - %s. %s", ev.Title, track.Name, dates, description),
+		Location:    eventLocation(track),
+		Dates:       dates,
+		Fees:        formatFees(ev),
+		Website:     webURL(ev.URL),
+		Status:      status,
+		Replacement: replacement,
+	}
+	if status != "" {
+		page.Description = status + ": " + page.Description
+	}
+	for _, c := range ev.Classes {
+		pc := pageClass{Name: c.Name}
+		if fee := formatFee(c.BuyinFee); fee != nil {
+			pc.BuyIn = *fee
+		}
+		for _, r := range c.Rules {
+			pc.Rules = append(pc.Rules, r.Rule)
+		}
+		page.Classes = append(page.Classes, pc)
+	}
+
+	ld, err := json.MarshalIndent(structuredData(ev, track), "  ", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// json escapes <, > and &, so the data cannot close the script element
+	page.StructuredData = template.JS("  " + string(ld))
+
+	var buf bytes.Buffer
+	if err := eventPageTemplate.Execute(&buf, page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func structuredData(ev db.Event, track db.Track) sportsEvent {
+	sd := sportsEvent{
+		Context:             "https://schema.org",
+		Type:                "SportsEvent",
+		Name:                ev.Title,
+		Description:         ev.Description,
+		StartDate:           ev.StartDate.Format(time.RFC3339),
+		EndDate:             ev.StartDate.Format(time.RFC3339),
+		EventStatus:         schemaEventStatus(ev),
+		EventAttendanceMode: "https://schema.org/OfflineEventAttendanceMode",
+		Location: place{
+			Type: "Place",
+			Name: track.Name,
+			Address: postalAddress{
+				Type:            "PostalAddress",
+				StreetAddress:   track.Address,
+				AddressLocality: track.City,
+				AddressRegion:   TrackState(track.Address),
+				AddressCountry:  "US",
+			},
+		},
+		URL: webURL(ev.URL),
+	}
+	if sd.Description == "" {
+		sd.Description = "Drag racing event at " + track.Name
+	}
+	if ev.EndDate != nil {
+		sd.EndDate = ev.EndDate.Format(time.RFC3339)
+	}
+	if sd.Location.Address.AddressRegion == "" {
+		sd.Location.Address.AddressRegion = "TX" // every listed track is in Texas
+	}
+	for _, f := range []struct {
+		name string
+		fee  *float64
+	}{
+		{"Driver Entry", ev.DriverFee},
+		{"Spectator Entry", ev.SpectatorFee},
+	} {
+		if f.fee != nil && *f.fee > 0 {
+			sd.Offers = append(sd.Offers, offer{Type: "Offer", Name: f.name, Price: *f.fee, PriceCurrency: "USD"})
+		}
+	}
+	return sd
+}
+
+// webURL returns u if it is an http or https URL, otherwise "".
+func webURL(u string) string {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	if strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") {
+		return u
+	}
+	return ""
+}
+
+// formatDateRange formats an event's dates as the site does: "Fri, Mar 20,
+// 2026", or "Mar 20 - Mar 22, 2026" for a multi-day event, with the start
+// time unless the event has none.
+func formatDateRange(ev db.Event) string {
+	s := ev.StartDate.Format("Mon, Jan 2, 2006")
+	if ev.EndDate != nil && ev.EndDate.Format("2006-01-02") != ev.StartDate.Format("2006-01-02") {
+		s = ev.StartDate.Format("Jan 2") + " - " + ev.EndDate.Format("Jan 2, 2006")
+	}
+	if !allDay(ev) {
+		s += ", starts " + ev.StartDate.Format("3:04 PM")
+	}
+	return s
+}
+
+// formatFees lists an event's fees as the site does: "Driver: $50 |
+// Spectator: $20".
+func formatFees(ev db.Event) string {
+	var fees []string
+	if fee := formatFee(ev.DriverFee); fee != nil {
+		fees = append(fees, "Driver: "+*fee)
+	}
+	if fee := formatFee(ev.SpectatorFee); fee != nil {
+		fees = append(fees, "Spectator: "+*fee)
+	}
+	if len(fees) == 0 {
+		return "Contact track for pricing"
+	}
+	return strings.Join(fees, " | ")
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+// updateSitemap returns the sitemap at path with its event page entries
+// replaced by pages. Other entries, such as the hand-written ones for the
+// site's own pages, are kept as they are.
+func updateSitemap(path string, pages []sitemapURL) ([]byte, error) {
+	var set sitemapURLSet
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := xml.Unmarshal(b, &set); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+
+	prefix := SiteURL + "/" + EventPageDir + "/"
+	urls := set.URLs[:0]
+	for _, u := range set.URLs {
+		if !strings.HasPrefix(u.Loc, prefix) {
+			urls = append(urls, u)
+		}
+	}
+	set.URLs = append(urls, pages...)
+
+	out, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// schemaEventStatus is the schema.org eventStatus of an event. A postponed
+// event with a replacement is EventRescheduled.
+func schemaEventStatus(ev db.Event) string {
+	switch ev.Status {
+	case db.StatusCancelled, db.StatusRainedOut:
+		return "https://schema.org/EventCancelled"
+	case db.StatusPostponed:
+		if ev.RescheduledTo != nil {
+			return "https://schema.org/EventRescheduled"
+		}
+		return "https://schema.org/EventPostponed"
+	}
+	return "https://schema.org/EventScheduled"
+}
+
+// statusLabel is an event's status for display, e.g. "Rained out", or empty
+// if it is scheduled.
+func statusLabel(ev db.Event) string {
+	if ev.Status == "" || ev.Status == db.StatusScheduled {
+		return ""
+	}
+	label := db.StatusLabel(ev.Status)
+	return strings.ToUpper(label[:1]) + label[1:]
+}
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+const testSitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://dfw-dragevents.com/</loc>
+    <lastmod>2025-11-16</lastmod>
+    <changefreq>weekly</changefreq>
+    <priority>1.0</priority>
+  </url>
+  <url>
+    <loc>https://dfw-dragevents.com/events/old-event-2025-01-01.html</loc>
+  </url>
+</urlset>
+`
+
+func TestEventPages(t *testing.T) {
+	siteDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(siteDir, "sitemap.xml"), []byte(testSitemap), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tracks, events := icsTestData()
+	events[0].Title = "TMCCC Race #1 <Finals>"
+	events[0].Classes[0].Rules = []db.EventClassRule{{Rule: `Max 10.5" tire`}}
+	events[1].URL = "javascript:alert(1)"
+	events[0].Status = db.StatusPostponed
+	events[0].RescheduledTo = &events[1].ID
+
+	res, err := EventPages(siteDir, tracks, events)
+	if err != nil {
+		t.Fatalf("EventPages failed: %v", err)
+	}
+	if len(res.Written) != 3 {
+		t.Errorf("Expected 2 pages and the sitemap, got %v", res.Written)
+	}
+
+	b, err := os.ReadFile(filepath.Join(siteDir, "events", "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e.html"))
+	if err != nil {
+		t.Fatalf("Failed to read page: %v", err)
+	}
+	page := string(b)
+	for _, want := range []string{
+		`<title>TMCCC Race #1 &lt;Finals&gt; | DFW Drag Racing Events</title>`,
+		`<meta property="og:url" content="https://dfw-dragevents.com/events/0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e.html">`,
+		`<meta property="og:title" content="TMCCC Race #1 &lt;Finals&gt; | DFW Drag Racing">`,
+		`<p class="text-body-secondary"><strong>Date:</strong> Sun, Mar 22, 2026, starts 9:00 AM</p>`,
+		`<p class="text-body-secondary"><strong>Fees:</strong> Driver: $40 | Spectator: $20</p>`,
+		`<p class="text-muted">Buy-in: $100</p>`,
+		`<li class="list-group-item">Max 10.5&#34; tire</li>`,
+		`<h5>Street Muscle</h5>`,
+		`<strong>Postponed.</strong> <a href="https://dfw-dragevents.com/events/1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f.html">See the new date</a>.`,
+		`<meta name="description" content="Postponed: TMCCC Race #1`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected page to contain %s", want)
+		}
+	}
+
+	m := regexp.MustCompile(`(?s)<script type="application/ld\+json">(.*?)</script>`).FindStringSubmatch(page)
+	if m == nil {
+		t.Fatal("Expected JSON-LD in the page")
+	}
+	var ld map[string]any
+	if err := json.Unmarshal([]byte(m[1]), &ld); err != nil {
+		t.Fatalf("Invalid JSON-LD: %v\n%s", err, m[1])
+	}
+	if ld["@type"] != "SportsEvent" || ld["name"] != "TMCCC Race #1 <Finals>" || ld["startDate"] != "2026-03-22T09:00:00-05:00" ||
+		ld["eventStatus"] != "https://schema.org/EventRescheduled" {
+		t.Errorf("Unexpected JSON-LD %v", ld)
+	}
+	if offers, _ := ld["offers"].([]any); len(offers) != 2 {
+		t.Errorf("Expected driver and spectator offers, got %v", ld["offers"])
+	}
+
+	b, _ = os.ReadFile(filepath.Join(siteDir, "events", "1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f.html"))
+	if strings.Contains(string(b), "javascript:") {
+		t.Error("Expected an unsafe event URL to be neutralized")
+	}
+	if !strings.Contains(string(b), "No classes listed.") {
+		t.Error("Expected a page without classes to say so")
+	}
+	if strings.Contains(string(b), `role="status"`) {
+		t.Error("Expected no status notice on a scheduled event's page")
+	}
+
+	b, _ = os.ReadFile(filepath.Join(siteDir, "sitemap.xml"))
+	sitemap := string(b)
+	if !strings.Contains(sitemap, "<loc>https://dfw-dragevents.com/</loc>") {
+		t.Error("Expected the sitemap to keep its other pages")
+	}
+	if strings.Contains(sitemap, "old-event") {
+		t.Error("Expected stale event pages to be dropped from the sitemap")
+	}
+	if !strings.Contains(sitemap, "<loc>https://dfw-dragevents.com/events/1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f.html</loc>\n    <lastmod>2026-02-03</lastmod>") {
+		t.Errorf("Expected the event pages in the sitemap:\n%s", sitemap)
+	}
+
+	// Dropping an event removes its page
+	res, err = EventPages(siteDir, tracks, events[:1])
+	if err != nil {
+		t.Fatalf("EventPages failed: %v", err)
+	}
+	if len(res.Removed) != 1 || res.Removed[0] != filepath.Join("events", "1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f.html") {
+		t.Errorf("Expected the swap meet page to be removed, got %v", res.Removed)
+	}
+}
+
+func TestEventPageURLsAreStable(t *testing.T) {
+	siteDir := t.TempDir()
+	tracks, events := icsTestData()
+	if _, err := EventPages(siteDir, tracks, events); err != nil {
+		t.Fatalf("EventPages failed: %v", err)
+	}
+
+	// renaming and moving an event keeps its page
+	events[0].Title = "TMCCC Race #1 (Rescheduled)"
+	events[0].StartDate = events[0].StartDate.AddDate(0, 0, 7)
+	res, err := EventPages(siteDir, tracks, events)
+	if err != nil {
+		t.Fatalf("EventPages failed: %v", err)
+	}
+	if len(res.Removed) != 0 {
+		t.Errorf("Expected no pages to be removed, got %v", res.Removed)
+	}
+	page := filepath.Join("events", "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e.html")
+	if len(res.Written) != 1 || res.Written[0] != page {
+		t.Errorf("Expected %s to be rewritten in place, got %v", page, res.Written)
+	}
+	if got := EventURL(events[0]); got != "https://dfw-dragevents.com/events/0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e.html" {
+		t.Errorf("Expected the feed link to be the page, got %q", got)
+	}
+
+	events[1].UUID = ""
+	if _, err := EventPages(siteDir, tracks, events); err == nil {
+		t.Error("Expected an error for an event without a UUID")
+	}
+}
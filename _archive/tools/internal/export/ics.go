@@ -0,0 +1,293 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// CalendarDir is the directory, within the data directory, that Calendars
+// writes its .ics files to.
+const CalendarDir = "calendars"
+
+// calendarName prefixes the name of every calendar.
+const calendarName = "DFW Drag Events"
+
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405Z"
+)
+
+// Calendars writes iCalendar (RFC 5545) feeds to dataDir/calendars: one
+// with every event (dfw-dragevents.ics), one per track (track-<slug>.ics)
+// and one per series (series-<slug>.ics). Calendars that no longer have
+// events, such as one for a deleted track, are removed. Nothing is written
+// if an event has no UUID or two tracks share a slug.
+func Calendars(dataDir string, tracks []db.Track, events []db.Event) (Result, error) {
+	var res Result
+	dir := filepath.Join(dataDir, CalendarDir)
+	if err := EnsureDir(dir); err != nil {
+		return res, err
+	}
+
+	if err := checkTrackSlugs(tracks); err != nil {
+		return res, err
+	}
+	byID := make(map[int64]db.Track, len(tracks))
+	for _, t := range tracks {
+		byID[t.ID] = t
+	}
+	for _, ev := range events {
+		if _, ok := byID[ev.TrackID]; !ok {
+			return res, fmt.Errorf("event %d: track %d not found", ev.ID, ev.TrackID)
+		}
+		if ev.UUID == "" {
+			return res, fmt.Errorf("event %d has no uuid; run 'db migrate'", ev.ID)
+		}
+	}
+
+	files := map[string][]byte{
+		"dfw-dragevents.ics": Calendar(calendarName, byID, events),
+	}
+	for _, t := range tracks {
+		var trackEvents []db.Event
+		for _, ev := range events {
+			if ev.TrackID == t.ID {
+				trackEvents = append(trackEvents, ev)
+			}
+		}
+		if len(trackEvents) > 0 {
+			name := "track-" + trackSlug(t) + ".ics"
+			files[name] = Calendar(calendarName+": "+t.Name, byID, trackEvents)
+		}
+	}
+	bySeries := make(map[string][]db.Event)
+	var series []string
+	for _, ev := range events {
+		if ev.Series == "" {
+			continue
+		}
+		slug := slugify(ev.Series)
+		if _, ok := bySeries[slug]; !ok {
+			series = append(series, slug)
+		}
+		bySeries[slug] = append(bySeries[slug], ev)
+	}
+	for _, slug := range series {
+		seriesEvents := bySeries[slug]
+		files["series-"+slug+".ics"] = Calendar(calendarName+": "+seriesEvents[0].Series, byID, seriesEvents)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := res.writeFile(dataDir, filepath.Join(CalendarDir, name), files[name]); err != nil {
+			return res, err
+		}
+	}
+	if err := res.removeStale(dataDir, CalendarDir, ".ics", files); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// removeStale deletes the files with extension ext in dataDir/sub that are
+// not in keep.
+func (r *Result) removeStale(dataDir, sub, ext string, keep map[string][]byte) error {
+	entries, err := os.ReadDir(filepath.Join(dataDir, sub))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ext {
+			continue
+		}
+		if _, ok := keep[e.Name()]; ok {
+			continue
+		}
+		name := filepath.Join(sub, e.Name())
+		if err := os.Remove(filepath.Join(dataDir, name)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		r.Removed = append(r.Removed, name)
+	}
+	return nil
+}
+
+// Calendar renders events as an iCalendar document named name. tracks maps
+// track IDs to tracks for the events' locations. The output depends only on
+// the events, so an unchanged schedule renders the same bytes.
+func Calendar(name string, tracks map[int64]db.Track, events []db.Event) []byte {
+	var w icsWriter
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//DFW Drag Events//dfw-dragevents//EN")
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	w.prop("NAME", name)
+	w.prop("X-WR-CALNAME", name)
+	w.line("REFRESH-INTERVAL;VALUE=DURATION:PT12H")
+	w.line("X-PUBLISHED-TTL:PT12H")
+	for _, ev := range events {
+		writeVEvent(&w, ev, tracks[ev.TrackID])
+	}
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+func writeVEvent(w *icsWriter, ev db.Event, track db.Track) {
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + ev.UUID + "@dfw-dragevents")
+	stamp := ev.StartDate
+	if t, err := time.Parse(time.RFC3339, ev.UpdatedAt); err == nil {
+		stamp = t
+	}
+	w.line("DTSTAMP:" + stamp.UTC().Format(icsDateTime))
+	if allDay(ev) {
+		end := ev.StartDate
+		if ev.EndDate != nil {
+			end = *ev.EndDate
+		}
+		// DTEND of an all-day event is the day after the last day
+		w.line("DTSTART;VALUE=DATE:" + ev.StartDate.Format(icsDate))
+		w.line("DTEND;VALUE=DATE:" + end.AddDate(0, 0, 1).Format(icsDate))
+	} else {
+		w.line("DTSTART:" + ev.StartDate.UTC().Format(icsDateTime))
+		if ev.EndDate != nil {
+			w.line("DTEND:" + ev.EndDate.UTC().Format(icsDateTime))
+		}
+	}
+	w.prop("SUMMARY", ev.Title)
+	w.prop("LOCATION", eventLocation(track))
+	w.prop("DESCRIPTION", eventDescription(ev))
+	if status := icsStatus(ev.Status); status != "" {
+		w.line("STATUS:" + status)
+	}
+	if ev.URL != "" {
+		w.line("URL:" + ev.URL)
+	}
+	if ev.Series != "" {
+		w.prop("CATEGORIES", ev.Series)
+	}
+	if t, err := time.Parse(time.RFC3339, ev.CreatedAt); err == nil {
+		w.line("CREATED:" + t.UTC().Format(icsDateTime))
+	}
+	if t, err := time.Parse(time.RFC3339, ev.UpdatedAt); err == nil {
+		w.line("LAST-MODIFIED:" + t.UTC().Format(icsDateTime))
+	}
+	w.line("END:VEVENT")
+}
+
+// icsStatus is the VEVENT STATUS of an event status: CANCELLED for events
+// cancelled or rained out, TENTATIVE for postponed ones, and none for
+// scheduled events.
+func icsStatus(status string) string {
+	switch status {
+	case db.StatusCancelled, db.StatusRainedOut:
+		return "CANCELLED"
+	case db.StatusPostponed:
+		return "TENTATIVE"
+	}
+	return ""
+}
+
+// allDay reports whether an event has dates but no times: it starts at
+// midnight and, if it has an end, ends at midnight too.
+func allDay(ev db.Event) bool {
+	midnight := func(t time.Time) bool {
+		h, m, s := t.Clock()
+		return h == 0 && m == 0 && s == 0
+	}
+	return midnight(ev.StartDate) && (ev.EndDate == nil || midnight(*ev.EndDate))
+}
+
+// eventLocation is the track's address, or its name and city if it has none.
+func eventLocation(t db.Track) string {
+	if t.Address != "" {
+		return t.Address
+	}
+	if t.City != "" {
+		return t.Name + ", " + t.City
+	}
+	return t.Name
+}
+
+// eventDescription lists the description, fees, classes and URL of an event.
+func eventDescription(ev db.Event) string {
+	var parts []string
+	if ev.Description != "" {
+		parts = append(parts, ev.Description)
+	}
+	var fees []string
+	if fee := formatFee(ev.DriverFee); fee != nil {
+		fees = append(fees, "Driver fee: "+*fee)
+	}
+	if fee := formatFee(ev.SpectatorFee); fee != nil {
+		fees = append(fees, "Spectator fee: "+*fee)
+	}
+	if len(fees) > 0 {
+		parts = append(parts, strings.Join(fees, "\n"))
+	}
+	if len(ev.Classes) > 0 {
+		lines := []string{"Classes:"}
+		for _, c := range ev.Classes {
+			line := "- " + c.Name
+			if fee := formatFee(c.BuyinFee); fee != nil {
+				line += " (" + *fee + " buy-in)"
+			}
+			lines = append(lines, line)
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	if ev.URL != "" {
+		parts = append(parts, "More info: "+ev.URL)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// icsWriter writes content lines with CRLF endings, folded at 75 octets.
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+// prop writes a property with a TEXT value, escaped as RFC 5545 requires.
+func (w *icsWriter) prop(name, value string) {
+	if value == "" {
+		return
+	}
+	w.line(name + ":" + escapeText(value))
+}
+
+func (w *icsWriter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		// never split a UTF-8 sequence
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut])
+		w.buf.WriteString("\r\n ")
+		s = s[cut:]
+		// the leading space of a continuation line counts toward its length
+		limit = 74
+	}
+	w.buf.WriteString(s)
+	w.buf.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
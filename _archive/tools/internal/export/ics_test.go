@@ -0,0 +1,165 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+func icsTestData() ([]db.Track, []db.Event) {
+	loc, _ := time.LoadLocation("America/Chicago")
+	start := time.Date(2026, 3, 22, 9, 0, 0, 0, loc)
+	end := time.Date(2026, 3, 22, 23, 0, 0, 0, loc)
+	driver, spectator, buyin := 40.0, 20.0, 100.0
+	tracks := []db.Track{
+		{ID: 1, Name: "Texas Motorplex", City: "Ennis", Address: "7500 US-287, Ennis, TX"},
+		{ID: 2, Name: "Xtreme Raceway Park", City: "Ferris"},
+	}
+	events := []db.Event{
+		{
+			ID: 7, UUID: "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e", Title: "TMCCC Race #1", TrackID: 1, StartDate: start, EndDate: &end,
+			DriverFee: &driver, SpectatorFee: &spectator, Series: "TMCCC",
+			URL: "https://tmccc.org", Description: "Texas Muscle Car Club; round one",
+			CreatedAt: "2026-01-02T03:04:05Z", UpdatedAt: "2026-02-03T04:05:06Z",
+			Classes: []db.EventClass{{Name: "Stock Muscle", BuyinFee: &buyin}, {Name: "Street Muscle"}},
+		},
+		{
+			ID: 8, UUID: "1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f", Title: "Swap Meet", TrackID: 2, StartDate: time.Date(2026, 4, 4, 0, 0, 0, 0, loc),
+			UpdatedAt: "2026-02-03T04:05:06Z",
+		},
+	}
+	return tracks, events
+}
+
+func TestCalendar(t *testing.T) {
+	tracks, events := icsTestData()
+	events[0].Status = db.StatusScheduled
+	events[1].Status = db.StatusCancelled
+	byID := map[int64]db.Track{1: tracks[0], 2: tracks[1]}
+	out := string(Calendar("DFW Drag Events", byID, events))
+
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("Expected a CRLF-delimited VCALENDAR, got:\n%s", out)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+
+	// unfold before looking at values
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	for _, want := range []string{
+		"UID:0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e@dfw-dragevents\r\n",
+		"DTSTAMP:20260203T040506Z\r\n",
+		"DTSTART:20260322T140000Z\r\n",
+		"DTEND:20260323T040000Z\r\n",
+		"SUMMARY:TMCCC Race #1\r\n",
+		`LOCATION:7500 US-287\, Ennis\, TX` + "\r\n",
+		`DESCRIPTION:Texas Muscle Car Club\; round one\n\nDriver fee: $40\nSpectator fee: $20\n\nClasses:\n- Stock Muscle ($100 buy-in)\n- Street Muscle\n\nMore info: https://tmccc.org` + "\r\n",
+		"URL:https://tmccc.org\r\n",
+		"CATEGORIES:TMCCC\r\n",
+		"CREATED:20260102T030405Z\r\n",
+		// all-day event: DTEND is the next day
+		"UID:1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f@dfw-dragevents\r\n",
+		"DTSTART;VALUE=DATE:20260404\r\nDTEND;VALUE=DATE:20260405\r\n",
+		`LOCATION:Xtreme Raceway Park\, Ferris` + "\r\n",
+		"STATUS:CANCELLED\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("Expected calendar to contain %q", want)
+		}
+	}
+
+	if n := strings.Count(unfolded, "STATUS:"); n != 1 {
+		t.Errorf("Expected only the cancelled event to have a STATUS, got %d", n)
+	}
+
+	// the same events render the same bytes
+	if again := string(Calendar("DFW Drag Events", byID, events)); again != out {
+		t.Error("Expected Calendar to be deterministic")
+	}
+}
+
+func TestICSLineFolding(t *testing.T) {
+	var w icsWriter
+	w.prop("DESCRIPTION", strings.Repeat("é", 100))
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\r\n"), "\r\n")
+	if len(lines) < 3 {
+		t.Fatalf("Expected the line to be folded, got %d lines", len(lines))
+	}
+	for i, line := range lines {
+		if len(line) > 75 {
+			t.Errorf("Line %d is %d octets", i, len(line))
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("Continuation line %d does not start with a space", i)
+		}
+	}
+	if strings.ReplaceAll(w.buf.String(), "\r\n ", "") != "DESCRIPTION:"+strings.Repeat("é", 100)+"\r\n" {
+		t.Error("Unfolding did not restore the line")
+	}
+}
+
+func TestCalendars(t *testing.T) {
+	dataDir := t.TempDir()
+	tracks, events := icsTestData()
+
+	stale := filepath.Join(dataDir, CalendarDir, "track-old-track.ics")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("BEGIN:VCALENDAR\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Calendars(dataDir, tracks, events)
+	if err != nil {
+		t.Fatalf("Calendars failed: %v", err)
+	}
+	want := []string{
+		filepath.Join(CalendarDir, "dfw-dragevents.ics"),
+		filepath.Join(CalendarDir, "series-tmccc.ics"),
+		filepath.Join(CalendarDir, "track-texas-motorplex-tx.ics"),
+		filepath.Join(CalendarDir, "track-xtreme-raceway-park.ics"),
+	}
+	if strings.Join(res.Written, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v written, got %v", want, res.Written)
+	}
+	if len(res.Removed) != 1 || res.Removed[0] != filepath.Join(CalendarDir, "track-old-track.ics") {
+		t.Errorf("Expected the stale calendar to be removed, got %v", res.Removed)
+	}
+
+	series, _ := os.ReadFile(filepath.Join(dataDir, CalendarDir, "series-tmccc.ics"))
+	if !strings.Contains(string(series), "X-WR-CALNAME:DFW Drag Events: TMCCC") ||
+		strings.Contains(string(series), "1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f@") {
+		t.Errorf("Expected the series calendar to hold only TMCCC events:\n%s", series)
+	}
+
+	res, err = Calendars(dataDir, tracks, events)
+	if err != nil {
+		t.Fatalf("Calendars failed: %v", err)
+	}
+	if len(res.Written) != 0 || len(res.Unchanged) != 4 {
+		t.Errorf("Expected every calendar to be unchanged, got %+v", res)
+	}
+}
+
+func TestCalendarsRejectsSharedTrackSlugs(t *testing.T) {
+	dataDir := t.TempDir()
+	tracks, events := icsTestData()
+	tracks = append(tracks, db.Track{ID: 3, Name: "Xtreme Raceway Park"})
+	events = append(events, db.Event{ID: 9, UUID: "2d9e5f40-ab3c-4e7d-9f80-9b0c1d2e3f40", Title: "Grudge Night", TrackID: 3,
+		StartDate: events[1].StartDate})
+
+	if _, err := Calendars(dataDir, tracks, events); err == nil || !strings.Contains(err.Error(), "share the slug") {
+		t.Fatalf("Expected a shared slug error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dataDir, CalendarDir)); len(entries) != 0 {
+		t.Errorf("Expected no calendars to be written, got %d", len(entries))
+	}
+}
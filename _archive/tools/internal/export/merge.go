@@ -0,0 +1,358 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// Provenance markers of merged events: where each record's fields came from.
+const (
+	ProvenanceAggregator = "aggregator" // the aggregator's record as it is
+	ProvenanceLocal      = "local"      // an event only the local database has
+	ProvenanceMerged     = "merged"     // the aggregator's record with local fields
+)
+
+// FeedEvent is an event of the aggregator's events.json, the flat list the
+// site reads, as written by the merge with its provenance added.
+type FeedEvent struct {
+	ID              string          `json:"id"`
+	Title           string          `json:"title"`
+	EventType       string          `json:"event_type"`
+	Series          *string         `json:"series"`
+	TrackID         string          `json:"track_id"` // slug, e.g. xtreme-tx
+	TrackName       string          `json:"track_name"`
+	TrackCity       string          `json:"track_city"`
+	TrackState      string          `json:"track_state"`
+	StartDate       string          `json:"start_date"` // local time at the track, 2006-01-02T15:04:05
+	EndDate         *string         `json:"end_date"`
+	Description     string          `json:"description"`
+	DriverFee       *float64        `json:"event_driver_fee"`
+	SpectatorFee    *float64        `json:"event_spectator_fee"`
+	RawDriverFee    *string         `json:"raw_driver_fee"`
+	RawSpectatorFee *string         `json:"raw_spectator_fee"`
+	URL             *string         `json:"url"`
+	Classes         []FeedClass     `json:"classes"`
+	Confidence      float64         `json:"confidence"`
+	UnclearFields   []string        `json:"unclear_fields"`
+	Flyers          json.RawMessage `json:"flyers"` // passed through as is
+	CreatedAt       string          `json:"created_at"`
+	UpdatedAt       string          `json:"updated_at"`
+	Status          string          `json:"status,omitempty"` // a local event's status unless it is scheduled
+	Provenance      string          `json:"provenance"`
+	LocalFields     []string        `json:"local_fields,omitempty"` // fields a merged record takes from the local event
+}
+
+type FeedClass struct {
+	Name     string          `json:"name"`
+	BuyinFee *float64        `json:"buyin_fee"`
+	Rules    []db.BundleRule `json:"rules"`
+}
+
+// unclearFieldsOf maps the aggregator's unclear_fields entries to the fields
+// they are about. An entry no longer applies once a local field replaces it.
+var unclearFieldsOf = map[string][]string{
+	"title":          {"title"},
+	"series":         {"series"},
+	"city":           {"track_city"},
+	"state":          {"track_state"},
+	"dates":          {"start_date", "end_date"},
+	"fees":           {"event_driver_fee", "event_spectator_fee"},
+	"fees.entry":     {"event_driver_fee"},
+	"fees.spectator": {"event_spectator_fee"},
+	"classes":        {"classes"},
+	"contact":        {"url"},
+}
+
+// LoadFeed reads the aggregator's events.json: a list of events, or an
+// object with an "events" list. It refuses a file the merge wrote, which
+// would pass earlier local fields off as the aggregator's, and records in
+// the nested events.schema.json shape, whose fields it would not see.
+func LoadFeed(path string) ([]FeedEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read aggregator feed: %w", err)
+	}
+	var feed []FeedEvent
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &feed)
+	} else {
+		var obj struct {
+			Events []FeedEvent `json:"events"`
+		}
+		err = json.Unmarshal(data, &obj)
+		feed = obj.Events
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse aggregator feed: %w", err)
+	}
+	for _, ev := range feed {
+		if ev.Provenance != "" {
+			return nil, fmt.Errorf("%s is merge output (event %s has provenance %q); merge the aggregator's own file",
+				path, ev.ID, ev.Provenance)
+		}
+		if ev.StartDate == "" {
+			return nil, fmt.Errorf("%s: event %s has no start_date; the merge reads the site's flat events.json, not the events.schema.json shape",
+				path, ev.ID)
+		}
+	}
+	return feed, nil
+}
+
+// MergeEvents combines the aggregator's feed with the local events. Events
+// imported from the aggregator are left out, as the feed has the current
+// version of them. A local event with the UUID of a feed event overrides
+// it field by field: the fields edited here (every field it has, for an
+// event that does not record them) replace the aggregator's, and the rest
+// are kept, so the feed's later corrections to them still show. Local events the feed does not have
+// are added. Feed events whose id is in suppressed are dropped and returned.
+// Events must have their classes and rules nested.
+func MergeEvents(feed []FeedEvent, tracks []db.Track, events []db.Event, suppressed map[string]bool) ([]FeedEvent, []EventRef, error) {
+	byTrack := make(map[int64]db.Track, len(tracks))
+	for _, t := range tracks {
+		byTrack[t.ID] = t
+	}
+	local := make(map[string]db.Event)
+	var order []string
+	for _, ev := range events {
+		if ev.Source == db.SourceAggregator {
+			continue
+		}
+		if ev.UUID == "" {
+			return nil, nil, fmt.Errorf("event %d has no uuid; run 'db migrate'", ev.ID)
+		}
+		if _, ok := byTrack[ev.TrackID]; !ok {
+			return nil, nil, fmt.Errorf("event %d: track %d not found", ev.ID, ev.TrackID)
+		}
+		id := strings.ToLower(ev.UUID)
+		local[id] = ev
+		order = append(order, id)
+	}
+
+	var out []FeedEvent
+	var hidden []EventRef
+	seen := make(map[string]bool, len(feed))
+	for _, rec := range feed {
+		id := strings.ToLower(rec.ID)
+		if seen[id] {
+			return nil, nil, fmt.Errorf("aggregator feed lists event %s twice", rec.ID)
+		}
+		seen[id] = true
+		if suppressed[id] {
+			hidden = append(hidden, EventRef{ID: rec.ID, Title: rec.Title})
+			continue
+		}
+		ev, ok := local[id]
+		if !ok {
+			rec.Provenance = ProvenanceAggregator
+			out = append(out, normalizeFeedEvent(rec))
+			continue
+		}
+		delete(local, id)
+		out = append(out, mergeEvent(rec, ev, byTrack[ev.TrackID]))
+	}
+	for _, id := range order {
+		if ev, ok := local[id]; ok {
+			out = append(out, localFeedEvent(ev, byTrack[ev.TrackID]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartDate < out[j].StartDate })
+	return out, hidden, nil
+}
+
+// Merged writes the merged events to dataDir/events.json.
+func Merged(dataDir string, merged []FeedEvent) (Result, error) {
+	var res Result
+	if err := EnsureDir(dataDir); err != nil {
+		return res, err
+	}
+	if err := res.writeEvents(dataDir, "events.json", merged); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// normalizeFeedEvent gives the lists the site iterates over a value, so
+// they marshal as [] rather than null.
+func normalizeFeedEvent(ev FeedEvent) FeedEvent {
+	if ev.Classes == nil {
+		ev.Classes = []FeedClass{}
+	}
+	for i := range ev.Classes {
+		if ev.Classes[i].Rules == nil {
+			ev.Classes[i].Rules = []db.BundleRule{}
+		}
+	}
+	if ev.UnclearFields == nil {
+		ev.UnclearFields = []string{}
+	}
+	if len(ev.Flyers) == 0 {
+		ev.Flyers = json.RawMessage("[]")
+	}
+	return ev
+}
+
+// localFeedEvent converts a local event the aggregator does not have.
+func localFeedEvent(ev db.Event, track db.Track) FeedEvent {
+	out := FeedEvent{
+		ID:           ev.UUID,
+		Title:        ev.Title,
+		EventType:    EventType(ev.Title),
+		Series:       optional(ev.Series),
+		TrackID:      trackSlug(track),
+		TrackName:    track.Name,
+		TrackCity:    track.City,
+		TrackState:   TrackState(track.Address),
+		StartDate:    ev.StartLocal,
+		EndDate:      optional(ev.EndLocal),
+		Description:  ev.Description,
+		DriverFee:    ev.DriverFee,
+		SpectatorFee: ev.SpectatorFee,
+		URL:          optional(ev.URL),
+		Classes:      feedClasses(ev.Classes),
+		Confidence:   curatedConfidence,
+		CreatedAt:    ev.CreatedAt,
+		UpdatedAt:    ev.UpdatedAt,
+		Status:       feedStatus(ev),
+		Provenance:   ProvenanceLocal,
+	}
+	return normalizeFeedEvent(out)
+}
+
+// feedStatus is the status a merged record carries: none for scheduled
+// events, which is what records without one are.
+func feedStatus(ev db.Event) string {
+	if ev.Status == db.StatusScheduled {
+		return ""
+	}
+	return ev.Status
+}
+
+// mergeEvent overrides the aggregator's record with the fields edited in the
+// local event, noting each one that differs in LocalFields. An event that
+// does not record its edited fields overrides every field it has.
+func mergeEvent(rec FeedEvent, ev db.Event, track db.Track) FeedEvent {
+	out := normalizeFeedEvent(rec)
+	out.Provenance = ProvenanceMerged
+	edited := func(field string, has bool) bool {
+		if len(ev.LocalFields) == 0 {
+			return has
+		}
+		return slices.Contains(ev.LocalFields, field)
+	}
+	take := func(field string, differs bool, set func()) {
+		if differs {
+			set()
+			out.LocalFields = append(out.LocalFields, field)
+		}
+	}
+	if edited(db.LocalTitle, true) {
+		take("title", ev.Title != rec.Title, func() { out.Title = ev.Title })
+	}
+	if edited(db.LocalSeries, ev.Series != "") {
+		take("series", !optionalEqual(rec.Series, ev.Series), func() { out.Series = optional(ev.Series) })
+	}
+	if edited(db.LocalTrack, true) {
+		slug, state := trackSlug(track), TrackState(track.Address)
+		take("track_id", slug != rec.TrackID, func() { out.TrackID = slug })
+		take("track_name", track.Name != rec.TrackName, func() { out.TrackName = track.Name })
+		if track.City != "" {
+			take("track_city", track.City != rec.TrackCity, func() { out.TrackCity = track.City })
+		}
+		if state != "" {
+			take("track_state", state != rec.TrackState, func() { out.TrackState = state })
+		}
+	}
+	if edited(db.LocalStartDate, true) {
+		take("start_date", ev.StartLocal != rec.StartDate, func() { out.StartDate = ev.StartLocal })
+	}
+	if edited(db.LocalEndDate, ev.EndLocal != "") {
+		take("end_date", !optionalEqual(rec.EndDate, ev.EndLocal), func() { out.EndDate = optional(ev.EndLocal) })
+	}
+	if edited(db.LocalDescription, ev.Description != "") {
+		take("description", ev.Description != rec.Description, func() { out.Description = ev.Description })
+	}
+	if edited(db.LocalDriverFee, ev.DriverFee != nil) {
+		take("event_driver_fee", !feeEqual(ev.DriverFee, rec.DriverFee), func() {
+			out.DriverFee, out.RawDriverFee = ev.DriverFee, nil
+		})
+	}
+	if edited(db.LocalSpectatorFee, ev.SpectatorFee != nil) {
+		take("event_spectator_fee", !feeEqual(ev.SpectatorFee, rec.SpectatorFee), func() {
+			out.SpectatorFee, out.RawSpectatorFee = ev.SpectatorFee, nil
+		})
+	}
+	if edited(db.LocalURL, ev.URL != "") {
+		take("url", !optionalEqual(rec.URL, ev.URL), func() { out.URL = optional(ev.URL) })
+	}
+	if status := feedStatus(ev); edited(db.LocalStatus, status != "") {
+		take("status", status != rec.Status, func() { out.Status = status })
+	}
+	if edited(db.LocalClasses, len(ev.Classes) > 0) {
+		classes := feedClasses(ev.Classes)
+		b1, _ := json.Marshal(classes)
+		b2, _ := json.Marshal(out.Classes)
+		take("classes", !bytes.Equal(b1, b2), func() { out.Classes = classes })
+	}
+	if len(out.LocalFields) == 0 {
+		return out
+	}
+
+	unclear := []string{}
+	for _, f := range out.UnclearFields {
+		if !slices.ContainsFunc(unclearFieldsOf[f], func(field string) bool { return slices.Contains(out.LocalFields, field) }) {
+			unclear = append(unclear, f)
+		}
+	}
+	out.UnclearFields = unclear
+	if later(ev.UpdatedAt, rec.UpdatedAt) {
+		out.UpdatedAt = ev.UpdatedAt
+	}
+	return out
+}
+
+func feedClasses(classes []db.EventClass) []FeedClass {
+	out := make([]FeedClass, 0, len(classes))
+	for _, c := range classes {
+		fc := FeedClass{Name: c.Name, BuyinFee: c.BuyinFee, Rules: []db.BundleRule{}}
+		for _, r := range c.Rules {
+			fc.Rules = append(fc.Rules, db.BundleRule(r.Rule))
+		}
+		out = append(out, fc)
+	}
+	return out
+}
+
+func feeEqual(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// optionalEqual reports whether the record's value p is s, where an empty s
+// is no value.
+func optionalEqual(p *string, s string) bool {
+	if o := optional(s); p == nil || o == nil {
+		return p == nil && o == nil
+	}
+	return *p == strings.TrimSpace(s)
+}
+
+// later reports whether timestamp a is after b. A timestamp that does not
+// parse is never later.
+func later(a, b string) bool {
+	ta, err := time.Parse(time.RFC3339, a)
+	if err != nil {
+		return false
+	}
+	tb, err := time.Parse(time.RFC3339, b)
+	return err != nil || ta.After(tb)
+}
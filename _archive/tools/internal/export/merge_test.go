@@ -0,0 +1,199 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+const testFeed = `[
+  {
+    "id": "57bc97b9-f865-436d-9d4f-190b56c92a25",
+    "title": "TMCCC Race #1",
+    "event_type": "points_race",
+    "series": null,
+    "track_id": "texas-motorplex-tx",
+    "track_name": "Texas Motorplex",
+    "track_city": "Ennis",
+    "track_state": "TX",
+    "start_date": "2026-03-22T09:00:00",
+    "end_date": "2026-03-22T23:59:59",
+    "description": "Round one",
+    "event_driver_fee": null,
+    "event_spectator_fee": 20,
+    "raw_driver_fee": "call",
+    "raw_spectator_fee": "$20",
+    "url": null,
+    "classes": [{"name": "Stock Muscle", "buyin_fee": null, "rules": []}],
+    "confidence": 0.8,
+    "unclear_fields": ["fees.entry", "times"],
+    "flyers": [{"file": "https://tmccc.org/events", "phash": null, "processed_at": "2026-01-01T00:00:00+00:00"}],
+    "created_at": "2026-01-01T00:00:00+00:00",
+    "updated_at": "2026-01-05T00:00:00+00:00"
+  },
+  {
+    "id": "11111111-2222-4333-8444-555555555555",
+    "title": "Misread Flyer",
+    "track_id": "xtreme-tx",
+    "track_name": "Xtreme Raceway Park",
+    "start_date": "2026-03-01T00:00:00"
+  },
+  {
+    "id": "66666666-7777-4888-9999-000000000000",
+    "title": "Friday Night Drags",
+    "track_id": "xtreme-tx",
+    "track_name": "Xtreme Raceway Park",
+    "track_city": "Ferris",
+    "track_state": "TX",
+    "start_date": "2026-04-17T18:00:00",
+    "description": "",
+    "classes": [],
+    "confidence": 0.9,
+    "unclear_fields": [],
+    "flyers": [],
+    "created_at": "2026-01-01T00:00:00+00:00",
+    "updated_at": "2026-01-01T00:00:00+00:00"
+  }
+]`
+
+func TestMergeEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.json")
+	if err := os.WriteFile(path, []byte(testFeed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := LoadFeed(path)
+	if err != nil {
+		t.Fatalf("LoadFeed failed: %v", err)
+	}
+
+	tracks, events := icsTestData()
+	tracks[0].Slug = "texas-motorplex-tx"
+	// the first local event corrects the aggregator's driver fee and classes
+	events[0].UUID = feed[0].ID
+	events[0].Source = db.SourceLocal
+	events[0].StartLocal = "2026-03-22T09:00:00"
+	events[0].EndLocal = "2026-03-22T23:59:59"
+	events[0].Description = ""
+	events[0].URL = ""
+	events[0].Series = ""
+	events[0].SpectatorFee = nil
+	// the second is only in the database
+	events[1].UUID = "0b7e3c52-1f0a-4c55-9d67-3b2a4c1e5f60"
+	events[1].Source = db.SourceLocal
+	events[1].StartLocal = "2026-04-04T00:00:00"
+	events[1].Status = db.StatusCancelled
+	// an event imported from the aggregator is superseded by the feed
+	imported := db.Event{ID: 9, Title: "Stale Copy", TrackID: 2, UUID: feed[2].ID, Source: db.SourceAggregator}
+	events = append(events, imported)
+
+	merged, hidden, err := MergeEvents(feed, tracks, events, map[string]bool{feed[1].ID: true})
+	if err != nil {
+		t.Fatalf("MergeEvents failed: %v", err)
+	}
+	if len(hidden) != 1 || hidden[0].Title != "Misread Flyer" {
+		t.Errorf("Expected the suppressed event to be hidden, got %v", hidden)
+	}
+	if len(merged) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", merged)
+	}
+
+	ev := merged[0]
+	if ev.Provenance != ProvenanceMerged {
+		t.Errorf("Expected a merged record, got %q", ev.Provenance)
+	}
+	if want := []string{"event_driver_fee", "classes"}; !reflect.DeepEqual(ev.LocalFields, want) {
+		t.Errorf("Expected local fields %v, got %v", want, ev.LocalFields)
+	}
+	if ev.DriverFee == nil || *ev.DriverFee != 40 || ev.RawDriverFee != nil {
+		t.Errorf("Expected the local driver fee without the raw text, got %v %v", ev.DriverFee, ev.RawDriverFee)
+	}
+	if ev.SpectatorFee == nil || *ev.SpectatorFee != 20 || ev.Description != "Round one" {
+		t.Errorf("Expected fields the local event lacks to be kept, got %+v", ev)
+	}
+	if len(ev.Classes) != 2 || ev.Classes[0].BuyinFee == nil || *ev.Classes[0].BuyinFee != 100 {
+		t.Errorf("Expected the local classes, got %+v", ev.Classes)
+	}
+	if !reflect.DeepEqual(ev.UnclearFields, []string{"times"}) {
+		t.Errorf("Expected the resolved unclear field to be dropped, got %v", ev.UnclearFields)
+	}
+	if ev.UpdatedAt != "2026-02-03T04:05:06Z" || ev.Confidence != 0.8 || !strings.Contains(string(ev.Flyers), "tmccc.org") {
+		t.Errorf("Unexpected merged metadata: %+v", ev)
+	}
+
+	if merged[0].Status != "" {
+		t.Errorf("Expected no status on a scheduled event, got %q", merged[0].Status)
+	}
+	if ev := merged[1]; ev.Provenance != ProvenanceLocal || ev.TrackID != "xtreme-raceway-park" || ev.Confidence != 1 || ev.EndDate != nil ||
+		ev.Status != db.StatusCancelled {
+		t.Errorf("Unexpected local record: %+v", ev)
+	}
+	if ev := merged[2]; ev.Provenance != ProvenanceAggregator || ev.Title != "Friday Night Drags" {
+		t.Errorf("Expected the feed's record over the imported copy, got %+v", ev)
+	}
+
+	// the merge output is not accepted as a feed
+	res, err := Merged(filepath.Dir(path), merged)
+	if err != nil {
+		t.Fatalf("Merged failed: %v", err)
+	}
+	if len(res.Written) != 1 || res.Events == nil || len(res.Events.Added) != 3 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if _, err := LoadFeed(filepath.Join(filepath.Dir(path), "events.json")); err == nil || !strings.Contains(err.Error(), "merge output") {
+		t.Errorf("Expected merge output to be refused as a feed, got %v", err)
+	}
+}
+
+func TestMergeEventsTakesOnlyEditedFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.json")
+	if err := os.WriteFile(path, []byte(testFeed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := LoadFeed(path)
+	if err != nil {
+		t.Fatalf("LoadFeed failed: %v", err)
+	}
+
+	tracks, events := icsTestData()
+	tracks[0].Slug = "texas-motorplex-tx"
+	// the title was edited here; the start date is the aggregator's from
+	// before the feed corrected it
+	ev := events[0]
+	ev.UUID = feed[0].ID
+	ev.Source = db.SourceLocal
+	ev.Title = "TMCCC Race #1 (Ennis)"
+	ev.StartLocal = "2026-03-21T09:00:00"
+	ev.LocalFields = []string{db.LocalTitle}
+
+	merged, _, err := MergeEvents(feed[:1], tracks, []db.Event{ev}, nil)
+	if err != nil {
+		t.Fatalf("MergeEvents failed: %v", err)
+	}
+	got := merged[0]
+	if got.Title != ev.Title || !reflect.DeepEqual(got.LocalFields, []string{"title"}) {
+		t.Errorf("Expected only the edited title to be local, got %q %v", got.Title, got.LocalFields)
+	}
+	if got.StartDate != "2026-03-22T09:00:00" {
+		t.Errorf("Expected the feed's corrected start date, got %q", got.StartDate)
+	}
+	if got.DriverFee != nil || len(got.Classes) != 1 {
+		t.Errorf("Expected the feed's fees and classes, got %v %+v", got.DriverFee, got.Classes)
+	}
+}
+
+func TestLoadFeedRejectsSchemaShape(t *testing.T) {
+	dir := t.TempDir()
+	tracks := []db.Track{{ID: 1, Name: "Texas Motorplex", Address: "Ennis, TX"}}
+	events := []db.Event{{ID: 1, Title: "Spring Nationals", TrackID: 1, StartDate: time.Now(), UUID: "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e"}}
+	if _, err := Aggregator(dir, tracks, events, nil); err != nil {
+		t.Fatalf("Aggregator failed: %v", err)
+	}
+	if _, err := LoadFeed(filepath.Join(dir, AggregatorFile)); err == nil || !strings.Contains(err.Error(), "events.schema.json shape") {
+		t.Errorf("Expected the aggregator export to be refused as a feed, got %v", err)
+	}
+}
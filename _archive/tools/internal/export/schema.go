@@ -0,0 +1,300 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft-07) document. Validate supports the
+// keywords events.schema.json uses: type, enum, required, properties,
+// additionalProperties, items, $ref to local definitions, minimum, maximum,
+// pattern and the uuid, date, date-time, email and uri formats. Other
+// keywords are ignored.
+type Schema struct {
+	root map[string]any
+}
+
+// Violation is one way a document fails its schema. Pointer is the JSON
+// pointer (RFC 6901) of the offending value; "" is the whole document.
+type Violation struct {
+	Pointer string
+	Message string
+}
+
+func (v Violation) String() string {
+	p := v.Pointer
+	if p == "" {
+		p = "/"
+	}
+	return p + ": " + v.Message
+}
+
+// ValidationError is returned when a document fails its schema. It lists
+// every violation, not just the first.
+type ValidationError struct {
+	File       string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %d schema violations, first %s", e.File, len(e.Violations), e.Violations[0])
+}
+
+// LoadSchema reads a JSON Schema from path.
+func LoadSchema(path string) (*Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(b)
+}
+
+// ParseSchema parses a JSON Schema document.
+func ParseSchema(b []byte) (*Schema, error) {
+	var root map[string]any
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate checks v against the schema. v is marshalled to JSON first, so it
+// is validated exactly as it would be written.
+func (s *Schema) Validate(v any) ([]Violation, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	var out []Violation
+	if err := s.validate(s.root, doc, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Schema) validate(schema map[string]any, v any, ptr string, out *[]Violation) error {
+	fail := func(format string, args ...any) {
+		*out = append(*out, Violation{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		if err != nil {
+			return err
+		}
+		return s.validate(target, v, ptr, out)
+	}
+
+	if t, ok := schema["type"]; ok {
+		types := schemaTypes(t)
+		if !typeMatches(types, v) {
+			fail("expected %s, got %s", strings.Join(types, " or "), jsonType(v))
+			return nil
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("%s is not one of %s", compact(v), compact(enum))
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		return s.validateObject(schema, val, ptr, out)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				if err := s.validate(items, item, ptr+"/"+strconv.Itoa(i), out); err != nil {
+					return err
+				}
+			}
+		}
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && val < min {
+			fail("%v is less than the minimum %v", val, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && val > max {
+			fail("%v is greater than the maximum %v", val, max)
+		}
+	case string:
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q in schema: %w", pattern, err)
+			}
+			if !re.MatchString(val) {
+				fail("%q does not match %s", val, pattern)
+			}
+		}
+		if format, ok := schema["format"].(string); ok {
+			if !validFormat(format, val) {
+				fail("%q is not a valid %s", val, format)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(schema, obj map[string]any, ptr string, out *[]Violation) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				*out = append(*out, Violation{Pointer: ptr, Message: fmt.Sprintf("missing required property %q", name)})
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := ptr + "/" + escapePointer(name)
+		if p, ok := props[name].(map[string]any); ok {
+			if err := s.validate(p, obj[name], child, out); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				*out = append(*out, Violation{Pointer: child, Message: "property is not allowed"})
+			}
+		case map[string]any:
+			if err := s.validate(extra, obj[name], child, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve looks up a local reference such as "#/definitions/Event".
+func (s *Schema) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+	var node any = s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = m[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	m, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %q is not a schema", ref)
+	}
+	return m, nil
+}
+
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var out []string
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func typeMatches(types []string, v any) bool {
+	actual := jsonType(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value. Whole numbers are
+// "integer", so they satisfy both "integer" and "number".
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validFormat checks the formats Validate supports; others always pass.
+func validFormat(format, s string) bool {
+	var err error
+	switch format {
+	case "uuid":
+		return uuidRe.MatchString(s)
+	case "date":
+		_, err = time.Parse("2006-01-02", s)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339Nano, s)
+	case "email":
+		_, err = mail.ParseAddress(s)
+	case "uri":
+		var u *url.URL
+		if u, err = url.Parse(s); err == nil && u.Scheme == "" {
+			return false
+		}
+	}
+	return err == nil
+}
+
+// escapePointer escapes a property name for use in a JSON pointer.
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func compact(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
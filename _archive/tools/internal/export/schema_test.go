@@ -0,0 +1,187 @@
+package export
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+const testSchema = `{
+  "type": "array",
+  "items": {"$ref": "#/definitions/Event"},
+  "definitions": {
+    "Event": {
+      "type": "object",
+      "required": ["id", "title", "dates"],
+      "properties": {
+        "id": {"type": "string", "format": "uuid"},
+        "title": {"type": "string"},
+        "event_type": {"type": "string", "enum": ["bracket", "unknown"]},
+        "confidence": {"type": "number", "minimum": 0, "maximum": 1},
+        "dates": {
+          "type": "object",
+          "required": ["start"],
+          "properties": {
+            "start": {"type": "string", "format": "date"},
+            "end": {"type": ["string", "null"], "format": "date"}
+          },
+          "additionalProperties": false
+        },
+        "race_start": {"type": ["string", "null"], "pattern": "^([01]\\d|2[0-3]):[0-5]\\d$"},
+        "website": {"type": ["string", "null"], "format": "uri"}
+      },
+      "additionalProperties": false
+    }
+  }
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+
+	valid := []map[string]any{{
+		"id": "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e", "title": "Good", "event_type": "bracket",
+		"confidence": 1, "dates": map[string]any{"start": "2026-03-22", "end": nil},
+		"race_start": "09:00", "website": "https://example.com",
+	}}
+	violations, err := schema.Validate(valid)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+
+	invalid := []map[string]any{{
+		"id": "42", "event_type": "drag", "confidence": 1.5,
+		"dates":      map[string]any{"start": "3/22/2026", "extra": true},
+		"race_start": "9:00", "website": "example.com", "track_name": "Motorplex",
+	}}
+	violations, err = schema.Validate(invalid)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	want := []string{
+		`/0: missing required property "title"`,
+		`/0/confidence: 1.5 is greater than the maximum 1`,
+		`/0/dates/extra: property is not allowed`,
+		`/0/dates/start: "3/22/2026" is not a valid date`,
+		`/0/event_type: "drag" is not one of ["bracket","unknown"]`,
+		`/0/id: "42" is not a valid uuid`,
+		`/0/race_start: "9:00" does not match`,
+		`/0/track_name: property is not allowed`,
+		`/0/website: "example.com" is not a valid uri`,
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if strings.HasPrefix(g, w) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected violation %q, got %v", w, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d violations, got %d: %v", len(want), len(got), got)
+	}
+}
+
+func TestSchemaValidateType(t *testing.T) {
+	schema, _ := ParseSchema([]byte(`{"type": "object", "properties": {"a": {"type": "integer"}, "a~b/c": {"type": "string"}}}`))
+	violations, err := schema.Validate(map[string]any{"a": 1.5, "a~b/c": nil})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(violations) != 2 {
+		t.Fatalf("Expected 2 violations, got %v", violations)
+	}
+	if violations[0].String() != "/a: expected integer, got number" {
+		t.Errorf("Unexpected violation: %s", violations[0])
+	}
+	if violations[1].Pointer != "/a~0b~1c" {
+		t.Errorf("Expected an escaped JSON pointer, got %q", violations[1].Pointer)
+	}
+}
+
+func TestAggregatorValidatesBeforeWriting(t *testing.T) {
+	dataDir := t.TempDir()
+	path := filepath.Join(dataDir, AggregatorFile)
+	if err := os.WriteFile(path, []byte("[]\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", AggregatorFile, err)
+	}
+
+	schema, _ := ParseSchema([]byte(`{"type": "array", "items": {"type": "object", "required": ["title"],
+		"properties": {"id": {"type": "string", "format": "uuid"}}}}`))
+	tracks := []db.Track{{ID: 1, Name: "Test Track"}}
+	events := []db.Event{{ID: 1, Title: "Bad", TrackID: 1, StartDate: time.Now(), UUID: "not-a-uuid"}}
+
+	_, err := Aggregator(dataDir, tracks, events, schema)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	if len(validationErr.Violations) != 1 || validationErr.Violations[0].Pointer != "/0/id" {
+		t.Errorf("Expected one violation at /0/id, got %v", validationErr.Violations)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "[]\n" {
+		t.Errorf("Expected %s to be left untouched, got %s", AggregatorFile, content)
+	}
+}
+
+// siteSchema is the schema of the aggregator's records, relative to this
+// package.
+var siteSchema = filepath.Join("..", "..", "..", "..", "site", "data", "events.schema.json")
+
+func TestAggregatorMatchesSiteSchema(t *testing.T) {
+	schema, err := LoadSchema(siteSchema)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+
+	loc, _ := time.LoadLocation("America/Chicago")
+	start := time.Date(2026, 4, 3, 18, 30, 0, 0, loc)
+	end := time.Date(2026, 4, 4, 23, 0, 0, 0, loc)
+	driverFee, spectatorFee, buyin := 40.0, 12.5, 100.0
+	later := start.AddDate(0, 0, 14)
+	tracks := []db.Track{
+		{ID: 1, Name: "Xtreme Raceway Park", City: "Ferris", Address: "1800 S Interstate 45, Ferris, TX 75125", URL: "https://xtremeracewaypark.com"},
+		{ID: 2, Name: "Test Track"},
+	}
+	events := []db.Event{
+		{
+			ID: 1, Title: "Friday Night Test N Tune", TrackID: 1, StartDate: start, EndDate: &end,
+			DriverFee: &driverFee, SpectatorFee: &spectatorFee, URL: "https://xtremeracewaypark.com/events",
+			Description: "Gates open at 5", Series: "1/8 Mile", Status: db.StatusScheduled,
+			UUID:      "0b7c3d2e-8f1a-4c5b-9d6e-7f8a9b0c1d2e",
+			CreatedAt: "2026-03-01T12:00:00Z", UpdatedAt: "2026-03-02T12:00:00Z",
+			Classes: []db.EventClass{{Name: "Pro Street", BuyinFee: &buyin, Rules: []db.EventClassRule{{Rule: "DOT tires"}}}},
+		},
+		{
+			ID: 2, Title: "Grudge Night", TrackID: 2, StartDate: later, Status: db.StatusScheduled,
+			UUID:      "1c8d4e3f-9a2b-4d6c-8e7f-8a9b0c1d2e3f",
+			CreatedAt: "2026-03-01T12:00:00Z", UpdatedAt: "2026-03-01T12:00:00Z",
+		},
+	}
+
+	if _, err := Aggregator(t.TempDir(), tracks, events, schema); err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			t.Fatalf("Aggregator output does not match %s: %v", siteSchema, validationErr.Violations)
+		}
+		t.Fatalf("Aggregator failed: %v", err)
+	}
+}
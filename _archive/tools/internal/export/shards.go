@@ -0,0 +1,170 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// Directories, within the data directory, that Sharded writes to.
+const (
+	TrackShardDir = "tracks"
+	MonthShardDir = "months"
+)
+
+// Shard describes one file of a sharded export. File is relative to the
+// data directory and uses forward slashes, so the site can fetch it as is.
+type Shard struct {
+	File   string `json:"file"`
+	Count  int    `json:"count"`
+	SHA256 string `json:"sha256"` // hex digest of the file's content
+}
+
+// TrackShard is the file with one track's events.
+type TrackShard struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	Shard
+}
+
+// MonthShard is the file with the events held in one month.
+type MonthShard struct {
+	Month string `json:"month"` // YYYY-MM
+	Shard
+}
+
+// Index is the index.json manifest of a sharded export.
+type Index struct {
+	Tracks  Shard        `json:"tracks"` // tracks.json
+	Events  Shard        `json:"events"` // events.json
+	ByTrack []TrackShard `json:"by_track"`
+	ByMonth []MonthShard `json:"by_month"`
+}
+
+// Sharded writes tracks.json and events.json like All, and splits the
+// events into tracks/<slug>.json per track and months/<YYYY-MM>.json per
+// month, with an index.json listing every file with its count and SHA-256.
+// An event spanning months is in each month's file; a track without events
+// gets an empty file. Shards that no longer have events are removed.
+// Nothing is written if two tracks share a slug.
+func Sharded(dataDir string, tracks []db.Track, events []db.Event) (Result, error) {
+	if err := checkTrackSlugs(tracks); err != nil {
+		return Result{}, err
+	}
+	res, err := All(dataDir, tracks, events)
+	if err != nil {
+		return res, err
+	}
+	var index Index
+	if _, index.Tracks, err = encodeShard("tracks.json", tracks, len(tracks)); err != nil {
+		return res, err
+	}
+	if _, index.Events, err = encodeShard("events.json", events, len(events)); err != nil {
+		return res, err
+	}
+
+	trackFiles := make(map[string][]byte)
+	for _, t := range tracks {
+		slug := trackSlug(t)
+		trackEvents := []db.Event{}
+		for _, ev := range events {
+			if ev.TrackID == t.ID {
+				trackEvents = append(trackEvents, ev)
+			}
+		}
+		name := slug + ".json"
+		b, shard, err := encodeShard(TrackShardDir+"/"+name, trackEvents, len(trackEvents))
+		if err != nil {
+			return res, err
+		}
+		trackFiles[name] = b
+		index.ByTrack = append(index.ByTrack, TrackShard{ID: t.ID, Name: t.Name, Slug: slug, Shard: shard})
+	}
+
+	byMonth := make(map[string][]db.Event)
+	var months []string
+	for _, ev := range events {
+		for _, m := range eventMonths(ev) {
+			if _, ok := byMonth[m]; !ok {
+				months = append(months, m)
+			}
+			byMonth[m] = append(byMonth[m], ev)
+		}
+	}
+	sort.Strings(months) // YYYY-MM sorts as text
+	monthFiles := make(map[string][]byte)
+	for _, m := range months {
+		name := m + ".json"
+		b, shard, err := encodeShard(MonthShardDir+"/"+name, byMonth[m], len(byMonth[m]))
+		if err != nil {
+			return res, err
+		}
+		monthFiles[name] = b
+		index.ByMonth = append(index.ByMonth, MonthShard{Month: m, Shard: shard})
+	}
+
+	if err := res.writeShards(dataDir, TrackShardDir, trackFiles); err != nil {
+		return res, err
+	}
+	if err := res.writeShards(dataDir, MonthShardDir, monthFiles); err != nil {
+		return res, err
+	}
+	if err := res.writeJSON(dataDir, "index.json", index); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// writeShards writes files, keyed by name, to dataDir/sub and removes the
+// other .json files there.
+func (r *Result) writeShards(dataDir, sub string, files map[string][]byte) error {
+	if err := EnsureDir(filepath.Join(dataDir, sub)); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := r.writeFile(dataDir, filepath.Join(sub, name), files[name]); err != nil {
+			return err
+		}
+	}
+	return r.removeStale(dataDir, sub, ".json", files)
+}
+
+// eventMonths lists the months, as YYYY-MM in the track's time zone, from
+// an event's start to its end.
+func eventMonths(ev db.Event) []string {
+	end := ev.StartDate
+	if ev.EndDate != nil && ev.EndDate.After(end) {
+		end = *ev.EndDate
+	}
+	last := end.Format("2006-01")
+	m := time.Date(ev.StartDate.Year(), ev.StartDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var months []string
+	for {
+		months = append(months, m.Format("2006-01"))
+		if months[len(months)-1] >= last {
+			return months
+		}
+		m = m.AddDate(0, 1, 0)
+	}
+}
+
+// encodeShard encodes v, a list of count items, as the content of file.
+func encodeShard(file string, v any, count int) ([]byte, Shard, error) {
+	b, err := marshalJSON(v)
+	if err != nil {
+		return nil, Shard{}, fmt.Errorf("%s: %w", file, err)
+	}
+	sum := sha256.Sum256(b)
+	return b, Shard{File: file, Count: count, SHA256: hex.EncodeToString(sum[:])}, nil
+}
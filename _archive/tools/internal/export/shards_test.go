@@ -0,0 +1,104 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+func TestSharded(t *testing.T) {
+	dataDir := t.TempDir()
+	loc, _ := time.LoadLocation("America/Chicago")
+	tracks := []db.Track{
+		{ID: 1, Name: "Texas Motorplex", Address: "7500 US-287, Ennis, TX"},
+		{ID: 2, Name: "Xtreme Raceway Park", Address: "Ferris, TX"},
+	}
+	augustEnd := time.Date(2026, 9, 2, 18, 0, 0, 0, loc)
+	events := []db.Event{
+		{ID: 1, Title: "Test and Tune", TrackID: 1, StartDate: time.Date(2026, 3, 22, 9, 0, 0, 0, loc)},
+		{ID: 2, Title: "Labor Day Shootout", TrackID: 1, StartDate: time.Date(2026, 8, 30, 9, 0, 0, 0, loc), EndDate: &augustEnd},
+	}
+
+	if _, err := Sharded(dataDir, tracks, events); err != nil {
+		t.Fatalf("Sharded failed: %v", err)
+	}
+
+	var index Index
+	readJSON(t, filepath.Join(dataDir, "index.json"), &index)
+	if index.Events.File != "events.json" || index.Events.Count != 2 {
+		t.Errorf("Unexpected events entry %+v", index.Events)
+	}
+	if len(index.ByTrack) != 2 {
+		t.Fatalf("Expected a shard per track, got %+v", index.ByTrack)
+	}
+	if s := index.ByTrack[0]; s.File != "tracks/texas-motorplex-tx.json" || s.Count != 2 {
+		t.Errorf("Unexpected track shard %+v", s)
+	}
+	if s := index.ByTrack[1]; s.Count != 0 {
+		t.Errorf("Expected an empty shard for a track without events, got %+v", s)
+	}
+	var months []string
+	for _, s := range index.ByMonth {
+		months = append(months, s.Month)
+	}
+	if want := []string{"2026-03", "2026-08", "2026-09"}; !slices.Equal(months, want) {
+		t.Errorf("Expected months %v, got %v", want, months)
+	}
+
+	for _, s := range append([]Shard{index.Tracks, index.Events, index.ByTrack[0].Shard}, index.ByMonth[2].Shard) {
+		b, err := os.ReadFile(filepath.Join(dataDir, filepath.FromSlash(s.File)))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", s.File, err)
+		}
+		if sum := sha256.Sum256(b); hex.EncodeToString(sum[:]) != s.SHA256 {
+			t.Errorf("%s: hash does not match the index", s.File)
+		}
+	}
+	var september []db.Event
+	readJSON(t, filepath.Join(dataDir, "months", "2026-09.json"), &september)
+	if len(september) != 1 || september[0].ID != 2 {
+		t.Errorf("Expected the Labor Day event in September, got %+v", september)
+	}
+
+	// Dropping the March event removes its month
+	res, err := Sharded(dataDir, tracks, events[1:])
+	if err != nil {
+		t.Fatalf("Sharded failed: %v", err)
+	}
+	if len(res.Removed) != 1 || res.Removed[0] != filepath.Join("months", "2026-03.json") {
+		t.Errorf("Expected the March shard to be removed, got %v", res.Removed)
+	}
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatalf("Invalid JSON in %s: %v", path, err)
+	}
+}
+
+func TestShardedRejectsSharedTrackSlugs(t *testing.T) {
+	dataDir := t.TempDir()
+	tracks := []db.Track{
+		{ID: 1, Name: "Xtreme Raceway Park", Address: "Ferris, TX"},
+		{ID: 2, Name: "Xtreme Raceway Park", Address: "1800 S Interstate 45, Ferris, TX 75125"},
+	}
+
+	if _, err := Sharded(dataDir, tracks, nil); err == nil {
+		t.Fatal("Expected an error for tracks sharing a slug")
+	}
+	if entries, _ := os.ReadDir(dataDir); len(entries) != 0 {
+		t.Errorf("Expected nothing to be written, got %d entries", len(entries))
+	}
+}
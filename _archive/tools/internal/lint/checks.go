@@ -0,0 +1,222 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// Severities of a finding. Only errors fail the lint command.
+const (
+	SeverityError   = "error"   // wrong data that should not be exported
+	SeverityWarning = "warning" // likely a mistake
+	SeverityInfo    = "info"    // worth filling in
+)
+
+// DefaultPastDays is how many days after it ends an event is reported by
+// the past-events check.
+const DefaultPastDays = 30
+
+// Options configures the checks.
+type Options struct {
+	Now      time.Time // the current time; zero means time.Now
+	PastDays int       // days after which an ended event is too old to list
+}
+
+// Finding is one problem a check found with an event.
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	EventID  int64  `json:"event_id"`
+	Event    string `json:"event"` // the event's title
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s [%d] %q: %s", f.Check, f.EventID, f.Event, f.Message)
+}
+
+// Check is a named data quality check run on each event.
+type Check struct {
+	Name        string
+	Severity    string
+	Description string
+	check       func(ev db.Event, opts Options) []string
+}
+
+// Checks are all the checks, in the order they are run.
+var Checks = []Check{
+	{"negative-fees", SeverityError, "driver, spectator or class buy-in fees below zero", checkNegativeFees},
+	{"end-before-start", SeverityError, "events that end before they start", checkEndBeforeStart},
+	{"no-classes", SeverityWarning, "events without classes", checkNoClasses},
+	{"class-without-rules", SeverityInfo, "classes without rules", checkClassWithoutRules},
+	{"past-events", SeverityWarning, "events that ended more than --past-days ago", checkPastEvents},
+	{"empty-url", SeverityWarning, "events without a URL", checkEmptyURL},
+	{"title-whitespace", SeverityWarning, "titles and class names with leading, trailing or repeated whitespace", checkTitleWhitespace},
+	{"status-in-title", SeverityWarning, "titles that say an event is cancelled, postponed or rained out instead of setting its status", checkStatusInTitle},
+}
+
+// Select returns the checks named in enable, or all checks if enable is
+// empty, minus those named in disable. Unknown names are an error.
+func Select(enable, disable []string) ([]Check, error) {
+	known := make(map[string]bool, len(Checks))
+	for _, c := range Checks {
+		known[c.Name] = true
+	}
+	for _, name := range append(append([]string(nil), enable...), disable...) {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown check %q", name)
+		}
+	}
+
+	var out []Check
+	for _, c := range Checks {
+		if len(enable) > 0 && !contains(enable, c.Name) || contains(disable, c.Name) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs checks on events, which should have their classes and rules
+// nested. Findings are ordered by severity, errors first, then by event.
+func Run(events []db.Event, checks []Check, opts Options) []Finding {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	var out []Finding
+	for _, ev := range events {
+		for _, c := range checks {
+			for _, msg := range c.check(ev, opts) {
+				out = append(out, Finding{Check: c.Name, Severity: c.Severity, EventID: ev.ID, Event: ev.Title, Message: msg})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return severityRank(out[i].Severity) < severityRank(out[j].Severity)
+	})
+	return out
+}
+
+func severityRank(s string) int {
+	switch s {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	}
+	return 2
+}
+
+// Count returns how many findings have the given severity.
+func Count(findings []Finding, severity string) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+func checkNegativeFees(ev db.Event, _ Options) []string {
+	var out []string
+	if ev.DriverFee != nil && *ev.DriverFee < 0 {
+		out = append(out, fmt.Sprintf("driver fee is %.2f", *ev.DriverFee))
+	}
+	if ev.SpectatorFee != nil && *ev.SpectatorFee < 0 {
+		out = append(out, fmt.Sprintf("spectator fee is %.2f", *ev.SpectatorFee))
+	}
+	for _, c := range ev.Classes {
+		if c.BuyinFee != nil && *c.BuyinFee < 0 {
+			out = append(out, fmt.Sprintf("class %q buy-in fee is %.2f", c.Name, *c.BuyinFee))
+		}
+	}
+	return out
+}
+
+func checkEndBeforeStart(ev db.Event, _ Options) []string {
+	if ev.EndDate != nil && ev.EndDate.Before(ev.StartDate) {
+		return []string{fmt.Sprintf("ends %s, before it starts %s", ev.EndDate.Format(db.DateLayout), ev.StartDate.Format(db.DateLayout))}
+	}
+	return nil
+}
+
+func checkNoClasses(ev db.Event, _ Options) []string {
+	if len(ev.Classes) == 0 {
+		return []string{"has no classes"}
+	}
+	return nil
+}
+
+func checkClassWithoutRules(ev db.Event, _ Options) []string {
+	var out []string
+	for _, c := range ev.Classes {
+		if len(c.Rules) == 0 {
+			out = append(out, fmt.Sprintf("class %q has no rules", c.Name))
+		}
+	}
+	return out
+}
+
+func checkPastEvents(ev db.Event, opts Options) []string {
+	end := ev.StartDate
+	if ev.EndDate != nil && ev.EndDate.After(end) {
+		end = *ev.EndDate
+	}
+	if days := int(opts.Now.Sub(end).Hours() / 24); days > opts.PastDays {
+		return []string{fmt.Sprintf("ended %d days ago", days)}
+	}
+	return nil
+}
+
+func checkEmptyURL(ev db.Event, _ Options) []string {
+	if strings.TrimSpace(ev.URL) == "" {
+		return []string{"has no URL"}
+	}
+	return nil
+}
+
+func checkTitleWhitespace(ev db.Event, _ Options) []string {
+	var out []string
+	if strayWhitespace(ev.Title) {
+		out = append(out, fmt.Sprintf("title %q has stray whitespace", ev.Title))
+	}
+	for _, c := range ev.Classes {
+		if strayWhitespace(c.Name) {
+			out = append(out, fmt.Sprintf("class name %q has stray whitespace", c.Name))
+		}
+	}
+	return out
+}
+
+// strayWhitespace reports whether s is not already in the form
+// strings.Join(strings.Fields(s), " ").
+func strayWhitespace(s string) bool {
+	return s != strings.Join(strings.Fields(s), " ")
+}
+
+// statusWordsRE matches the ways titles announce a status, e.g.
+// "**CANCELLED**", "Postponed" or "RAINED OUT".
+var statusWordsRE = regexp.MustCompile(`(?i)\b(cancell?ed|postponed|rain(ed)?[ -]?out)\b`)
+
+func checkStatusInTitle(ev db.Event, _ Options) []string {
+	if m := statusWordsRE.FindString(ev.Title); m != "" {
+		return []string{fmt.Sprintf("title says %q; set the event's status instead", m)}
+	}
+	return nil
+}
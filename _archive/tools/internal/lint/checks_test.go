@@ -0,0 +1,70 @@
+package lint
+
+import (
+	"testing"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	fee := func(v float64) *float64 { return &v }
+	start := time.Date(2026, 6, 12, 9, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+	old := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	rules := []db.EventClassRule{{Rule: "DOT tires"}}
+	events := []db.Event{
+		{ID: 1, Title: "Spring Nationals", StartDate: start, URL: "https://example.com",
+			Classes: []db.EventClass{{Name: "Stock Muscle", Rules: rules}}},
+		{ID: 2, Title: " Friday  Night Drags", StartDate: start, EndDate: &before, DriverFee: fee(-40),
+			Classes: []db.EventClass{{Name: "Pro  Street", BuyinFee: fee(-5)}}},
+		{ID: 3, Title: "Season Opener", StartDate: old, URL: "https://example.com"},
+		{ID: 4, Title: "TMCCC Race #3 **CANCELLED**", StartDate: start, URL: "https://example.com",
+			Classes: []db.EventClass{{Name: "Stock Muscle", Rules: rules}}},
+	}
+
+	findings := Run(events, Checks, Options{Now: now, PastDays: DefaultPastDays})
+	got := make(map[string][]int64)
+	for _, f := range findings {
+		got[f.Check] = append(got[f.Check], f.EventID)
+	}
+	want := map[string][]int64{
+		"negative-fees":       {2, 2},
+		"end-before-start":    {2},
+		"no-classes":          {3},
+		"class-without-rules": {2},
+		"past-events":         {3},
+		"empty-url":           {2},
+		"title-whitespace":    {2, 2},
+		"status-in-title":     {4},
+	}
+	for name, ids := range want {
+		if len(got[name]) != len(ids) || got[name][0] != ids[0] {
+			t.Errorf("%s: expected findings for %v, got %v", name, ids, got[name])
+		}
+	}
+	if len(findings) != 10 {
+		t.Errorf("Expected 10 findings, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Severity != SeverityError || findings[len(findings)-1].Severity != SeverityInfo {
+		t.Errorf("Expected errors first and info last, got %v", findings)
+	}
+	if n := Count(findings, SeverityError); n != 3 {
+		t.Errorf("Expected 3 errors, got %d", n)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	checks, err := Select(nil, []string{"empty-url", "past-events"})
+	if err != nil || len(checks) != len(Checks)-2 {
+		t.Errorf("Expected all but 2 checks, got %d (%v)", len(checks), err)
+	}
+	checks, err = Select([]string{"no-classes", "empty-url"}, []string{"empty-url"})
+	if err != nil || len(checks) != 1 || checks[0].Name != "no-classes" {
+		t.Errorf("Expected only no-classes, got %+v (%v)", checks, err)
+	}
+	if _, err := Select([]string{"no-such-check"}, nil); err == nil {
+		t.Error("Expected an error for an unknown check")
+	}
+}
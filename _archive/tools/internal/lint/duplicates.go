@@ -0,0 +1,218 @@
+// Package lint finds problems in the event data before it is exported.
+package lint
+
+import (
+	"sort"
+	"strings"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+// DefaultSimilarity is the title similarity at or above which two events at
+// the same track on overlapping dates are taken to be duplicates.
+const DefaultSimilarity = 0.75
+
+// AutoMergeSimilarity is the title similarity a cluster needs to be merged
+// without asking: the titles have the same words, give or take a typo.
+const AutoMergeSimilarity = 1
+
+// Cluster is a set of events that are likely the same event.
+type Cluster struct {
+	Events     []db.Event // by start date, then ID
+	Keep       int64      // the event suggested to keep; see suggestKeep
+	Similarity float64    // the lowest title similarity that joined the cluster
+}
+
+// Duplicates clusters events that are likely duplicates: at the same track,
+// on overlapping days, with titles at least threshold similar (see
+// TitleSimilarity). Events are joined transitively, so a cluster can hold
+// events that only match through a third. Events should have their classes
+// and rules nested, which the suggested keeper is chosen by. Clusters are
+// ordered by their first event's start date.
+func Duplicates(events []db.Event, threshold float64) []Cluster {
+	parent := make([]int, len(events))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	lowest := make(map[int]float64) // by root
+
+	for i := range events {
+		for j := i + 1; j < len(events); j++ {
+			a, b := events[i], events[j]
+			if a.TrackID != b.TrackID || !daysOverlap(a, b) {
+				continue
+			}
+			sim := TitleSimilarity(a.Title, b.Title)
+			if sim < threshold {
+				continue
+			}
+			ri, rj := find(i), find(j)
+			if ri == rj {
+				continue
+			}
+			low := sim
+			for _, r := range []int{ri, rj} {
+				if v, ok := lowest[r]; ok && v < low {
+					low = v
+				}
+			}
+			delete(lowest, ri)
+			delete(lowest, rj)
+			parent[rj] = ri
+			lowest[ri] = low
+		}
+	}
+
+	byRoot := make(map[int][]db.Event)
+	for i, ev := range events {
+		byRoot[find(i)] = append(byRoot[find(i)], ev)
+	}
+	var out []Cluster
+	for root, evs := range byRoot {
+		if len(evs) < 2 {
+			continue
+		}
+		sort.Slice(evs, func(i, j int) bool {
+			if !evs[i].StartDate.Equal(evs[j].StartDate) {
+				return evs[i].StartDate.Before(evs[j].StartDate)
+			}
+			return evs[i].ID < evs[j].ID
+		})
+		out = append(out, Cluster{Events: evs, Keep: suggestKeep(evs), Similarity: lowest[root]})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].Events[0], out[j].Events[0]
+		if !a.StartDate.Equal(b.StartDate) {
+			return a.StartDate.Before(b.StartDate)
+		}
+		return a.ID < b.ID
+	})
+	return out
+}
+
+// daysOverlap reports whether two events share a day at the track. An event
+// without an end date lasts its start day.
+func daysOverlap(a, b db.Event) bool {
+	aStart, aEnd := eventDays(a)
+	bStart, bEnd := eventDays(b)
+	return aStart <= bEnd && bStart <= aEnd
+}
+
+func eventDays(ev db.Event) (start, end string) {
+	start = ev.StartDate.Format("2006-01-02")
+	end = start
+	if ev.EndDate != nil {
+		end = ev.EndDate.Format("2006-01-02")
+	}
+	return start, end
+}
+
+// TitleSimilarity scores how alike two titles are, from 0 to 1, after
+// normalizing them with db.NormalizeTitle: the words the titles share over
+// the words in either (their Jaccard index), so that a short title is not
+// taken for every longer one containing its words. Words of five or more
+// letters match with a typo (see wordsMatch); numbers must match exactly,
+// so "TMCCC Race #2" is not "TMCCC Race #3".
+func TitleSimilarity(a, b string) float64 {
+	aw, bw := uniqueWords(db.NormalizeTitle(a)), uniqueWords(db.NormalizeTitle(b))
+	if len(aw) == 0 || len(bw) == 0 {
+		return 0
+	}
+	used := make([]bool, len(bw))
+	shared := 0
+	for _, w := range aw {
+		for k, x := range bw {
+			if !used[k] && wordsMatch(w, x) {
+				used[k] = true
+				shared++
+				break
+			}
+		}
+	}
+	return float64(shared) / float64(len(aw)+len(bw)-shared)
+}
+
+func uniqueWords(s string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// wordsMatch reports whether two normalized words are the same, or both of
+// five or more letters and at least 80% alike, as "nationals" and
+// "nationls" are. Words with digits must be the same.
+func wordsMatch(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) < 5 || len(b) < 5 || strings.ContainsAny(a+b, "0123456789") {
+		return false
+	}
+	return editSimilarity(a, b) >= 0.8
+}
+
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+// suggestKeep picks the event of a cluster to keep: one entered locally over
+// one imported from the aggregator, then the one with the most classes and
+// rules, then the oldest.
+func suggestKeep(events []db.Event) int64 {
+	best := events[0]
+	for _, ev := range events[1:] {
+		if betterKeep(ev, best) {
+			best = ev
+		}
+	}
+	return best.ID
+}
+
+func betterKeep(a, b db.Event) bool {
+	if al, bl := a.Source != db.SourceAggregator, b.Source != db.SourceAggregator; al != bl {
+		return al
+	}
+	if ad, bd := detail(a), detail(b); ad != bd {
+		return ad > bd
+	}
+	return a.ID < b.ID
+}
+
+// detail counts an event's classes and rules.
+func detail(ev db.Event) int {
+	n := len(ev.Classes)
+	for _, c := range ev.Classes {
+		n += len(c.Rules)
+	}
+	return n
+}
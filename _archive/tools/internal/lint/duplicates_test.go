@@ -0,0 +1,79 @@
+package lint
+
+import (
+	"testing"
+	"time"
+
+	"dfw-dragevents/tools/internal/db"
+)
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{" IHRA Bracket Series", "IHRA  bracket series", 1, 1},
+		{"Fall Nationls", "Fall Nationals", 1, 1},
+		{"TMCCC Race #2", "TMCCC Race #2 Weekend", 0.75, 0.75},
+		{"TMCCC Race #2", "TMCCC Race #3", 0, 0.5},
+		{"Friday Night Drags", "Friday Night Grudge", 0, 0.5},
+		{"Friday Night Drags", "Test N Tune", 0, 0},
+		{"Bracket", "Xtreme Bracket Series", 0, 0.34},
+		// from the aggregator's feed: the same series, different events
+		{"Xtreme NHRA Bracket Series", "2026 Pro 1 Racing & Safety Products Xtreme Bracket Series - April 3-4 (Friday Night Under the Lights)", 0, 0.2},
+		{"", "Test N Tune", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := TitleSimilarity(tt.a, tt.b); got < tt.min || got > tt.max {
+			t.Errorf("TitleSimilarity(%q, %q) = %.2f, want %.2f to %.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 4, d, 18, 0, 0, 0, time.UTC) }
+	end := day(13)
+	rules := []db.EventClassRule{{Rule: "DOT tires"}}
+	events := []db.Event{
+		{ID: 1, Title: "TMCCC Race #2", TrackID: 1, StartDate: day(12), Source: db.SourceAggregator},
+		{ID: 2, Title: "TMCCC Race #2 Weekend", TrackID: 1, StartDate: day(11), EndDate: &end, Source: db.SourceLocal,
+			Classes: []db.EventClass{{Name: "Stock Muscle", Rules: rules}}},
+		{ID: 3, Title: "TMCCC Race #2", TrackID: 2, StartDate: day(12)},            // another track
+		{ID: 4, Title: "TMCCC Race #2", TrackID: 1, StartDate: day(19)},            // another weekend
+		{ID: 5, Title: "Spring Nationals", TrackID: 1, StartDate: day(12)},         // another title
+		{ID: 6, Title: "Spring Natinals", TrackID: 1, StartDate: day(12)},          // a typo of 5
+		{ID: 9, Title: "Friday Night Grudge", TrackID: 1, StartDate: day(3)},       // not 7 or 8
+		{ID: 7, Title: "Friday Night Drags", TrackID: 1, StartDate: day(3)},        // another week
+		{ID: 8, Title: "Friday Night Drags", TrackID: 1, StartDate: day(3).Add(1)}, // same day as 7
+	}
+
+	clusters := Duplicates(events, DefaultSimilarity)
+	if len(clusters) != 3 {
+		t.Fatalf("Expected 3 clusters, got %+v", clusters)
+	}
+	ids := func(c Cluster) []int64 {
+		var out []int64
+		for _, ev := range c.Events {
+			out = append(out, ev.ID)
+		}
+		return out
+	}
+	if got := ids(clusters[0]); len(got) != 2 || got[0] != 7 || got[1] != 8 || clusters[0].Keep != 7 {
+		t.Errorf("Expected events 7 and 8 keeping 7, got %v keeping %d", got, clusters[0].Keep)
+	}
+	// the local event with classes wins over the imported one
+	if got := ids(clusters[1]); len(got) != 2 || got[0] != 2 || got[1] != 1 || clusters[1].Keep != 2 {
+		t.Errorf("Expected events 2 and 1 keeping 2, got %v keeping %d", got, clusters[1].Keep)
+	}
+	if got := ids(clusters[1]); len(got) != 2 || got[0] != 2 || got[1] != 1 || clusters[1].Similarity >= AutoMergeSimilarity {
+		t.Errorf("Expected events 2 and 1 to need confirming, got %v at %.2f", got, clusters[1].Similarity)
+	}
+	if got := ids(clusters[2]); len(got) != 2 || got[0] != 5 || got[1] != 6 || clusters[2].Similarity < AutoMergeSimilarity {
+		t.Errorf("Expected the typo of events 5 and 6 to be merged automatically, got %v at %.2f", got, clusters[2].Similarity)
+	}
+
+	if clusters := Duplicates(events, 1.01); len(clusters) != 0 {
+		t.Errorf("Expected no clusters above full similarity, got %+v", clusters)
+	}
+}